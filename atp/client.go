@@ -7,6 +7,9 @@ import (
 	"go.flow.arcalot.io/pluginsdk/schema"
 	"io"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const MinSupportedATPVersion = 1
@@ -22,11 +25,21 @@ type ClientChannel interface {
 // Client is the way to read information from the ATP server and then send a task to it in the form of a step.
 // A step can only be sent once, but signals can be sent until the step is over. It is a single session.
 type Client interface {
+	// MessageSender sends extension messages to the ATP server. It can only be used while a step is executing.
+	MessageSender
 	// ReadSchema reads the schema from the ATP server.
 	ReadSchema() (*schema.SchemaSchema, error)
 	// Execute executes a step with a given context and returns the resulting output.
 	Execute(input schema.Input, receivedSignals chan schema.Input, emittedSignals chan<- schema.Input) (outputID string, outputData any, err error)
+	// ExecuteWithMetrics executes a step like Execute, but returns the output alongside the metrics collected during
+	// the execution.
+	ExecuteWithMetrics(input schema.Input, receivedSignals chan schema.Input, emittedSignals chan<- schema.Input) (ExecutionResult, error)
+	// PluginMetadata returns the metadata the plugin sent with the schema. It returns nil if the schema has not been
+	// read yet or the plugin didn't send any metadata.
+	PluginMetadata() *PluginMetadata
+	// Encoder returns the CBOR encoder of the client, or nil if the client uses a different encoding.
 	Encoder() *cbor.Encoder
+	// Decoder returns the CBOR decoder of the client, or nil if the client uses a different encoding.
 	Decoder() *cbor.Decoder
 }
 
@@ -42,46 +55,98 @@ func NewClientWithLogger(
 	channel ClientChannel,
 	logger log.Logger,
 ) Client {
-	decMode, err := cbor.DecOptions{
-		ExtraReturnErrors: cbor.ExtraDecErrorUnknownField,
-	}.DecMode()
+	return newClient(channel, logger, cborCodec{}, newOptions(nil))
+}
+
+// NewClientWithOptions creates a new ATP client (part of the engine code) with a logger and options. It returns an
+// error if the options are invalid, for example if they select an unsupported encoding.
+func NewClientWithOptions(
+	channel ClientChannel,
+	logger log.Logger,
+	opts ...Option,
+) (Client, error) {
+	clientOptions := newOptions(opts)
+	messageCodec, err := newCodec(clientOptions.encoding)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+	return newClient(channel, logger, messageCodec, clientOptions), nil
+}
+
+func newClient(
+	channel ClientChannel,
+	logger log.Logger,
+	messageCodec codec,
+	clientOptions *options,
+) *client {
 	if logger == nil {
 		logger = log.NewLogger(log.LevelDebug, log.NewNOOPLogger())
 	}
+	reader := &countingReader{reader: channel}
+	writer := &countingWriter{writer: channel}
 	return &client{
-		-1, // unknown
-		channel,
-		decMode,
-		logger,
-		decMode.NewDecoder(channel),
-		cbor.NewEncoder(channel),
+		atpVersion: -1, // unknown
+		channel:    channel,
+		reader:     reader,
+		writer:     writer,
+		codec:      messageCodec,
+		logger:     logger,
+		decoder:    messageCodec.newDecoder(reader, true),
+		encoder:    messageCodec.newEncoder(writer),
+		token:      clientOptions.token,
+
+		messageHandlers: clientOptions.messageHandlers,
 	}
 }
 
+// ExecutionResult holds the output of a step execution and the metrics collected while running it.
+type ExecutionResult struct {
+	OutputID   string
+	OutputData any
+	Metrics    ExecutionMetrics
+}
+
 func (c *client) Decoder() *cbor.Decoder {
-	return c.decoder
+	decoder, _ := c.decoder.(*cbor.Decoder)
+	return decoder
 }
 
 func (c *client) Encoder() *cbor.Encoder {
-	return c.encoder
+	encoder, _ := c.encoder.(*cbor.Encoder)
+	return encoder
 }
 
 type client struct {
-	atpVersion int64
-	channel    ClientChannel
-	decMode    cbor.DecMode
-	logger     log.Logger
-	decoder    *cbor.Decoder
-	encoder    *cbor.Encoder
+	atpVersion      int64
+	channel         ClientChannel
+	reader          *countingReader
+	writer          *countingWriter
+	codec           codec
+	logger          log.Logger
+	decoder         Decoder
+	encoder         Encoder
+	schemaReadTime  time.Duration
+	pluginMetadata  *PluginMetadata
+	capabilities    []string
+	token           string
+	messageHandlers map[uint32]MessageHandler
+	encoderLock     sync.Mutex
+	workStarted     uint32
+	signalsSent     uint64
+	signalsReceived uint64
 }
 
 func (c *client) ReadSchema() (*schema.SchemaSchema, error) {
 	c.logger.Debugf("Reading plugin schema...")
+	start := time.Now()
+	defer func() {
+		c.schemaReadTime = time.Since(start)
+	}()
 
-	if err := c.encoder.Encode(nil); err != nil {
+	if err := c.encoder.Encode(ClientHelloMessage{
+		Capabilities: []string{CapabilityPluginMetadata},
+		Token:        c.token,
+	}); err != nil {
 		c.logger.Errorf("Failed to encode ATP start output message: %v", err)
 		return nil, fmt.Errorf("failed to encode start output message (%w)", err)
 	}
@@ -100,6 +165,12 @@ func (c *client) ReadSchema() (*schema.SchemaSchema, error) {
 			MinSupportedATPVersion, MaxSupportedATPVersion)
 	}
 	c.atpVersion = hello.Version
+	c.pluginMetadata = hello.Metadata
+	c.capabilities = hello.Capabilities
+	if hello.Metadata != nil {
+		c.logger.Debugf("Plugin %s version %s (SDK version %s).", hello.Metadata.Name, hello.Metadata.Version,
+			hello.Metadata.SDKVersion)
+	}
 
 	unserializedSchema, err := schema.UnserializeSchema(hello.Schema)
 	if err != nil {
@@ -110,19 +181,45 @@ func (c *client) ReadSchema() (*schema.SchemaSchema, error) {
 	return unserializedSchema, nil
 }
 
+func (c *client) PluginMetadata() *PluginMetadata {
+	return c.pluginMetadata
+}
+
 func (c *client) Execute(
 	stepData schema.Input,
 	receivedSignals chan schema.Input,
 	emittedSignals chan<- schema.Input,
 ) (outputID string, outputData any, err error) {
+	result, err := c.ExecuteWithMetrics(stepData, receivedSignals, emittedSignals)
+	return result.OutputID, result.OutputData, err
+}
+
+func (c *client) ExecuteWithMetrics(
+	stepData schema.Input,
+	receivedSignals chan schema.Input,
+	emittedSignals chan<- schema.Input,
+) (ExecutionResult, error) {
 	c.logger.Debugf("Executing plugin step %s...", stepData.ID)
-	if err := c.encoder.Encode(StartWorkMessage{
+	if len(stepData.Secrets) > 0 && !hasCapability(c.capabilities, CapabilitySecrets) {
+		c.logger.Errorf("Step %s was called with secrets, but the plugin does not support them.", stepData.ID)
+		return ExecutionResult{Metrics: c.metrics()}, fmt.Errorf(
+			"the plugin does not support secrets, refusing to send them for step %s", stepData.ID)
+	}
+	startWorkMessage := StartWorkMessage{
 		StepID: stepData.ID,
 		Config: stepData.InputData,
-	}); err != nil {
+	}
+	if stepData.TraceContext != nil {
+		startWorkMessage.TraceParent = stepData.TraceContext.TraceParent
+		startWorkMessage.TraceState = stepData.TraceContext.TraceState
+		startWorkMessage.Baggage = stepData.TraceContext.Baggage
+	}
+	startWorkMessage.Secrets = stepData.Secrets
+	if err := c.encoder.Encode(startWorkMessage); err != nil {
 		c.logger.Errorf("Step %s failed to write start work message: %v", stepData.ID, err)
-		return "", nil, fmt.Errorf("failed to write work start message (%w)", err)
+		return ExecutionResult{Metrics: c.metrics()}, fmt.Errorf("failed to write work start message (%w)", err)
 	}
+	atomic.StoreUint32(&c.workStarted, 1)
 	c.logger.Debugf("Step %s started, waiting for response...", stepData.ID)
 
 	doneChannel := make(chan bool, 1) // Needs a buffer to not hang.
@@ -130,7 +227,25 @@ func (c *client) Execute(
 	go func() {
 		c.executeWriteLoop(stepData, receivedSignals, doneChannel)
 	}()
-	return c.executeReadLoop(stepData, receivedSignals)
+	outputID, outputData, workMetrics, err := c.executeReadLoop(stepData, emittedSignals)
+	result := ExecutionResult{
+		OutputID:   outputID,
+		OutputData: outputData,
+		Metrics:    c.metrics(),
+	}
+	workMetrics.applyTo(&result.Metrics)
+	return result, err
+}
+
+// metrics returns the metrics collected on the client side.
+func (c *client) metrics() ExecutionMetrics {
+	return ExecutionMetrics{
+		SchemaRead:      c.schemaReadTime,
+		BytesIn:         c.reader.bytes(),
+		BytesOut:        c.writer.bytes(),
+		SignalsSent:     atomic.LoadUint64(&c.signalsSent),
+		SignalsReceived: atomic.LoadUint64(&c.signalsReceived),
+	}
 }
 
 // handleClosure is the deferred function that will handle closing of the received channel,
@@ -174,16 +289,15 @@ func (c *client) executeWriteLoop(
 			return
 		}
 		c.logger.Debugf("Sending signal with ID '%s' to step with ID '%s'", signal.ID, stepData.ID)
-		if err := c.encoder.Encode(RuntimeMessage{
-			MessageTypeSignal,
-			signalMessage{
-				StepID:   stepData.ID,
-				SignalID: signal.ID,
-				Data:     signal.InputData,
-			}}); err != nil {
-			c.logger.Errorf("Step %s failed to write signal (%s) with error: %w", stepData.ID, signal.ID, err)
+		if err := c.SendMessage(MessageTypeSignal, signalMessage{
+			StepID:   stepData.ID,
+			SignalID: signal.ID,
+			Data:     signal.InputData,
+		}); err != nil {
+			c.logger.Errorf("Step %s failed to write signal (%s) with error: %v", stepData.ID, signal.ID, err)
 			return
 		}
+		atomic.AddUint64(&c.signalsSent, 1)
 		c.logger.Debugf("Successfully sent signal with ID '%s' to step with ID '%s'", signal.ID, stepData.ID)
 	}
 }
@@ -192,24 +306,23 @@ func (c *client) executeWriteLoop(
 // It branches off with different logic for ATP versions 1 and 2.
 func (c *client) executeReadLoop(
 	stepData schema.Input, emittedSignals chan<- schema.Input,
-) (outputID string, outputData any, err error) {
-	cborReader := c.decMode.NewDecoder(c.channel)
+) (outputID string, outputData any, metrics *workMetrics, err error) {
 	if c.atpVersion >= 2 {
-		return c.executeReadLoopV2(cborReader, stepData, emittedSignals)
+		return c.executeReadLoopV2(c.decoder, stepData, emittedSignals)
 	} else {
-		return c.executeReadLoopV1(cborReader, stepData)
+		return c.executeReadLoopV1(c.decoder, stepData)
 	}
 }
 
 // executeReadLoopV1 is the legacy read loop function, that only waits for work done.
 func (c *client) executeReadLoopV1(
-	cborReader *cbor.Decoder,
+	decoder Decoder,
 	stepData schema.Input,
-) (outputID string, outputData any, err error) {
+) (outputID string, outputData any, metrics *workMetrics, err error) {
 	var doneMessage workDoneMessage
-	if err := cborReader.Decode(&doneMessage); err != nil {
+	if err := decoder.Decode(&doneMessage); err != nil {
 		c.logger.Errorf("Failed to read or decode work done message: (%w) for step %s", err, stepData.ID)
-		return "", nil,
+		return "", nil, nil,
 			fmt.Errorf("failed to read or decode work done message (%w) for step %s", err, stepData.ID)
 	}
 	return c.handleWorkDone(stepData, doneMessage)
@@ -217,55 +330,71 @@ func (c *client) executeReadLoopV1(
 
 // executeReadLoopV2 is the new read loop function, that supports the RuntimeMessage loop.
 func (c *client) executeReadLoopV2(
-	cborReader *cbor.Decoder,
+	decoder Decoder,
 	stepData schema.Input,
 	emittedSignals chan<- schema.Input,
-) (outputID string, outputData any, err error) {
+) (outputID string, outputData any, metrics *workMetrics, err error) {
+	var doneMessage *workDoneMessage
+	dispatcher := newMessageDispatcher(c.codec, c.atpVersion, c.messageHandlers)
+	dispatcher.handlers[MessageTypeWorkDone] = func(data any) error {
+		doneMessage = data.(*workDoneMessage)
+		return nil
+	}
+	dispatcher.handlers[MessageTypeSignal] = func(data any) error {
+		c.handleSignal(stepData, data.(*signalMessage), emittedSignals)
+		return nil
+	}
+
 	// Loop and get all messages
-	// The message is generic, so we must find the type and decode the full message next.
+	// The message is generic, so the dispatcher finds the type and decodes the full message next.
 	var runtimeMessage DecodedRuntimeMessage
-	for {
-		if err := cborReader.Decode(&runtimeMessage); err != nil {
+	for doneMessage == nil {
+		if err := decoder.Decode(&runtimeMessage); err != nil {
 			c.logger.Errorf("Step %s failed to read or decode runtime message: %v", stepData.ID, err)
-			return "", nil,
+			return "", nil, nil,
 				fmt.Errorf("failed to read or decode runtime message (%w)", err)
 		}
-		switch runtimeMessage.MessageID {
-		case MessageTypeWorkDone:
-			var doneMessage workDoneMessage
-			if err := cbor.Unmarshal(runtimeMessage.RawMessageData, &doneMessage); err != nil {
-				c.logger.Errorf("Failed to decode work done message (%v) for step ID %s ", err, stepData.ID)
-				return "", nil,
-					fmt.Errorf("failed to read work done message (%w)", err)
-			}
-			return c.handleWorkDone(stepData, doneMessage)
-		case MessageTypeSignal:
-			var signalMessage signalMessage
-			if err := cbor.Unmarshal(runtimeMessage.RawMessageData, &signalMessage); err != nil {
-				c.logger.Errorf("Step %s failed to decode signal message: %v", stepData.ID, err)
-			}
-			if stepData.ID != signalMessage.StepID {
-				c.logger.Warningf("Step %s sent signal %s, but the step ID '%s' sent by the plugin does not match. Ignoring signal.",
-					stepData.ID, signalMessage.SignalID, signalMessage.StepID)
-				continue // Don't process the signal
-			}
-			if emittedSignals == nil {
-				c.logger.Warningf("Step '%s' sent signal '%s'. Ignoring; signal handling is not implemented (emittedSignals is nil).",
-					stepData.ID, signalMessage.SignalID)
-			} else {
-				c.logger.Debugf("Got signal from step '%s' with ID '%s'", stepData.ID, signalMessage.SignalID)
-				emittedSignals <- signalMessage.ToInput()
-			}
-		default:
-			c.logger.Warningf("Step %s sent unknown message type: %s", stepData.ID, runtimeMessage.MessageID)
+		handled, err := dispatcher.dispatch(runtimeMessage)
+		if err != nil {
+			c.logger.Errorf("Step %s failed to handle runtime message %d: %v", stepData.ID, runtimeMessage.MessageID, err)
+			return "", nil, nil, err
 		}
+		if !handled {
+			c.logger.Warningf("Step %s sent unknown message type %d, ignoring.", stepData.ID, runtimeMessage.MessageID)
+		}
+	}
+	return c.handleWorkDone(stepData, *doneMessage)
+}
+
+func (c *client) handleSignal(stepData schema.Input, signal *signalMessage, emittedSignals chan<- schema.Input) {
+	atomic.AddUint64(&c.signalsReceived, 1)
+	if stepData.ID != signal.StepID {
+		c.logger.Warningf("Step %s sent signal %s, but the step ID '%s' sent by the plugin does not match. Ignoring signal.",
+			stepData.ID, signal.SignalID, signal.StepID)
+		return
+	}
+	if emittedSignals == nil {
+		c.logger.Warningf("Step '%s' sent signal '%s'. Ignoring; signal handling is not implemented (emittedSignals is nil).",
+			stepData.ID, signal.SignalID)
+		return
+	}
+	c.logger.Debugf("Got signal from step '%s' with ID '%s'", stepData.ID, signal.SignalID)
+	emittedSignals <- signal.ToInput()
+}
+
+// SendMessage sends a runtime message to the server. Messages sent before the start work message would be mistaken
+// for it, so this fails until a step is executing.
+func (c *client) SendMessage(messageID uint32, data any) error {
+	if atomic.LoadUint32(&c.workStarted) == 0 {
+		return fmt.Errorf("runtime messages can only be sent while a step is executing")
 	}
+	return encodeMessage(c.encoder, &c.encoderLock, c.atpVersion, messageID, data)
 }
 
 func (c *client) handleWorkDone(
 	stepData schema.Input,
 	doneMessage workDoneMessage,
-) (outputID string, outputData any, err error) {
+) (outputID string, outputData any, metrics *workMetrics, err error) {
 	c.logger.Debugf("Step %s completed with output ID '%s'.", stepData.ID, doneMessage.OutputID)
 
 	// Print debug logs from the step as debug.
@@ -276,5 +405,5 @@ func (c *client) handleWorkDone(
 		}
 	}
 
-	return doneMessage.OutputID, doneMessage.OutputData, nil
+	return doneMessage.OutputID, doneMessage.OutputData, doneMessage.Metrics, nil
 }
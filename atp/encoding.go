@@ -0,0 +1,129 @@
+package atp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/fxamacker/cbor/v2"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// Encoding is the wire format used to transport ATP messages.
+type Encoding string
+
+const (
+	// EncodingCBOR is the standard ATP encoding.
+	EncodingCBOR Encoding = "cbor"
+	// EncodingJSONLines encodes each message as a single line of JSON using the same message structures as the CBOR
+	// encoding. It is intended for debugging only, for example to drive a plugin by hand from a terminal.
+	EncodingJSONLines Encoding = "json"
+)
+
+// Encoder writes ATP messages to the underlying stream.
+type Encoder interface {
+	Encode(v any) error
+}
+
+// Decoder reads ATP messages from the underlying stream.
+type Decoder interface {
+	Decode(v any) error
+}
+
+// RawMessage holds the still-encoded data of a message, regardless of the encoding in use.
+type RawMessage []byte
+
+// UnmarshalCBOR stores a copy of the raw CBOR data.
+func (r *RawMessage) UnmarshalCBOR(data []byte) error {
+	*r = append(RawMessage(nil), data...)
+	return nil
+}
+
+// UnmarshalJSON stores a copy of the raw JSON data.
+func (r *RawMessage) UnmarshalJSON(data []byte) error {
+	*r = append(RawMessage(nil), data...)
+	return nil
+}
+
+// codec creates encoders and decoders for a specific Encoding.
+type codec interface {
+	newEncoder(w io.Writer) Encoder
+	// newDecoder creates a decoder. If strict is set, unknown fields in structs result in an error.
+	newDecoder(r io.Reader, strict bool) Decoder
+	// unmarshal decodes a RawMessage received in the same encoding.
+	unmarshal(data RawMessage, v any) error
+}
+
+func newCodec(encoding Encoding) (codec, error) {
+	switch encoding {
+	case EncodingCBOR, "":
+		return cborCodec{}, nil
+	case EncodingJSONLines:
+		return jsonLinesCodec{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported ATP encoding: %s", encoding)
+	}
+}
+
+var strictCBORDecMode = func() cbor.DecMode {
+	decMode, err := cbor.DecOptions{
+		ExtraReturnErrors: cbor.ExtraDecErrorUnknownField,
+	}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+	return decMode
+}()
+
+type cborCodec struct{}
+
+func (cborCodec) newEncoder(w io.Writer) Encoder {
+	return cbor.NewEncoder(w)
+}
+
+func (cborCodec) newDecoder(r io.Reader, strict bool) Decoder {
+	if strict {
+		return strictCBORDecMode.NewDecoder(r)
+	}
+	return cbor.NewDecoder(r)
+}
+
+func (cborCodec) unmarshal(data RawMessage, v any) error {
+	return cbor.Unmarshal(data, v)
+}
+
+type jsonLinesCodec struct{}
+
+func (jsonLinesCodec) newEncoder(w io.Writer) Encoder {
+	// The JSON encoder terminates each value with a newline, which makes the stream newline-delimited.
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return encoder
+}
+
+func (jsonLinesCodec) newDecoder(r io.Reader, strict bool) Decoder {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	return jsonNumberDecoder{decoder}
+}
+
+func (c jsonLinesCodec) unmarshal(data RawMessage, v any) error {
+	return c.newDecoder(bytes.NewReader(data), false).Decode(v)
+}
+
+// jsonNumberDecoder decodes the numbers of untyped values as int64 or float64, so that integers above 2^53 keep
+// their value instead of going through float64.
+type jsonNumberDecoder struct {
+	decoder *json.Decoder
+}
+
+func (d jsonNumberDecoder) Decode(v any) error {
+	if err := d.decoder.Decode(v); err != nil {
+		return err
+	}
+	return schema.ConvertJSONNumbers(v)
+}
@@ -0,0 +1,157 @@
+package atp_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.arcalot.io/log/v2"
+	"go.flow.arcalot.io/pluginsdk/atp"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+func TestProtocol_JSONLines_Client_Execute(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	stdinReader, stdinWriter := io.Pipe()
+	stdoutReader, stdoutWriter := io.Pipe()
+
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, atp.RunATPServer(
+			ctx,
+			stdinReader,
+			stdoutWriter,
+			helloWorldSchema,
+			atp.WithEncoding(atp.EncodingJSONLines),
+		))
+	}()
+
+	go func() {
+		defer wg.Done()
+		cli, err := atp.NewClientWithOptions(channel{
+			Reader:  stdoutReader,
+			Writer:  stdinWriter,
+			Context: nil,
+			cancel:  cancel,
+		}, log.NewTestLogger(t), atp.WithEncoding(atp.EncodingJSONLines))
+		assert.NoError(t, err)
+		assert.Nil(t, cli.Encoder())
+
+		pluginSchema, err := cli.ReadSchema()
+		assert.NoError(t, err)
+		assert.NotNil(t, pluginSchema.Steps()["hello-world"])
+
+		result, err := cli.ExecuteWithMetrics(
+			schema.Input{
+				ID:        "hello-world",
+				InputData: map[string]any{"name": "Arca Lot"},
+			}, nil, nil)
+		assert.NoError(t, err)
+		assert.Equals(t, result.OutputID, "success")
+		assert.Equals(t, result.OutputData.(map[string]any)["message"].(string), "Hello, Arca Lot!")
+	}()
+
+	wg.Wait()
+}
+
+func TestProtocol_JSONLines_ByHand(t *testing.T) {
+	// Simulates a developer typing the messages into a terminal.
+	stdinReader, stdinWriter := io.Pipe()
+	stdoutReader, stdoutWriter := io.Pipe()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- atp.RunATPServer(
+			context.Background(),
+			stdinReader,
+			stdoutWriter,
+			helloWorldSchema,
+			atp.WithEncoding(atp.EncodingJSONLines),
+		)
+		_ = stdoutWriter.Close()
+	}()
+	go func() {
+		_, _ = io.WriteString(stdinWriter, "null\n")
+		_, _ = io.WriteString(stdinWriter, `{"id": "hello-world", "config": {"name": "Arca Lot"}}`+"\n")
+	}()
+
+	lines := bufio.NewScanner(stdoutReader)
+	lines.Buffer(make([]byte, 1024*1024), 1024*1024)
+
+	assert.Equals(t, lines.Scan(), true)
+	var hello atp.HelloMessage
+	assert.NoError(t, json.Unmarshal(lines.Bytes(), &hello))
+	assert.Equals(t, hello.Version, atp.ProtocolVersion)
+
+	assert.Equals(t, lines.Scan(), true)
+	var workDone struct {
+		ID   uint32 `json:"id"`
+		Data struct {
+			OutputID   string         `json:"output_id"`
+			OutputData map[string]any `json:"output_data"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(lines.Bytes(), &workDone))
+	assert.Equals(t, workDone.ID, atp.MessageTypeWorkDone)
+	assert.Equals(t, workDone.Data.OutputID, "success")
+	assert.Equals(t, workDone.Data.OutputData["message"].(string), "Hello, Arca Lot!")
+
+	assert.NoError(t, <-errs)
+}
+
+func TestProtocol_JSONLines_LargeInteger(t *testing.T) {
+	stdinReader, stdinWriter := io.Pipe()
+	stdoutReader, stdoutWriter := io.Pipe()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- atp.RunATPServer(
+			context.Background(),
+			stdinReader,
+			stdoutWriter,
+			helloWorldSchema,
+			atp.WithEncoding(atp.EncodingJSONLines),
+		)
+		_ = stdoutWriter.Close()
+	}()
+	go func() {
+		_, _ = io.WriteString(stdinWriter, "null\n")
+		// The integer is above 2^53, so it would change if it was decoded as float64.
+		_, _ = io.WriteString(stdinWriter, `{"id": "hello-world", "config": {"name": 9007199254740993}}`+"\n")
+	}()
+
+	lines := bufio.NewScanner(stdoutReader)
+	lines.Buffer(make([]byte, 1024*1024), 1024*1024)
+	assert.Equals(t, lines.Scan(), true)
+	assert.Equals(t, lines.Scan(), true)
+	var workDone struct {
+		Data struct {
+			OutputData map[string]any `json:"output_data"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(lines.Bytes(), &workDone))
+	assert.Equals(t, workDone.Data.OutputData["message"].(string), "Hello, 9007199254740993!")
+	assert.NoError(t, <-errs)
+}
+
+func TestProtocol_UnsupportedEncoding(t *testing.T) {
+	stdinReader, _ := io.Pipe()
+	_, stdoutWriter := io.Pipe()
+	assert.Error(t, atp.RunATPServer(
+		context.Background(),
+		stdinReader,
+		stdoutWriter,
+		helloWorldSchema,
+		atp.WithEncoding("xml"),
+	))
+	_, err := atp.NewClientWithOptions(channel{Reader: stdinReader, Writer: stdoutWriter}, nil, atp.WithEncoding("xml"))
+	assert.Error(t, err)
+	_, err = atp.NewInProcessClient(context.Background(), helloWorldSchema, nil, atp.WithEncoding("xml"))
+	assert.Error(t, err)
+}
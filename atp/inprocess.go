@@ -0,0 +1,97 @@
+package atp
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	log "go.arcalot.io/log/v2"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// InProcessClient is a Client connected to a plugin schema running in the same process.
+type InProcessClient interface {
+	Client
+	io.Closer
+
+	// Wait waits for the in-process server to finish and returns its error, if any. If the session is abandoned
+	// before a step completes, call Close first.
+	Wait() error
+}
+
+// NewInProcessClient runs an ATP server for the given schema inside the current process and returns a client that is
+// connected to it. The messages pass through the full protocol, including serialization, signals and version
+// handling, but use in-memory pipes instead of OS pipes. The server does not install OS signal handlers; cancel the
+// passed context instead.
+//
+// Like any ATP session, the returned client can execute a single step. It returns an error if the options are
+// invalid.
+func NewInProcessClient(
+	ctx context.Context,
+	pluginSchema *schema.CallableSchema,
+	logger log.Logger,
+	opts ...Option,
+) (InProcessClient, error) {
+	c := &inProcessClient{
+		done: make(chan struct{}),
+	}
+	cli, err := NewClientWithOptions(c, logger, opts...)
+	if err != nil {
+		return nil, err
+	}
+	c.Client = cli
+
+	stdinReader, stdinWriter := io.Pipe()
+	stdoutReader, stdoutWriter := io.Pipe()
+	serverCtx, cancel := context.WithCancel(ctx)
+
+	c.cancel = cancel
+	c.stdin = stdinWriter
+	c.stdout = stdoutReader
+	serverOpts := append(append([]Option{}, opts...), WithoutOSSignalHandling())
+	go func() {
+		defer close(c.done)
+		c.err = RunATPServer(serverCtx, stdinReader, stdoutWriter, pluginSchema, serverOpts...)
+		// Make sure the client sees the end of the session instead of waiting for a message that never comes.
+		if c.err != nil {
+			_ = stdoutWriter.CloseWithError(c.err)
+		} else {
+			_ = stdoutWriter.Close()
+		}
+	}()
+	return c, nil
+}
+
+type inProcessClient struct {
+	Client
+
+	cancel    context.CancelFunc
+	done      chan struct{}
+	err       error
+	stdin     *io.PipeWriter
+	stdout    *io.PipeReader
+	closeOnce sync.Once
+}
+
+func (c *inProcessClient) Read(p []byte) (int, error) {
+	return c.stdout.Read(p)
+}
+
+func (c *inProcessClient) Write(p []byte) (int, error) {
+	return c.stdin.Write(p)
+}
+
+// Close tears down the connection to the in-process server and cancels any step that is still running.
+func (c *inProcessClient) Close() error {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		_ = c.stdin.Close()
+		_ = c.stdout.Close()
+	})
+	return nil
+}
+
+func (c *inProcessClient) Wait() error {
+	<-c.done
+	return c.err
+}
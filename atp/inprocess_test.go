@@ -0,0 +1,60 @@
+package atp_test
+
+import (
+	"context"
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.arcalot.io/log/v2"
+	"go.flow.arcalot.io/pluginsdk/atp"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+func TestInProcessClient_Execute(t *testing.T) {
+	cli, err := atp.NewInProcessClient(context.Background(), helloWorldSchema, log.NewTestLogger(t))
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, cli.Close())
+	}()
+
+	pluginSchema, err := cli.ReadSchema()
+	assert.NoError(t, err)
+	assert.NotNil(t, pluginSchema.Steps()["hello-world"])
+
+	result, err := cli.ExecuteWithMetrics(
+		schema.Input{
+			ID:        "hello-world",
+			InputData: map[string]any{"name": "Arca Lot"},
+		}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equals(t, result.OutputID, "success")
+	assert.Equals(t, result.OutputData.(map[any]any)["message"].(string), "Hello, Arca Lot!")
+	assert.NoError(t, cli.Wait())
+}
+
+func TestInProcessClient_UnknownStep(t *testing.T) {
+	cli, err := atp.NewInProcessClient(context.Background(), helloWorldSchema, log.NewTestLogger(t))
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, cli.Close())
+	}()
+
+	_, err = cli.ReadSchema()
+	assert.NoError(t, err)
+
+	_, err = cli.ExecuteWithMetrics(
+		schema.Input{
+			ID:        "non-existent",
+			InputData: map[string]any{"name": "Arca Lot"},
+		}, nil, nil)
+	assert.Error(t, err)
+	assert.Error(t, cli.Wait())
+}
+
+func TestInProcessClient_Close(t *testing.T) {
+	// Abandoning the session before it starts must not leave the server hanging.
+	cli, err := atp.NewInProcessClient(context.Background(), helloWorldSchema, log.NewTestLogger(t))
+	assert.NoError(t, err)
+	assert.NoError(t, cli.Close())
+	_ = cli.Wait()
+}
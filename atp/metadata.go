@@ -0,0 +1,94 @@
+package atp
+
+import (
+	"runtime/debug"
+)
+
+const sdkModulePath = "go.flow.arcalot.io/pluginsdk"
+
+// PluginMetadata describes the plugin build that serves a schema. It is sent in the hello message to clients that
+// announce CapabilityPluginMetadata.
+type PluginMetadata struct {
+	// Name is the human-readable name of the plugin.
+	Name string `cbor:"name,omitempty" json:"name,omitempty" yaml:"name,omitempty"`
+	// Version is the version of the plugin itself.
+	Version string `cbor:"version,omitempty" json:"version,omitempty" yaml:"version,omitempty"`
+	// SDKVersion is the version of the Go SDK the plugin was built with.
+	SDKVersion string `cbor:"sdk_version,omitempty" json:"sdk_version,omitempty" yaml:"sdk_version,omitempty"`
+	// Authors lists the authors of the plugin.
+	Authors []string `cbor:"authors,omitempty" json:"authors,omitempty" yaml:"authors,omitempty"`
+	// SourceURL points to the source code of the plugin.
+	SourceURL string `cbor:"source_url,omitempty" json:"source_url,omitempty" yaml:"source_url,omitempty"`
+	// License is the SPDX identifier of the license of the plugin.
+	License string `cbor:"license,omitempty" json:"license,omitempty" yaml:"license,omitempty"`
+	// Build describes the binary the plugin runs from.
+	Build *BuildInfo `cbor:"build,omitempty" json:"build,omitempty" yaml:"build,omitempty"`
+}
+
+// BuildInfo holds the parts of the Go build information that identify a plugin binary.
+type BuildInfo struct {
+	// GoVersion is the Go version the binary was built with.
+	GoVersion string `cbor:"go_version,omitempty" json:"go_version,omitempty" yaml:"go_version,omitempty"`
+	// Path is the package path of the main package.
+	Path string `cbor:"path,omitempty" json:"path,omitempty" yaml:"path,omitempty"`
+	// Module is the path of the main module.
+	Module string `cbor:"module,omitempty" json:"module,omitempty" yaml:"module,omitempty"`
+	// ModuleVersion is the version of the main module, usually "(devel)" for local builds.
+	ModuleVersion string `cbor:"module_version,omitempty" json:"module_version,omitempty" yaml:"module_version,omitempty"`
+	// VCSRevision is the version control revision the binary was built from.
+	VCSRevision string `cbor:"vcs_revision,omitempty" json:"vcs_revision,omitempty" yaml:"vcs_revision,omitempty"`
+	// VCSTime is the time of the version control revision in RFC3339 format.
+	VCSTime string `cbor:"vcs_time,omitempty" json:"vcs_time,omitempty" yaml:"vcs_time,omitempty"`
+	// VCSModified indicates that the working tree had local modifications at build time.
+	VCSModified bool `cbor:"vcs_modified,omitempty" json:"vcs_modified,omitempty" yaml:"vcs_modified,omitempty"`
+}
+
+// WithBuildInfo returns a copy of the metadata with SDKVersion and Build filled in from the build information embedded
+// in the running binary. Values that are already set are kept.
+func (m PluginMetadata) WithBuildInfo() PluginMetadata {
+	buildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		return m
+	}
+	if m.SDKVersion == "" {
+		m.SDKVersion = sdkVersion(buildInfo)
+	}
+	if m.Build == nil {
+		m.Build = newBuildInfo(buildInfo)
+	}
+	return m
+}
+
+func sdkVersion(buildInfo *debug.BuildInfo) string {
+	modules := append([]*debug.Module{&buildInfo.Main}, buildInfo.Deps...)
+	for _, module := range modules {
+		if module.Path != sdkModulePath {
+			continue
+		}
+		if module.Replace != nil && module.Replace.Version != "" {
+			return module.Replace.Version
+		}
+		return module.Version
+	}
+	return ""
+}
+
+func newBuildInfo(buildInfo *debug.BuildInfo) *BuildInfo {
+	result := &BuildInfo{
+		GoVersion:     buildInfo.GoVersion,
+		Path:          buildInfo.Path,
+		Module:        buildInfo.Main.Path,
+		ModuleVersion: buildInfo.Main.Version,
+	}
+	for _, setting := range buildInfo.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			result.VCSRevision = setting.Value
+		case "vcs.time":
+			result.VCSTime = setting.Value
+		case "vcs.modified":
+			result.VCSModified = setting.Value == "true"
+		}
+	}
+	return result
+}
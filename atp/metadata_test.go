@@ -0,0 +1,70 @@
+package atp_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"go.arcalot.io/assert"
+	"go.arcalot.io/log/v2"
+	"go.flow.arcalot.io/pluginsdk/atp"
+)
+
+func TestProtocol_PluginMetadata(t *testing.T) {
+	cli, err := atp.NewInProcessClient(
+		context.Background(),
+		helloWorldSchema,
+		log.NewTestLogger(t),
+		atp.WithPluginMetadata(atp.PluginMetadata{
+			Name:      "Hello world",
+			Version:   "1.2.3",
+			Authors:   []string{"Arca Lot"},
+			SourceURL: "https://github.com/arcalot/arcaflow-plugin-sdk-go",
+			License:   "Apache-2.0",
+		}),
+	)
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, cli.Close())
+	}()
+
+	assert.Nil(t, cli.PluginMetadata())
+	_, err = cli.ReadSchema()
+	assert.NoError(t, err)
+
+	metadata := cli.PluginMetadata()
+	assert.NotNil(t, metadata)
+	assert.Equals(t, metadata.Name, "Hello world")
+	assert.Equals(t, metadata.Version, "1.2.3")
+	assert.Equals(t, metadata.Authors, []string{"Arca Lot"})
+	assert.Equals(t, metadata.License, "Apache-2.0")
+	assert.NotNil(t, metadata.Build)
+	assert.Equals(t, metadata.Build.GoVersion != "", true)
+}
+
+func TestProtocol_PluginMetadata_LegacyClient(t *testing.T) {
+	// A client that sends an empty start message must get a hello message without metadata.
+	stdinReader, stdinWriter := io.Pipe()
+	stdoutReader, stdoutWriter := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = atp.RunATPServer(
+			ctx,
+			stdinReader,
+			stdoutWriter,
+			helloWorldSchema,
+			atp.WithoutOSSignalHandling(),
+			atp.WithPluginMetadata(atp.PluginMetadata{Name: "Hello world"}),
+		)
+	}()
+
+	assert.NoError(t, cbor.NewEncoder(stdinWriter).Encode(nil))
+	var hello map[string]any
+	assert.NoError(t, cbor.NewDecoder(stdoutReader).Decode(&hello))
+	assert.Equals(t, hello["version"], any(uint64(atp.ProtocolVersion)))
+	_, hasMetadata := hello["metadata"]
+	assert.Equals(t, hasMetadata, false)
+}
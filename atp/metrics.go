@@ -0,0 +1,90 @@
+package atp
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// ExecutionMetrics holds measurements about a single step execution. The durations spent in the plugin are measured
+// on the server side and transported to the client with the work done message, while the byte and signal counters
+// are always from the perspective of the side collecting them.
+type ExecutionMetrics struct {
+	// SchemaRead is the time it took to read and unserialize the plugin schema. Only measured on the client side.
+	SchemaRead time.Duration
+	// InputUnserialize is the time the plugin spent unserializing and validating the step input.
+	InputUnserialize time.Duration
+	// Handler is the time the plugin spent in the step initializer and handler.
+	Handler time.Duration
+	// OutputSerialize is the time the plugin spent validating and serializing the step output.
+	OutputSerialize time.Duration
+	// BytesIn is the number of bytes read from the other side.
+	BytesIn uint64
+	// BytesOut is the number of bytes written to the other side.
+	BytesOut uint64
+	// SignalsSent is the number of signals sent to the other side.
+	SignalsSent uint64
+	// SignalsReceived is the number of signals received from the other side.
+	SignalsReceived uint64
+}
+
+// MetricsHook is called by the server with the metrics collected for a step execution.
+type MetricsHook func(stepID string, metrics ExecutionMetrics)
+
+// workMetrics is the wire representation of the plugin-side durations, in nanoseconds.
+type workMetrics struct {
+	InputUnserialize int64 `cbor:"input_unserialize_ns" json:"input_unserialize_ns"`
+	Handler          int64 `cbor:"handler_ns" json:"handler_ns"`
+	OutputSerialize  int64 `cbor:"output_serialize_ns" json:"output_serialize_ns"`
+}
+
+func newWorkMetrics(timings *schema.CallTimings) *workMetrics {
+	return &workMetrics{
+		InputUnserialize: int64(timings.InputUnserialize + timings.InputValidate),
+		Handler:          int64(timings.Handler),
+		OutputSerialize:  int64(timings.OutputValidate + timings.OutputSerialize),
+	}
+}
+
+func (w *workMetrics) applyTo(metrics *ExecutionMetrics) {
+	if w == nil {
+		return
+	}
+	metrics.InputUnserialize = time.Duration(w.InputUnserialize)
+	metrics.Handler = time.Duration(w.Handler)
+	metrics.OutputSerialize = time.Duration(w.OutputSerialize)
+}
+
+// countingReader counts the bytes read from the underlying reader.
+type countingReader struct {
+	reader io.Reader
+	count  uint64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	atomic.AddUint64(&c.count, uint64(n))
+	return n, err
+}
+
+func (c *countingReader) bytes() uint64 {
+	return atomic.LoadUint64(&c.count)
+}
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	writer io.Writer
+	count  uint64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.writer.Write(p)
+	atomic.AddUint64(&c.count, uint64(n))
+	return n, err
+}
+
+func (c *countingWriter) bytes() uint64 {
+	return atomic.LoadUint64(&c.count)
+}
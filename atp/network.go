@@ -0,0 +1,83 @@
+package atp
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+
+	log "go.arcalot.io/log/v2"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// ServeATP accepts connections on the listener and runs a separate ATP session for each of them, so each connection
+// can execute a single step. Wrap the listener with tls.NewListener to encrypt the connections, see
+// NewServerTLSConfig, and use WithToken to authenticate clients. ServeATP returns when the context is cancelled or
+// the listener fails, after the running sessions have finished.
+func ServeATP(
+	ctx context.Context,
+	listener net.Listener,
+	pluginSchema *schema.CallableSchema,
+	logger log.Logger,
+	opts ...Option,
+) error {
+	if logger == nil {
+		logger = log.NewLogger(log.LevelDebug, log.NewNOOPLogger())
+	}
+	serverOpts := append(append([]Option{}, opts...), WithoutOSSignalHandling())
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept ATP connection (%w)", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				_ = conn.Close()
+			}()
+			logger.Debugf("Accepted ATP connection from %s.", conn.RemoteAddr())
+			if err := RunATPServer(ctx, conn, conn, pluginSchema, serverOpts...); err != nil {
+				logger.Errorf("ATP session with %s failed: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// Dial connects to an ATP server listening on the TCP address, using TLS if tlsConfig is not nil. Pass the result to
+// NewClientWithOptions to talk to the plugin.
+func Dial(ctx context.Context, address string, tlsConfig *tls.Config) (ClientChannel, error) {
+	if tlsConfig == nil {
+		dialer := &net.Dialer{}
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to ATP server %s (%w)", address, err)
+		}
+		return conn, nil
+	}
+	dialer := &tls.Dialer{Config: tlsConfig}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ATP server %s (%w)", address, err)
+	}
+	// Complete the handshake here so certificate problems surface as a connection error.
+	if err := conn.(*tls.Conn).HandshakeContext(ctx); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("TLS handshake with ATP server %s failed (%w)", address, err)
+	}
+	return conn, nil
+}
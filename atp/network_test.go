@@ -0,0 +1,255 @@
+package atp_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.arcalot.io/assert"
+	"go.arcalot.io/log/v2"
+	"go.flow.arcalot.io/pluginsdk/atp"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// testCertificates holds the paths of a generated CA, a server certificate for 127.0.0.1 and a client certificate.
+type testCertificates struct {
+	caFile         string
+	serverCertFile string
+	serverKeyFile  string
+	clientCertFile string
+	clientKeyFile  string
+}
+
+func generateTestCertificates(t *testing.T) testCertificates {
+	dir := t.TempDir()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ATP test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	assert.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	assert.NoError(t, err)
+
+	issue := func(name string, serial int64, usage x509.ExtKeyUsage) (string, string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		assert.NoError(t, err)
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		assert.NoError(t, err)
+		certFile := filepath.Join(dir, name+".crt")
+		keyFile := filepath.Join(dir, name+".key")
+		writePEM(t, certFile, "CERTIFICATE", der)
+		writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+		return certFile, keyFile
+	}
+
+	result := testCertificates{caFile: filepath.Join(dir, "ca.crt")}
+	writePEM(t, result.caFile, "CERTIFICATE", caDER)
+	result.serverCertFile, result.serverKeyFile = issue("server", 2, x509.ExtKeyUsageServerAuth)
+	result.clientCertFile, result.clientKeyFile = issue("client", 3, x509.ExtKeyUsageClientAuth)
+	return result
+}
+
+func writePEM(t *testing.T, file string, blockType string, data []byte) {
+	assert.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0600))
+}
+
+// startTLSServer starts an ATP server with mutual TLS on a random local port and returns its address.
+func startTLSServer(t *testing.T, certs testCertificates, opts ...atp.Option) string {
+	serverTLSConfig, err := atp.NewServerTLSConfig(certs.serverCertFile, certs.serverKeyFile, certs.caFile)
+	assert.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- atp.ServeATP(ctx, tls.NewListener(listener, serverTLSConfig), helloWorldSchema, log.NewTestLogger(t), opts...)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+	return listener.Addr().String()
+}
+
+func TestServeATP_MutualTLS(t *testing.T) {
+	certs := generateTestCertificates(t)
+	address := startTLSServer(t, certs, atp.WithToken("s3cr3t"))
+
+	clientTLSConfig, err := atp.NewClientTLSConfig(certs.caFile, certs.clientCertFile, certs.clientKeyFile)
+	assert.NoError(t, err)
+
+	// Each connection is a separate session, so run two steps one after the other.
+	for i := 0; i < 2; i++ {
+		conn, err := atp.Dial(context.Background(), address, clientTLSConfig)
+		assert.NoError(t, err)
+		cli, err := atp.NewClientWithOptions(conn, log.NewTestLogger(t), atp.WithToken("s3cr3t"))
+		assert.NoError(t, err)
+		_, err = cli.ReadSchema()
+		assert.NoError(t, err)
+		result, err := cli.ExecuteWithMetrics(schema.Input{
+			ID:        "hello-world",
+			InputData: map[string]any{"name": "Arca Lot"},
+		}, nil, nil)
+		assert.NoError(t, err)
+		assert.Equals(t, result.OutputData.(map[any]any)["message"].(string), "Hello, Arca Lot!")
+		assert.NoError(t, conn.Close())
+	}
+}
+
+func TestServeATP_InvalidToken(t *testing.T) {
+	certs := generateTestCertificates(t)
+	address := startTLSServer(t, certs, atp.WithToken("s3cr3t"))
+
+	clientTLSConfig, err := atp.NewClientTLSConfig(certs.caFile, certs.clientCertFile, certs.clientKeyFile)
+	assert.NoError(t, err)
+	for _, opts := range [][]atp.Option{{atp.WithToken("guessed")}, nil} {
+		conn, err := atp.Dial(context.Background(), address, clientTLSConfig)
+		assert.NoError(t, err)
+		cli, err := atp.NewClientWithOptions(conn, log.NewTestLogger(t), opts...)
+		assert.NoError(t, err)
+		_, err = cli.ReadSchema()
+		assert.Error(t, err)
+		assert.NoError(t, conn.Close())
+	}
+}
+
+func TestServeATP_MissingClientCertificate(t *testing.T) {
+	certs := generateTestCertificates(t)
+	address := startTLSServer(t, certs)
+
+	clientTLSConfig, err := atp.NewClientTLSConfig(certs.caFile, "", "")
+	assert.NoError(t, err)
+	// With TLS 1.3 the server verifies the client certificate after the client considers the handshake done, so
+	// the failure may only surface when reading the hello message.
+	conn, err := atp.Dial(context.Background(), address, clientTLSConfig)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	_, err = atp.NewClientWithLogger(conn, log.NewTestLogger(t)).ReadSchema()
+	assert.Error(t, err)
+}
+
+func TestServeATP_UntrustedServer(t *testing.T) {
+	certs := generateTestCertificates(t)
+	address := startTLSServer(t, certs)
+
+	// A client trusting a different CA must refuse to connect.
+	otherCerts := generateTestCertificates(t)
+	clientTLSConfig, err := atp.NewClientTLSConfig(otherCerts.caFile, certs.clientCertFile, certs.clientKeyFile)
+	assert.NoError(t, err)
+	_, err = atp.Dial(context.Background(), address, clientTLSConfig)
+	assert.Error(t, err)
+}
+
+func TestTLSConfig_Errors(t *testing.T) {
+	_, err := atp.NewServerTLSConfig("", "", "")
+	assert.Error(t, err)
+	_, err = atp.NewClientTLSConfig("", "client.crt", "")
+	assert.Error(t, err)
+	_, err = atp.NewClientTLSConfig(filepath.Join(t.TempDir(), "missing.crt"), "", "")
+	assert.Error(t, err)
+}
+
+type releaseStepData struct {
+	released chan struct{}
+}
+
+var releaseSchema = schema.NewCallableSchema(
+	schema.NewCallableStepWithSignals[*releaseStepData, helloWorldInput](
+		"release",
+		helloWorldInputSchema,
+		map[string]*schema.StepOutputSchema{
+			"success": helloWorldSchema.StepsValue["hello-world"].Outputs()["success"],
+		},
+		map[string]schema.CallableSignal{
+			"release": schema.NewCallableSignal(
+				"release",
+				helloWorldInputSchema,
+				nil,
+				func(_ context.Context, data *releaseStepData, _ helloWorldInput) {
+					// Closing twice panics, so this fails if two executions share the step data.
+					close(data.released)
+				},
+			),
+		},
+		nil,
+		nil,
+		func() *releaseStepData {
+			return &releaseStepData{released: make(chan struct{})}
+		},
+		func(ctx context.Context, data *releaseStepData, input helloWorldInput) (string, any) {
+			select {
+			case <-data.released:
+				return "success", helloWorldOutput{Message: "Released " + input.Name}
+			case <-ctx.Done():
+				return "success", helloWorldOutput{Message: "Cancelled"}
+			}
+		},
+	),
+)
+
+func TestServeATP_StepDataPerExecution(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- atp.ServeATP(ctx, listener, releaseSchema, log.NewTestLogger(t))
+	}()
+	defer func() {
+		cancel()
+		assert.NoError(t, <-done)
+	}()
+
+	// Both executions use the same callable step, but each must get fresh step data.
+	for i := 0; i < 2; i++ {
+		conn, err := atp.Dial(context.Background(), listener.Addr().String(), nil)
+		assert.NoError(t, err)
+		cli := atp.NewClientWithLogger(conn, log.NewTestLogger(t))
+		_, err = cli.ReadSchema()
+		assert.NoError(t, err)
+		receivedSignals := make(chan schema.Input, 1)
+		receivedSignals <- schema.Input{ID: "release", InputData: map[string]any{"name": "signal"}}
+		result, err := cli.ExecuteWithMetrics(schema.Input{
+			ID:        "release",
+			InputData: map[string]any{"name": "Arca Lot"},
+		}, receivedSignals, nil)
+		assert.NoError(t, err)
+		assert.Equals(t, result.OutputData.(map[any]any)["message"].(string), "Released Arca Lot")
+		assert.NoError(t, conn.Close())
+	}
+}
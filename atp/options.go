@@ -0,0 +1,72 @@
+package atp
+
+// Option configures an ATP client or server. Options that only apply to one side are ignored by the other.
+type Option func(*options)
+
+type options struct {
+	encoding        Encoding
+	metricsHook     MetricsHook
+	ignoreOSSignals bool
+	pluginMetadata  *PluginMetadata
+	token           string
+	messageHandlers map[uint32]MessageHandler
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithEncoding sets the wire encoding. Both the client and the server must use the same encoding. Defaults to
+// EncodingCBOR.
+func WithEncoding(encoding Encoding) Option {
+	return func(o *options) {
+		o.encoding = encoding
+	}
+}
+
+// WithMetricsHook sets a function the server calls with the collected metrics once a step execution ends.
+func WithMetricsHook(hook MetricsHook) Option {
+	return func(o *options) {
+		o.metricsHook = hook
+	}
+}
+
+// WithoutOSSignalHandling prevents the server from cancelling the step on SIGINT and SIGTERM. Use this when the
+// server is embedded in a larger program that handles OS signals itself.
+func WithoutOSSignalHandling() Option {
+	return func(o *options) {
+		o.ignoreOSSignals = true
+	}
+}
+
+// WithPluginMetadata sets the metadata the server sends to clients in the hello message. The SDK version and build
+// information are filled in automatically. Clients ignore this option.
+func WithPluginMetadata(metadata PluginMetadata) Option {
+	return func(o *options) {
+		withBuildInfo := metadata.WithBuildInfo()
+		o.pluginMetadata = &withBuildInfo
+	}
+}
+
+// WithToken sets a pre-shared token. The client sends it in its hello message, and the server refuses to talk to
+// clients that don't present the same token. Only use it over an encrypted transport, such as TLS.
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithMessageHandler sets the handler for a runtime message registered with RegisterMessageType. Without a handler,
+// received messages of that type are ignored, or end the session if they are critical.
+func WithMessageHandler(messageID uint32, handler MessageHandler) Option {
+	return func(o *options) {
+		if o.messageHandlers == nil {
+			o.messageHandlers = map[uint32]MessageHandler{}
+		}
+		o.messageHandlers[messageID] = handler
+	}
+}
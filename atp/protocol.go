@@ -1,20 +1,83 @@
 package atp
 
 import (
-	"github.com/fxamacker/cbor/v2"
 	"go.flow.arcalot.io/pluginsdk/schema"
 )
 
 const ProtocolVersion int64 = 2
 
+// CapabilityPluginMetadata announces that the client understands the Metadata field of the HelloMessage.
+const CapabilityPluginMetadata = "plugin_metadata"
+
+// CapabilitySecrets announces that the server accepts the Secrets field of the StartWorkMessage.
+const CapabilitySecrets = "secrets"
+
+// serverCapabilities lists the capabilities the server announces in the HelloMessage.
+var serverCapabilities = []string{CapabilitySecrets}
+
+// ClientHelloMessage is the start message sent by the client. Older clients send an empty message instead, so every
+// field is optional and the server must not rely on any of them.
+type ClientHelloMessage struct {
+	// Capabilities lists the optional protocol features the client understands.
+	Capabilities []string `cbor:"capabilities,omitempty" json:"capabilities,omitempty"`
+	// Token is the pre-shared token authenticating the client, if any.
+	Token string `cbor:"token,omitempty" json:"token,omitempty"`
+}
+
+// HasCapability returns true if the client announced the given capability.
+func (c ClientHelloMessage) HasCapability(capability string) bool {
+	return hasCapability(c.Capabilities, capability)
+}
+
 type HelloMessage struct {
-	Version int64 `cbor:"version"`
-	Schema  any   `cbor:"schema"`
+	Version int64 `cbor:"version" json:"version"`
+	Schema  any   `cbor:"schema" json:"schema"`
+	// Metadata describes the plugin build. It is only sent to clients that announce CapabilityPluginMetadata.
+	Metadata *PluginMetadata `cbor:"metadata,omitempty" json:"metadata,omitempty"`
+	// Capabilities lists the optional protocol features the server supports. It is only sent to clients that
+	// announce at least one capability themselves, as older clients reject unknown fields.
+	Capabilities []string `cbor:"capabilities,omitempty" json:"capabilities,omitempty"`
+}
+
+// HasCapability returns true if the server announced the given capability.
+func (h HelloMessage) HasCapability(capability string) bool {
+	return hasCapability(h.Capabilities, capability)
+}
+
+func hasCapability(capabilities []string, capability string) bool {
+	for _, announced := range capabilities {
+		if announced == capability {
+			return true
+		}
+	}
+	return false
 }
 
 type StartWorkMessage struct {
-	StepID string `cbor:"id"`
-	Config any    `cbor:"config"`
+	StepID string `cbor:"id" json:"id"`
+	Config any    `cbor:"config" json:"config"`
+	// TraceParent optionally holds the W3C traceparent of the caller.
+	TraceParent string `cbor:"traceparent,omitempty" json:"traceparent,omitempty"`
+	// TraceState optionally holds the W3C tracestate of the caller.
+	TraceState string `cbor:"tracestate,omitempty" json:"tracestate,omitempty"`
+	// Baggage optionally holds arbitrary key-value pairs to propagate to the step.
+	Baggage map[string]string `cbor:"baggage,omitempty" json:"baggage,omitempty"`
+	// Secrets optionally holds the values of input properties marked as secret, see schema.Secrets. Only send it to
+	// servers announcing CapabilitySecrets.
+	Secrets schema.Secrets `cbor:"secrets,omitempty" json:"secrets,omitempty"`
+}
+
+// TraceContext returns the trace context sent with the start work message, if any.
+func (s StartWorkMessage) TraceContext() *schema.TraceContext {
+	traceContext := schema.TraceContext{
+		TraceParent: s.TraceParent,
+		TraceState:  s.TraceState,
+		Baggage:     s.Baggage,
+	}
+	if traceContext.IsEmpty() {
+		return nil
+	}
+	return &traceContext
 }
 
 // All messages that can be contained in a RuntimeMessage struct.
@@ -24,25 +87,26 @@ const (
 )
 
 type RuntimeMessage struct {
-	MessageID   uint32 `cbor:"id"`
-	MessageData any    `cbor:"data"`
+	MessageID   uint32 `cbor:"id" json:"id"`
+	MessageData any    `cbor:"data" json:"data"`
 }
 
 type DecodedRuntimeMessage struct {
-	MessageID      uint32          `cbor:"id"`
-	RawMessageData cbor.RawMessage `cbor:"data"`
+	MessageID      uint32     `cbor:"id" json:"id"`
+	RawMessageData RawMessage `cbor:"data" json:"data"`
 }
 
 type workDoneMessage struct {
-	OutputID   string `cbor:"output_id"`
-	OutputData any    `cbor:"output_data"`
-	DebugLogs  string `cbor:"debug_logs"`
+	OutputID   string       `cbor:"output_id" json:"output_id"`
+	OutputData any          `cbor:"output_data" json:"output_data"`
+	DebugLogs  string       `cbor:"debug_logs" json:"debug_logs"`
+	Metrics    *workMetrics `cbor:"metrics,omitempty" json:"metrics,omitempty"`
 }
 
 type signalMessage struct {
-	StepID   string `cbor:"step_id"`
-	SignalID string `cbor:"signal_id"`
-	Data     any    `cbor:"data"`
+	StepID   string `cbor:"step_id" json:"step_id"`
+	SignalID string `cbor:"signal_id" json:"signal_id"`
+	Data     any    `cbor:"data" json:"data"`
 }
 
 func (s signalMessage) ToInput() schema.Input {
@@ -586,3 +586,173 @@ func newATPServer(
 		cbor.NewEncoder(channel),
 	}
 }
+
+type traceTestInput struct{}
+
+func traceTestStepHandler(ctx context.Context, _ traceTestInput) (string, any) {
+	traceContext, ok := schema.TraceContextFromContext(ctx)
+	if !ok {
+		return "success", map[string]any{"traceparent": "", "baggage": ""}
+	}
+	return "success", map[string]any{
+		"traceparent": traceContext.TraceParent,
+		"baggage":     traceContext.Baggage["tenant"],
+	}
+}
+
+var traceTestSchema = schema.NewCallableSchema(
+	schema.NewCallableStep[traceTestInput](
+		"trace",
+		schema.NewScopeSchema(
+			schema.NewStructMappedObjectSchema[traceTestInput](
+				"Input",
+				map[string]*schema.PropertySchema{},
+			),
+		),
+		map[string]*schema.StepOutputSchema{
+			"success": schema.NewStepOutputSchema(
+				schema.NewScopeSchema(
+					schema.NewObjectSchema(
+						"Output",
+						map[string]*schema.PropertySchema{
+							"traceparent": schema.NewPropertySchema(
+								schema.NewStringSchema(nil, nil, nil),
+								nil,
+								true,
+								nil,
+								nil,
+								nil,
+								nil,
+								nil,
+							),
+							"baggage": schema.NewPropertySchema(
+								schema.NewStringSchema(nil, nil, nil),
+								nil,
+								true,
+								nil,
+								nil,
+								nil,
+								nil,
+								nil,
+							),
+						},
+					),
+				),
+				nil,
+				false,
+			),
+		},
+		nil,
+		traceTestStepHandler,
+	),
+)
+
+func TestProtocol_TraceContext(t *testing.T) {
+	// The trace context sent by the client must reach the step handler through its context.
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	stdinReader, stdinWriter := io.Pipe()
+	stdoutReader, stdoutWriter := io.Pipe()
+
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, atp.RunATPServer(
+			ctx,
+			stdinReader,
+			stdoutWriter,
+			traceTestSchema,
+		))
+	}()
+
+	go func() {
+		defer wg.Done()
+		cli := atp.NewClientWithLogger(channel{
+			Reader:  stdoutReader,
+			Writer:  stdinWriter,
+			Context: nil,
+			cancel:  cancel,
+		}, log.NewTestLogger(t))
+
+		_, err := cli.ReadSchema()
+		assert.NoError(t, err)
+
+		result, err := cli.ExecuteWithMetrics(
+			schema.Input{
+				ID:        "trace",
+				InputData: map[string]any{},
+				TraceContext: &schema.TraceContext{
+					TraceParent: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+					Baggage:     map[string]string{"tenant": "arcalot"},
+				},
+			}, nil, nil)
+		assert.NoError(t, err)
+		assert.Equals(t, result.OutputID, "success")
+		assert.Equals(
+			t,
+			result.OutputData.(map[any]any)["traceparent"].(string),
+			"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		)
+		assert.Equals(t, result.OutputData.(map[any]any)["baggage"].(string), "arcalot")
+	}()
+
+	wg.Wait()
+}
+
+func TestProtocol_Metrics(t *testing.T) {
+	// Both the client and the server hook must see the metrics of the execution.
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	stdinReader, stdinWriter := io.Pipe()
+	stdoutReader, stdoutWriter := io.Pipe()
+
+	var serverStepID string
+	var serverMetrics atp.ExecutionMetrics
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, atp.RunATPServer(
+			ctx,
+			stdinReader,
+			stdoutWriter,
+			helloWorldSchema,
+			atp.WithMetricsHook(func(stepID string, metrics atp.ExecutionMetrics) {
+				serverStepID = stepID
+				serverMetrics = metrics
+			}),
+		))
+	}()
+
+	var clientMetrics atp.ExecutionMetrics
+	go func() {
+		defer wg.Done()
+		cli := atp.NewClientWithLogger(channel{
+			Reader:  stdoutReader,
+			Writer:  stdinWriter,
+			Context: nil,
+			cancel:  cancel,
+		}, log.NewTestLogger(t))
+
+		_, err := cli.ReadSchema()
+		assert.NoError(t, err)
+
+		result, err := cli.ExecuteWithMetrics(
+			schema.Input{
+				ID:        "hello-world",
+				InputData: map[string]any{"name": "Arca Lot"},
+			}, nil, nil)
+		assert.NoError(t, err)
+		clientMetrics = result.Metrics
+	}()
+
+	wg.Wait()
+	assert.Equals(t, serverStepID, "hello-world")
+	assert.Equals(t, clientMetrics.SchemaRead > 0, true)
+	assert.Equals(t, clientMetrics.Handler > 0, true)
+	assert.Equals(t, clientMetrics.Handler, serverMetrics.Handler)
+	assert.Equals(t, clientMetrics.InputUnserialize, serverMetrics.InputUnserialize)
+	assert.Equals(t, clientMetrics.OutputSerialize, serverMetrics.OutputSerialize)
+	assert.Equals(t, clientMetrics.BytesOut > 0, true)
+	assert.Equals(t, clientMetrics.BytesOut, serverMetrics.BytesIn)
+	assert.Equals(t, clientMetrics.BytesIn, serverMetrics.BytesOut)
+}
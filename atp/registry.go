@@ -0,0 +1,157 @@
+package atp
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// MessageCriticalFlag is set in the ID of critical messages. A peer that receives a critical message it doesn't
+// understand must end the session, while other unknown messages are ignored. The flag is part of the ID so that
+// peers can tell the criticality of messages they don't know.
+const MessageCriticalFlag uint32 = 1 << 31
+
+// MinExtensionMessageID is the lowest message ID, without the MessageCriticalFlag, that third parties may register.
+// Lower IDs are reserved for ATP itself.
+const MinExtensionMessageID uint32 = 1000
+
+// MessageType describes a message that can be sent inside a RuntimeMessage.
+type MessageType struct {
+	// ID identifies the message on the wire.
+	ID uint32
+	// Name is a human-readable name used in logs and errors.
+	Name string
+	// MinVersion is the first ATP version the message is available in. Runtime messages were introduced in version
+	// 2, so lower values are treated as 2.
+	MinVersion int64
+	// New returns a pointer to an empty value to decode the message data into.
+	New func() any
+}
+
+// NewMessageType creates a message type that decodes its data into a *T.
+func NewMessageType[T any](id uint32, name string, minVersion int64) MessageType {
+	return MessageType{
+		ID:         id,
+		Name:       name,
+		MinVersion: minVersion,
+		New: func() any {
+			return new(T)
+		},
+	}
+}
+
+// Critical returns true if peers that don't understand the message must end the session.
+func (m MessageType) Critical() bool {
+	return isCriticalMessage(m.ID)
+}
+
+// AvailableIn returns true if the message can be sent in a session using the given ATP version.
+func (m MessageType) AvailableIn(version int64) bool {
+	return version >= 2 && version >= m.MinVersion
+}
+
+func isCriticalMessage(messageID uint32) bool {
+	return messageID&MessageCriticalFlag != 0
+}
+
+var messageTypes = struct {
+	lock  sync.RWMutex
+	types map[uint32]MessageType
+}{
+	types: map[uint32]MessageType{
+		MessageTypeWorkDone: NewMessageType[workDoneMessage](MessageTypeWorkDone, "work done", 2),
+		MessageTypeSignal:   NewMessageType[signalMessage](MessageTypeSignal, "signal", 2),
+	},
+}
+
+// RegisterMessageType registers an extension message type for all ATP clients and servers in the process. Call it
+// from an init function. The ID, without the MessageCriticalFlag, must be at least MinExtensionMessageID.
+func RegisterMessageType(messageType MessageType) error {
+	if messageType.ID&^MessageCriticalFlag < MinExtensionMessageID {
+		return fmt.Errorf("message ID %d is reserved for ATP, extension messages must use IDs from %d",
+			messageType.ID, MinExtensionMessageID)
+	}
+	if messageType.New == nil {
+		return fmt.Errorf("message type %d (%s) has no New function", messageType.ID, messageType.Name)
+	}
+	messageTypes.lock.Lock()
+	defer messageTypes.lock.Unlock()
+	if existing, ok := messageTypes.types[messageType.ID]; ok {
+		return fmt.Errorf("message ID %d is already registered for %s", messageType.ID, existing.Name)
+	}
+	messageTypes.types[messageType.ID] = messageType
+	return nil
+}
+
+// LookupMessageType returns the registered message type with the given ID.
+func LookupMessageType(messageID uint32) (MessageType, bool) {
+	messageTypes.lock.RLock()
+	defer messageTypes.lock.RUnlock()
+	messageType, ok := messageTypes.types[messageID]
+	return messageType, ok
+}
+
+// MessageHandler handles a received runtime message. The data is the value returned by the New function of the
+// message type, filled with the decoded message data.
+type MessageHandler func(data any) error
+
+// MessageSender sends runtime messages to the other side of an ATP session.
+type MessageSender interface {
+	// SendMessage sends a registered message. It fails if the message is not available in the ATP version of the
+	// session.
+	SendMessage(messageID uint32, data any) error
+}
+
+// ErrUnknownCriticalMessage indicates that a critical message was received that has no registered type or handler.
+var ErrUnknownCriticalMessage = errors.New("unknown critical message")
+
+// messageDispatcher decodes runtime messages and passes them to the handler registered for their ID.
+type messageDispatcher struct {
+	codec    codec
+	version  int64
+	handlers map[uint32]MessageHandler
+}
+
+func newMessageDispatcher(codec codec, version int64, extensionHandlers map[uint32]MessageHandler) *messageDispatcher {
+	handlers := make(map[uint32]MessageHandler, len(extensionHandlers))
+	for messageID, handler := range extensionHandlers {
+		handlers[messageID] = handler
+	}
+	return &messageDispatcher{
+		codec:    codec,
+		version:  version,
+		handlers: handlers,
+	}
+}
+
+// dispatch decodes the message and calls its handler. It returns false without an error if the message is not
+// critical and is ignored because it is unknown, has no handler or is not available in the session's ATP version.
+func (d *messageDispatcher) dispatch(message DecodedRuntimeMessage) (handled bool, err error) {
+	messageType, known := LookupMessageType(message.MessageID)
+	handler, hasHandler := d.handlers[message.MessageID]
+	if !known || !hasHandler || !messageType.AvailableIn(d.version) {
+		if isCriticalMessage(message.MessageID) {
+			return false, fmt.Errorf("%w: %d", ErrUnknownCriticalMessage, message.MessageID)
+		}
+		return false, nil
+	}
+	data := messageType.New()
+	if err := d.codec.unmarshal(message.RawMessageData, data); err != nil {
+		return false, fmt.Errorf("failed to decode %s message (%w)", messageType.Name, err)
+	}
+	return true, handler(data)
+}
+
+// encodeMessage sends a runtime message after checking that it is registered and available in the ATP version.
+func encodeMessage(encoder Encoder, lock *sync.Mutex, version int64, messageID uint32, data any) error {
+	messageType, ok := LookupMessageType(messageID)
+	if !ok {
+		return fmt.Errorf("unregistered message ID: %d", messageID)
+	}
+	if !messageType.AvailableIn(version) {
+		return fmt.Errorf("the %s message is not available in ATP version %d", messageType.Name, version)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	return encoder.Encode(RuntimeMessage{messageID, data})
+}
@@ -0,0 +1,224 @@
+package atp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.arcalot.io/assert"
+	"go.arcalot.io/log/v2"
+	"go.flow.arcalot.io/pluginsdk/atp"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+type progressMessage struct {
+	Percent int `cbor:"percent" json:"percent"`
+}
+
+const (
+	progressMessageID = atp.MinExtensionMessageID + 1
+	abortMessageID    = (atp.MinExtensionMessageID + 2) | atp.MessageCriticalFlag
+)
+
+func init() {
+	for _, messageType := range []atp.MessageType{
+		atp.NewMessageType[progressMessage](progressMessageID, "progress", 2),
+		atp.NewMessageType[struct{}](abortMessageID, "abort", 2),
+	} {
+		if err := atp.RegisterMessageType(messageType); err != nil {
+			panic(err)
+		}
+	}
+}
+
+type extensionTestInput struct {
+	Critical bool `json:"critical"`
+}
+
+var extensionTestSchema = schema.NewCallableSchema(
+	schema.NewCallableStep[extensionTestInput](
+		"report",
+		schema.NewScopeSchema(
+			schema.NewStructMappedObjectSchema[extensionTestInput](
+				"Input",
+				map[string]*schema.PropertySchema{
+					"critical": schema.NewPropertySchema(
+						schema.NewBoolSchema(),
+						nil,
+						true,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+					),
+				},
+			),
+		),
+		map[string]*schema.StepOutputSchema{
+			"success": schema.NewStepOutputSchema(
+				schema.NewScopeSchema(schema.NewObjectSchema("Output", map[string]*schema.PropertySchema{})),
+				nil,
+				false,
+			),
+		},
+		nil,
+		func(ctx context.Context, input extensionTestInput) (string, any) {
+			sender, ok := atp.MessageSenderFromContext(ctx)
+			if !ok {
+				panic("no message sender in the step context")
+			}
+			// The client may hang up after a critical message, so send errors just end the step.
+			messages := []atp.RuntimeMessage{
+				{MessageID: progressMessageID, MessageData: progressMessage{50}},
+				{MessageID: progressMessageID, MessageData: progressMessage{100}},
+			}
+			if input.Critical {
+				messages = append(messages, atp.RuntimeMessage{MessageID: abortMessageID, MessageData: struct{}{}})
+			}
+			// The plugin also emits a signal, which must reach the emitted signals channel.
+			messages = append(messages, atp.RuntimeMessage{
+				MessageID: atp.MessageTypeSignal,
+				MessageData: map[string]any{
+					"step_id":   "report",
+					"signal_id": "progress-signal",
+					"data":      map[string]any{},
+				},
+			})
+			for _, message := range messages {
+				if err := sender.SendMessage(message.MessageID, message.MessageData); err != nil {
+					break
+				}
+			}
+			return "success", map[string]any{}
+		},
+	),
+)
+
+func TestRegisterMessageType_Errors(t *testing.T) {
+	assert.Error(t, atp.RegisterMessageType(atp.NewMessageType[progressMessage](atp.MessageTypeSignal, "signal", 2)))
+	assert.Error(t, atp.RegisterMessageType(atp.NewMessageType[progressMessage](
+		5|atp.MessageCriticalFlag, "reserved", 2),
+	))
+	assert.Error(t, atp.RegisterMessageType(atp.NewMessageType[progressMessage](progressMessageID, "duplicate", 2)))
+	assert.Error(t, atp.RegisterMessageType(atp.MessageType{ID: atp.MinExtensionMessageID + 100, Name: "no-new"}))
+
+	signalType, ok := atp.LookupMessageType(atp.MessageTypeSignal)
+	assert.Equals(t, ok, true)
+	assert.Equals(t, signalType.Critical(), false)
+	assert.Equals(t, signalType.AvailableIn(1), false)
+	assert.Equals(t, signalType.AvailableIn(2), true)
+	abortType, ok := atp.LookupMessageType(abortMessageID)
+	assert.Equals(t, ok, true)
+	assert.Equals(t, abortType.Critical(), true)
+}
+
+func TestProtocol_ExtensionMessages(t *testing.T) {
+	var progress []int
+	cli, err := atp.NewInProcessClient(
+		context.Background(),
+		extensionTestSchema,
+		log.NewTestLogger(t),
+		atp.WithMessageHandler(progressMessageID, func(data any) error {
+			progress = append(progress, data.(*progressMessage).Percent)
+			return nil
+		}),
+	)
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, cli.Close())
+	}()
+	_, err = cli.ReadSchema()
+	assert.NoError(t, err)
+
+	emittedSignals := make(chan schema.Input, 1)
+	receivedSignals := make(chan schema.Input)
+	result, err := cli.ExecuteWithMetrics(
+		schema.Input{ID: "report", InputData: map[string]any{"critical": false}},
+		receivedSignals,
+		emittedSignals,
+	)
+	assert.NoError(t, err)
+	assert.Equals(t, result.OutputID, "success")
+	assert.Equals(t, progress, []int{50, 100})
+	assert.Equals(t, (<-emittedSignals).ID, "progress-signal")
+	assert.NoError(t, cli.Wait())
+}
+
+func TestProtocol_ExtensionMessages_UnhandledCritical(t *testing.T) {
+	// Without a handler the progress messages are ignored, but the critical abort message ends the session.
+	cli, err := atp.NewInProcessClient(context.Background(), extensionTestSchema, log.NewTestLogger(t))
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, cli.Close())
+	}()
+	_, err = cli.ReadSchema()
+	assert.NoError(t, err)
+
+	_, err = cli.ExecuteWithMetrics(schema.Input{ID: "report", InputData: map[string]any{"critical": true}}, nil, nil)
+	assert.Error(t, err)
+	assert.Equals(t, errors.Is(err, atp.ErrUnknownCriticalMessage), true)
+}
+
+func TestProtocol_ExtensionMessages_ClientToServer(t *testing.T) {
+	received := make(chan int, 1)
+	// The step waits for the message from the client, so it is still running when the message arrives.
+	waitingSchema := schema.NewCallableSchema(
+		schema.NewCallableStep[struct{}](
+			"wait",
+			schema.NewScopeSchema(schema.NewStructMappedObjectSchema[struct{}]("Input", map[string]*schema.PropertySchema{})),
+			map[string]*schema.StepOutputSchema{
+				"success": schema.NewStepOutputSchema(
+					schema.NewScopeSchema(schema.NewObjectSchema("Output", map[string]*schema.PropertySchema{
+						"percent": schema.NewPropertySchema(
+							schema.NewIntSchema(nil, nil, nil),
+							nil,
+							true,
+							nil,
+							nil,
+							nil,
+							nil,
+							nil,
+						),
+					})),
+					nil,
+					false,
+				),
+			},
+			nil,
+			func(_ context.Context, _ struct{}) (string, any) {
+				return "success", map[string]any{"percent": int64(<-received)}
+			},
+		),
+	)
+	cli, err := atp.NewInProcessClient(
+		context.Background(),
+		waitingSchema,
+		log.NewTestLogger(t),
+		atp.WithMessageHandler(progressMessageID, func(data any) error {
+			received <- data.(*progressMessage).Percent
+			return nil
+		}),
+	)
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, cli.Close())
+	}()
+	_, err = cli.ReadSchema()
+	assert.NoError(t, err)
+	// Messages would be mistaken for the start work message, so they can only be sent once the step runs.
+	assert.Error(t, cli.SendMessage(progressMessageID, progressMessage{Percent: 1}))
+
+	results := make(chan atp.ExecutionResult, 1)
+	go func() {
+		result, err := cli.ExecuteWithMetrics(schema.Input{ID: "wait", InputData: map[string]any{}}, nil, nil)
+		assert.NoError(t, err)
+		results <- result
+	}()
+	for cli.SendMessage(progressMessageID, progressMessage{Percent: 42}) != nil {
+		time.Sleep(time.Millisecond)
+	}
+	result := <-results
+	assert.Equals(t, result.OutputData.(map[any]any)["percent"], any(uint64(42)))
+}
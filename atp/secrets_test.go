@@ -0,0 +1,142 @@
+package atp_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"go.arcalot.io/assert"
+	"go.arcalot.io/log/v2"
+	"go.flow.arcalot.io/pluginsdk/atp"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+type secretsTestInput struct {
+	Token string `json:"token"`
+}
+
+var secretsTestSchema = schema.NewCallableSchema(
+	schema.NewCallableStep[secretsTestInput](
+		"authenticate",
+		schema.NewScopeSchema(
+			schema.NewStructMappedObjectSchema[secretsTestInput](
+				"Input",
+				map[string]*schema.PropertySchema{
+					"token": schema.NewPropertySchema(
+						schema.NewStringSchema(schema.IntPointer(8), nil, nil),
+						nil,
+						true,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+					).MarkSecret(),
+				},
+			),
+		),
+		map[string]*schema.StepOutputSchema{
+			"success": schema.NewStepOutputSchema(
+				schema.NewScopeSchema(
+					schema.NewObjectSchema(
+						"Output",
+						map[string]*schema.PropertySchema{
+							"authenticated": schema.NewPropertySchema(
+								schema.NewBoolSchema(),
+								nil,
+								true,
+								nil,
+								nil,
+								nil,
+								nil,
+								nil,
+							),
+						},
+					),
+				),
+				nil,
+				false,
+			),
+		},
+		nil,
+		func(ctx context.Context, input secretsTestInput) (string, any) {
+			return "success", map[string]any{
+				"authenticated": input.Token == "" &&
+					schema.SecretsFromContext(ctx)["token"] == "letmeinplease",
+			}
+		},
+	),
+)
+
+func TestProtocol_Secrets(t *testing.T) {
+	cli, err := atp.NewInProcessClient(context.Background(), secretsTestSchema, log.NewTestLogger(t))
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, cli.Close())
+	}()
+	_, err = cli.ReadSchema()
+	assert.NoError(t, err)
+
+	result, err := cli.ExecuteWithMetrics(schema.Input{
+		ID:        "authenticate",
+		InputData: map[string]any{},
+		Secrets:   schema.Secrets{"token": "letmeinplease"},
+	}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equals(t, result.OutputID, "success")
+	assert.Equals(t, result.OutputData.(map[any]any)["authenticated"].(bool), true)
+	assert.NoError(t, cli.Wait())
+}
+
+func TestProtocol_Secrets_InvalidValue(t *testing.T) {
+	cli, err := atp.NewInProcessClient(context.Background(), secretsTestSchema, log.NewTestLogger(t))
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, cli.Close())
+	}()
+	_, err = cli.ReadSchema()
+	assert.NoError(t, err)
+
+	_, err = cli.ExecuteWithMetrics(schema.Input{
+		ID:        "authenticate",
+		InputData: map[string]any{},
+		Secrets:   schema.Secrets{"token": "short"},
+	}, nil, nil)
+	assert.Error(t, err)
+	err = cli.Wait()
+	assert.Error(t, err)
+	assert.Equals(t, strings.Contains(err.Error(), "short"), false)
+}
+
+func TestProtocol_Secrets_UnsupportedByServer(t *testing.T) {
+	// A server that doesn't announce any capabilities must not receive secrets.
+	stdinReader, stdinWriter := io.Pipe()
+	stdoutReader, stdoutWriter := io.Pipe()
+	go func() {
+		var clientHello any
+		assert.NoError(t, cbor.NewDecoder(stdinReader).Decode(&clientHello))
+		serializedSchema, err := secretsTestSchema.SelfSerialize()
+		assert.NoError(t, err)
+		assert.NoError(t, cbor.NewEncoder(stdoutWriter).Encode(map[string]any{
+			"version": atp.ProtocolVersion,
+			"schema":  serializedSchema,
+		}))
+	}()
+	cli := atp.NewClientWithLogger(channel{
+		Reader: stdoutReader,
+		Writer: stdinWriter,
+		cancel: func() {},
+	}, log.NewTestLogger(t))
+	_, err := cli.ReadSchema()
+	assert.NoError(t, err)
+
+	_, err = cli.ExecuteWithMetrics(schema.Input{
+		ID:        "authenticate",
+		InputData: map[string]any{},
+		Secrets:   schema.Secrets{"token": "letmeinplease"},
+	}, nil, nil)
+	assert.Error(t, err)
+	assert.Equals(t, strings.Contains(err.Error(), "letmeinplease"), false)
+}
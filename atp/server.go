@@ -2,13 +2,14 @@ package atp
 
 import (
 	"context"
+	"crypto/subtle"
 	"fmt"
-	"github.com/fxamacker/cbor/v2"
 	"go.flow.arcalot.io/pluginsdk/schema"
 	"io"
 	"os"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 )
 
@@ -18,8 +19,12 @@ func RunATPServer(
 	stdin io.ReadCloser,
 	stdout io.WriteCloser,
 	pluginSchema *schema.CallableSchema,
+	opts ...Option,
 ) error {
-	session := initializeATPServerSession(ctx, stdin, stdout, pluginSchema)
+	session, err := initializeATPServerSession(ctx, stdin, stdout, pluginSchema, newOptions(opts))
+	if err != nil {
+		return err
+	}
 	wg := &sync.WaitGroup{}
 	wg.Add(1)
 
@@ -30,20 +35,28 @@ func RunATPServer(
 
 	workError := session.handleClosure(stdin)
 
-	// Ensure that the session is done.
+	// Ensure that the session is done, then release the session context and the OS signal handler with it.
 	wg.Wait()
+	(*session.cancel)()
 	return workError
 }
 
 type atpServerSession struct {
-	ctx          context.Context
-	cancel       *context.CancelFunc
-	req          StartWorkMessage
-	cborStdin    *cbor.Decoder
-	cborStdout   *cbor.Encoder
-	workDone     chan error
-	doneChannel  chan bool
-	pluginSchema *schema.CallableSchema
+	ctx             context.Context
+	encoderLock     sync.Mutex
+	finishOnce      sync.Once
+	cancel          *context.CancelFunc
+	req             StartWorkMessage
+	stdin           *countingReader
+	stdout          *countingWriter
+	decoder         Decoder
+	encoder         Encoder
+	codec           codec
+	workDone        chan error
+	doneChannel     chan bool
+	pluginSchema    *schema.CallableSchema
+	options         *options
+	signalsReceived uint64
 }
 
 func initializeATPServerSession(
@@ -51,37 +64,50 @@ func initializeATPServerSession(
 	stdin io.ReadCloser,
 	stdout io.WriteCloser,
 	pluginSchema *schema.CallableSchema,
-) *atpServerSession {
+	opts *options,
+) (*atpServerSession, error) {
+	// The ATP protocol uses CBOR unless a debug encoding was requested.
+	messageCodec, err := newCodec(opts.encoding)
+	if err != nil {
+		return nil, err
+	}
 	subCtx, cancel := context.WithCancel(ctx)
 	workDone := make(chan error, 1)
-	// The ATP protocol uses CBOR.
-	cborStdin := cbor.NewDecoder(stdin)
-	cborStdout := cbor.NewEncoder(stdout)
+	countingStdin := &countingReader{reader: stdin}
+	countingStdout := &countingWriter{writer: stdout}
 	doneChannel := make(chan bool, 1) // Buffer to prevent it from hanging if something unexpected happens.
 
-	// Cancel the sub context on sigint or sigterm.
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		select {
-		case <-sigs:
-			// Got sigterm. So cancel context.
-			cancel()
-		case <-subCtx.Done():
-			// Done. No sigterm.
-		}
-	}()
+	if !opts.ignoreOSSignals {
+		// Cancel the sub context on sigint or sigterm.
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			defer signal.Stop(sigs)
+			select {
+			case <-sigs:
+				// Got sigterm. So cancel context.
+				cancel()
+			case <-subCtx.Done():
+				// Done. No sigterm.
+			}
+		}()
+	}
 
 	return &atpServerSession{
-		ctx:          subCtx,
+		// Every session runs a single step execution, so the step data and the signals are scoped to the session.
+		ctx:          schema.ContextWithExecution(subCtx),
 		cancel:       &cancel,
 		req:          StartWorkMessage{},
-		cborStdin:    cborStdin,
-		cborStdout:   cborStdout,
+		stdin:        countingStdin,
+		stdout:       countingStdout,
+		decoder:      messageCodec.newDecoder(countingStdin, false),
+		encoder:      messageCodec.newEncoder(countingStdout),
+		codec:        messageCodec,
 		workDone:     workDone,
 		doneChannel:  doneChannel,
 		pluginSchema: pluginSchema,
-	}
+		options:      opts,
+	}, nil
 }
 
 func (s *atpServerSession) handleClosure(stdin io.ReadCloser) error {
@@ -97,12 +123,22 @@ func (s *atpServerSession) handleClosure(stdin io.ReadCloser) error {
 	return workError
 }
 
+// finish reports the result of the session. Only the first result counts, so it is safe to call from multiple
+// goroutines.
+func (s *atpServerSession) finish(err error) {
+	s.finishOnce.Do(func() {
+		s.workDone <- err
+	})
+}
+
 func (s *atpServerSession) runATPReadLoop() {
+	dispatcher := newMessageDispatcher(s.codec, ProtocolVersion, s.options.messageHandlers)
+	dispatcher.handlers[MessageTypeSignal] = s.handleSignal
 	// The message is generic, so we must find the type and decode the full message next.
 	var runtimeMessage DecodedRuntimeMessage
 	for {
 		// First, decode the message
-		if err := s.cborStdin.Decode(&runtimeMessage); err != nil {
+		if err := s.decoder.Decode(&runtimeMessage); err != nil {
 			// Failed to decode. If it's done, that's okay. If not, there's a problem.
 			done := false
 			select {
@@ -111,47 +147,50 @@ func (s *atpServerSession) runATPReadLoop() {
 				// Prevents it from blocking
 			}
 			if !done {
-				s.workDone <- fmt.Errorf("failed to read or decode runtime message: %w", err)
+				s.finish(fmt.Errorf("failed to read or decode runtime message: %w", err))
 			}
 			return
 		}
-		switch runtimeMessage.MessageID {
-		case MessageTypeSignal:
-			var signalMessage signalMessage
-			if err := cbor.Unmarshal(runtimeMessage.RawMessageData, &signalMessage); err != nil {
-				s.workDone <- fmt.Errorf("failed to decode signal message: %w", err)
-			}
-			if s.req.StepID != signalMessage.StepID {
-				s.workDone <- fmt.Errorf("signal sent with mismatched step ID, got %s, expected %s",
-					signalMessage.StepID, s.req.StepID)
-			}
-			if err := s.pluginSchema.CallSignal(s.ctx, signalMessage.StepID, signalMessage.SignalID, signalMessage.Data); err != nil {
-				s.workDone <- fmt.Errorf("failed while running signal ID %s: %w",
-					signalMessage.SignalID, err)
-			}
-		default:
-			s.workDone <- fmt.Errorf("unknown message ID received: %d", runtimeMessage.MessageID)
+		if _, err := dispatcher.dispatch(runtimeMessage); err != nil {
+			s.finish(err)
+			return
 		}
 	}
 }
 
+func (s *atpServerSession) handleSignal(data any) error {
+	atomic.AddUint64(&s.signalsReceived, 1)
+	signal := data.(*signalMessage)
+	if s.req.StepID != signal.StepID {
+		return fmt.Errorf("signal sent with mismatched step ID, got %s, expected %s", signal.StepID, s.req.StepID)
+	}
+	if err := s.pluginSchema.CallSignal(s.ctx, signal.StepID, signal.SignalID, signal.Data); err != nil {
+		return fmt.Errorf("failed while running signal ID %s: %w", signal.SignalID, err)
+	}
+	return nil
+}
+
+// SendMessage sends a runtime message to the client.
+func (s *atpServerSession) SendMessage(messageID uint32, data any) error {
+	return encodeMessage(s.encoder, &s.encoderLock, ProtocolVersion, messageID, data)
+}
+
 func (s *atpServerSession) run(wg *sync.WaitGroup) {
 	defer func() {
 		s.doneChannel <- true
-		close(s.workDone)
 		wg.Done()
 	}()
 
 	err := s.sendInitialMessagesToClient()
 	if err != nil {
-		s.workDone <- err
+		s.finish(err)
 		return
 	}
 
 	// Now, get the work message that dictates which step to run and the config info.
-	err = s.cborStdin.Decode(&s.req)
+	err = s.decoder.Decode(&s.req)
 	if err != nil {
-		s.workDone <- fmt.Errorf("failed to CBOR-decode start work message (%w)", err)
+		s.finish(fmt.Errorf("failed to decode start work message (%w)", err))
 		return
 	}
 
@@ -160,31 +199,72 @@ func (s *atpServerSession) run(wg *sync.WaitGroup) {
 		s.runATPReadLoop()
 	}()
 
+	// Pass the trace context on to the step, if the client sent one.
+	stepCtx := s.ctx
+	if traceContext := s.req.TraceContext(); traceContext != nil {
+		stepCtx = schema.ContextWithTraceContext(stepCtx, *traceContext)
+	}
+	timings := &schema.CallTimings{}
+	stepCtx = schema.ContextWithCallTimings(stepCtx, timings)
+	stepCtx = contextWithMessageSender(stepCtx, s)
+	stepCtx = context.WithValue(stepCtx, stepIDKey{}, s.req.StepID)
+	defer s.reportMetrics(timings)
+
 	// Call the step in the provided callable schema.
-	outputID, outputData, err := s.pluginSchema.CallStep(s.ctx, s.req.StepID, s.req.Config)
+	outputID, outputData, err := s.pluginSchema.CallStepWithSecrets(stepCtx, s.req.StepID, s.req.Config, s.req.Secrets)
 	if err != nil {
-		s.workDone <- err
+		s.finish(err)
 		return
 	}
 
 	// Lastly, send the work done message.
-	err = s.cborStdout.Encode(
-		RuntimeMessage{
-			MessageTypeWorkDone,
-			workDoneMessage{
-				outputID,
-				outputData,
-				"",
-			},
+	err = s.SendMessage(
+		MessageTypeWorkDone,
+		workDoneMessage{
+			outputID,
+			outputData,
+			"",
+			newWorkMetrics(timings),
 		},
 	)
 	if err != nil {
-		s.workDone <- fmt.Errorf("failed to encode CBOR response (%w)", err)
+		s.finish(fmt.Errorf("failed to encode work done message (%w)", err))
 		return
 	}
 
 	// finished with no error!
-	s.workDone <- nil
+	s.finish(nil)
+}
+
+type messageSenderKey struct{}
+
+func contextWithMessageSender(ctx context.Context, sender MessageSender) context.Context {
+	return context.WithValue(ctx, messageSenderKey{}, sender)
+}
+
+// MessageSenderFromContext returns the sender for runtime messages to the client when called from a step running in
+// an ATP server.
+func MessageSenderFromContext(ctx context.Context) (MessageSender, bool) {
+	sender, ok := ctx.Value(messageSenderKey{}).(MessageSender)
+	return sender, ok
+}
+
+type stepIDKey struct{}
+
+// StepIDFromContext returns the ID of the running step when called from a step running in an ATP server.
+func StepIDFromContext(ctx context.Context) (string, bool) {
+	stepID, ok := ctx.Value(stepIDKey{}).(string)
+	return stepID, ok
+}
+
+// SendSignal emits a signal of the step with the given ID to the client, using a sender returned by
+// MessageSenderFromContext. The data must already be serialized with the schema of the signal.
+func SendSignal(sender MessageSender, stepID string, signalID string, data any) error {
+	return sender.SendMessage(MessageTypeSignal, signalMessage{
+		StepID:   stepID,
+		SignalID: signalID,
+		Data:     data,
+	})
 }
 
 func (s *atpServerSession) sendInitialMessagesToClient() error {
@@ -194,17 +274,44 @@ func (s *atpServerSession) sendInitialMessagesToClient() error {
 		return err
 	}
 
-	// First, the start message, which is just an empty message.
-	var empty any
-	err = s.cborStdin.Decode(&empty)
+	// First, the start message. Older clients send an empty message, newer ones announce their capabilities.
+	var clientHello ClientHelloMessage
+	err = s.decoder.Decode(&clientHello)
 	if err != nil {
-		return fmt.Errorf("failed to CBOR-decode start output message (%w)", err)
+		return fmt.Errorf("failed to decode start output message (%w)", err)
+	}
+	if s.options.token != "" && subtle.ConstantTimeCompare([]byte(s.options.token), []byte(clientHello.Token)) != 1 {
+		return fmt.Errorf("the client did not present a valid token")
 	}
 
 	// Next, send the hello message, which includes the version and schema.
-	err = s.cborStdout.Encode(HelloMessage{ProtocolVersion, serializedSchema})
+	hello := HelloMessage{
+		Version: ProtocolVersion,
+		Schema:  serializedSchema,
+	}
+	if clientHello.HasCapability(CapabilityPluginMetadata) {
+		hello.Metadata = s.options.pluginMetadata
+	}
+	if len(clientHello.Capabilities) > 0 {
+		hello.Capabilities = serverCapabilities
+	}
+	err = s.encoder.Encode(hello)
 	if err != nil {
-		return fmt.Errorf("failed to CBOR-encode schema (%w)", err)
+		return fmt.Errorf("failed to encode schema (%w)", err)
 	}
 	return nil
 }
+
+// reportMetrics passes the metrics of the current step execution to the metrics hook, if any.
+func (s *atpServerSession) reportMetrics(timings *schema.CallTimings) {
+	if s.options.metricsHook == nil {
+		return
+	}
+	metrics := ExecutionMetrics{
+		BytesIn:         s.stdin.bytes(),
+		BytesOut:        s.stdout.bytes(),
+		SignalsReceived: atomic.LoadUint64(&s.signalsReceived),
+	}
+	newWorkMetrics(timings).applyTo(&metrics)
+	s.options.metricsHook(s.req.StepID, metrics)
+}
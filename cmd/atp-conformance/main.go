@@ -0,0 +1,123 @@
+// Command atp-conformance checks that a plugin executable speaks the Arcaflow Transport Protocol correctly.
+// Usage:
+//
+//	atp-conformance [-timeout 30s] [-input step=input.yaml]... [-signal-input step.signal=data.yaml]... [-v]
+//	    plugin-executable [args...]
+//
+// If no arguments are given after the executable, --atp is passed to it. The command exits with a non-zero exit code
+// if any check fails.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	log "go.arcalot.io/log/v2"
+	"go.flow.arcalot.io/pluginsdk/conformance"
+	"gopkg.in/yaml.v3"
+)
+
+// stepInputs collects the repeatable -input flag.
+type stepInputs map[string]any
+
+func (s stepInputs) String() string {
+	return ""
+}
+
+func (s stepInputs) Set(value string) error {
+	stepID, file, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("expected step=file, got %s", value)
+	}
+	input, err := readYAMLFile(file)
+	if err != nil {
+		return err
+	}
+	s[stepID] = input
+	return nil
+}
+
+// signalInputs collects the repeatable -signal-input flag.
+type signalInputs map[string]map[string]any
+
+func (s signalInputs) String() string {
+	return ""
+}
+
+func (s signalInputs) Set(value string) error {
+	id, file, ok := strings.Cut(value, "=")
+	stepID, signalID, hasSignal := strings.Cut(id, ".")
+	if !ok || !hasSignal {
+		return fmt.Errorf("expected step.signal=file, got %s", value)
+	}
+	data, err := readYAMLFile(file)
+	if err != nil {
+		return err
+	}
+	if s[stepID] == nil {
+		s[stepID] = map[string]any{}
+	}
+	s[stepID][signalID] = data
+	return nil
+}
+
+func readYAMLFile(file string) (any, error) {
+	data, err := os.ReadFile(file) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	var result any
+	if err := yaml.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse %s (%w)", file, err)
+	}
+	return result, nil
+}
+
+func main() {
+	inputs := stepInputs{}
+	signals := signalInputs{}
+	flags := flag.NewFlagSet("atp-conformance", flag.ExitOnError)
+	timeout := flags.Duration("timeout", conformance.DefaultTimeout, "Time a single check may take.")
+	flags.Var(inputs, "input", "Valid input for a step as step=file.yaml, used to run the step. Can be repeated.")
+	flags.Var(
+		signals,
+		"signal-input",
+		"Data for a signal sent to a running step as step.signal=file.yaml. Signals without data get an empty "+
+			"object. Can be repeated.",
+	)
+	verbose := flags.Bool("v", false, "Show the standard error of the plugin and the ATP client logs.")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(flags.Output(), "Usage: atp-conformance [options] plugin-executable [args...]")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(os.Args[1:])
+	if flags.NArg() < 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	target := conformance.ExecutableTarget{
+		Path: flags.Arg(0),
+		Args: flags.Args()[1:],
+	}
+	config := conformance.Config{
+		StepInputs:   inputs,
+		SignalInputs: signals,
+		Timeout:      *timeout,
+	}
+	if *verbose {
+		target.Stderr = os.Stderr
+		config.Logger = log.NewGoLogger(log.LevelDebug)
+	}
+
+	report := conformance.Run(context.Background(), target, config)
+	for _, result := range report.Results {
+		fmt.Printf("%-7s %s: %s\n", strings.ToUpper(string(result.Status)), result.Check, result.Message)
+	}
+	if report.Failed() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,357 @@
+// Package conformance checks that a plugin speaks the Arcaflow Transport Protocol correctly. It works against plugin
+// executables written with any SDK, as well as against any ATP ClientChannel.
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	log "go.arcalot.io/log/v2"
+	"go.flow.arcalot.io/pluginsdk/atp"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// DefaultTimeout is the time a single check may take, unless configured otherwise.
+const DefaultTimeout = 30 * time.Second
+
+// Config configures the conformance suite.
+type Config struct {
+	// StepInputs holds valid serialized input data for steps, keyed by step ID. Checks that need to run a step
+	// successfully are skipped for steps without input.
+	StepInputs map[string]any
+	// SignalInputs holds serialized data for the signals sent to running steps, keyed by step ID and then signal ID.
+	// Signals without data get an empty object.
+	SignalInputs map[string]map[string]any
+	// Timeout is the time a single check may take. Defaults to DefaultTimeout.
+	Timeout time.Duration
+	// Logger receives the ATP client logs. If nil, they are discarded.
+	Logger log.Logger
+}
+
+// Status is the outcome of a single check.
+type Status string
+
+const (
+	// StatusPassed indicates that the plugin behaved correctly.
+	StatusPassed Status = "passed"
+	// StatusFailed indicates that the plugin violated the protocol.
+	StatusFailed Status = "failed"
+	// StatusSkipped indicates that the check could not run, for example because no step input was configured.
+	StatusSkipped Status = "skipped"
+)
+
+// Result is the outcome of a single check.
+type Result struct {
+	// Check is the name of the check.
+	Check string
+	// Status is the outcome of the check.
+	Status Status
+	// Message explains the outcome.
+	Message string
+}
+
+// Report holds the results of all checks in the order they ran.
+type Report struct {
+	Results []Result
+}
+
+// Failed returns true if any of the checks failed.
+func (r Report) Failed() bool {
+	for _, result := range r.Results {
+		if result.Status == StatusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// Run runs the conformance suite against the target. It checks the hello message and version, the validity of the
+// schema, the shutdown of the plugin when its input is closed, the rejection of unknown step IDs and of invalid
+// input, and the routing of signals to running steps.
+func Run(ctx context.Context, target Target, config Config) Report {
+	if config.Timeout == 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.Logger == nil {
+		config.Logger = log.NewLogger(log.LevelDebug, log.NewNOOPLogger())
+	}
+	s := &suite{
+		target: target,
+		config: config,
+	}
+
+	s.checkHandshake(ctx)
+	if s.schema == nil {
+		// Without a schema none of the other checks can run.
+		return s.report
+	}
+	s.checkUnknownStep(ctx)
+	s.checkInvalidInput(ctx)
+	s.checkSignalRouting(ctx)
+	return s.report
+}
+
+type suite struct {
+	target Target
+	config Config
+	schema *schema.SchemaSchema
+	report Report
+}
+
+func (s *suite) add(check string, status Status, format string, args ...any) {
+	s.report.Results = append(s.report.Results, Result{
+		Check:   check,
+		Status:  status,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (s *suite) newClient(channel atp.ClientChannel) atp.Client {
+	return atp.NewClientWithLogger(channel, s.config.Logger)
+}
+
+// session opens a new session and runs the function with a timeout. The channel is closed when the function returns
+// or the timeout is reached, whichever comes first. The returned error is the result of closing the channel.
+func (s *suite) session(ctx context.Context, f func(channel atp.ClientChannel)) (timedOut bool, closeErr error, err error) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+	channel, err := s.target.Open(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f(channel)
+	}()
+	select {
+	case <-done:
+		return false, channel.Close(), nil
+	case <-ctx.Done():
+		closeErr = channel.Close()
+		<-done
+		return true, closeErr, nil
+	}
+}
+
+// stepIDs returns the step IDs in a stable order.
+func (s *suite) stepIDs() []string {
+	steps := s.schema.Steps()
+	result := make([]string, 0, len(steps))
+	for stepID := range steps {
+		result = append(result, stepID)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (s *suite) checkHandshake(ctx context.Context) {
+	var hello atp.HelloMessage
+	var helloErr error
+	timedOut, closeErr, err := s.session(ctx, func(channel atp.ClientChannel) {
+		cli := s.newClient(channel)
+		if helloErr = cli.Encoder().Encode(atp.ClientHelloMessage{}); helloErr != nil {
+			return
+		}
+		helloErr = cli.Decoder().Decode(&hello)
+	})
+	switch {
+	case err != nil:
+		s.add("handshake", StatusFailed, "failed to start the plugin: %v", err)
+		return
+	case timedOut:
+		s.add("handshake", StatusFailed, "no hello message within %s", s.config.Timeout)
+		return
+	case helloErr != nil:
+		s.add("handshake", StatusFailed, "failed to exchange the hello message: %v", helloErr)
+		return
+	case hello.Version < atp.MinSupportedATPVersion || hello.Version > atp.MaxSupportedATPVersion:
+		s.add("handshake", StatusFailed, "unsupported ATP version %d, expected between %d and %d",
+			hello.Version, atp.MinSupportedATPVersion, atp.MaxSupportedATPVersion)
+		return
+	}
+	s.add("handshake", StatusPassed, "ATP version %d", hello.Version)
+
+	// The hello session ended without a start work message, so the plugin must have shut down. Plugins may report
+	// the missing work as an error, the Go SDK does, so only a plugin that keeps running fails the check.
+	var shutdownTimeoutErr ShutdownTimeoutError
+	switch {
+	case errors.As(closeErr, &shutdownTimeoutErr):
+		s.add("shutdown on EOF", StatusFailed, "%v", closeErr)
+	case closeErr != nil:
+		s.add("shutdown on EOF", StatusPassed, "the plugin shut down after its input was closed (%v)", closeErr)
+	default:
+		s.add("shutdown on EOF", StatusPassed, "the plugin shut down cleanly after its input was closed")
+	}
+
+	s.checkSchema(hello.Schema)
+}
+
+func (s *suite) checkSchema(serializedSchema any) {
+	pluginSchema, err := schema.UnserializeSchema(serializedSchema)
+	if err != nil {
+		s.add("schema", StatusFailed, "invalid schema: %v", err)
+		return
+	}
+	if len(pluginSchema.Steps()) == 0 {
+		s.add("schema", StatusFailed, "the schema has no steps")
+		return
+	}
+	for stepID, step := range pluginSchema.Steps() {
+		if step.ID() != stepID {
+			s.add("schema", StatusFailed, "step %s is listed under the ID %s", step.ID(), stepID)
+			return
+		}
+		if len(step.Outputs()) == 0 {
+			s.add("schema", StatusFailed, "step %s has no outputs", stepID)
+			return
+		}
+	}
+	s.schema = pluginSchema
+	s.add("schema", StatusPassed, "%d valid step(s)", len(pluginSchema.Steps()))
+}
+
+func (s *suite) checkUnknownStep(ctx context.Context) {
+	const unknownStepID = "conformance-unknown-step"
+	if _, ok := s.schema.Steps()[unknownStepID]; ok {
+		s.add("unknown step", StatusSkipped, "the plugin has a step called %s", unknownStepID)
+		return
+	}
+	var result atp.ExecutionResult
+	var executeErr error
+	timedOut, _, err := s.session(ctx, func(channel atp.ClientChannel) {
+		result, executeErr = s.execute(channel, schema.Input{ID: unknownStepID, InputData: map[string]any{}}, nil, nil)
+	})
+	switch {
+	case err != nil:
+		s.add("unknown step", StatusFailed, "failed to start the plugin: %v", err)
+	case timedOut:
+		s.add("unknown step", StatusFailed, "the plugin did not reject the unknown step within %s", s.config.Timeout)
+	case executeErr == nil:
+		s.add("unknown step", StatusFailed, "the plugin ran an unknown step and returned the output %s", result.OutputID)
+	default:
+		s.add("unknown step", StatusPassed, "the plugin rejected the unknown step")
+	}
+}
+
+func (s *suite) checkInvalidInput(ctx context.Context) {
+	for _, stepID := range s.stepIDs() {
+		check := "invalid input: " + stepID
+		var result atp.ExecutionResult
+		var executeErr error
+		// No step input schema accepts a plain string as its root object.
+		input := schema.Input{ID: stepID, InputData: "conformance-invalid-input"}
+		timedOut, _, err := s.session(ctx, func(channel atp.ClientChannel) {
+			result, executeErr = s.execute(channel, input, nil, nil)
+		})
+		switch {
+		case err != nil:
+			s.add(check, StatusFailed, "failed to start the plugin: %v", err)
+		case timedOut:
+			s.add(check, StatusFailed, "the plugin did not reject the invalid input within %s", s.config.Timeout)
+		case executeErr != nil:
+			s.add(check, StatusPassed, "the plugin rejected the invalid input")
+		case s.isErrorOutput(stepID, result.OutputID):
+			s.add(check, StatusPassed, "the plugin returned the error output %s", result.OutputID)
+		default:
+			s.add(check, StatusFailed, "the plugin accepted invalid input and returned the output %s", result.OutputID)
+		}
+	}
+}
+
+func (s *suite) checkSignalRouting(ctx context.Context) {
+	for _, stepID := range s.stepIDs() {
+		step := s.schema.Steps()[stepID]
+		if len(step.SignalHandlers()) == 0 && len(step.SignalEmitters()) == 0 {
+			continue
+		}
+		check := "signal routing: " + stepID
+		inputData, ok := s.config.StepInputs[stepID]
+		if !ok {
+			s.add(check, StatusSkipped, "no input configured for step %s", stepID)
+			continue
+		}
+
+		signalIDs := make([]string, 0, len(step.SignalHandlers()))
+		for signalID := range step.SignalHandlers() {
+			signalIDs = append(signalIDs, signalID)
+		}
+		sort.Strings(signalIDs)
+		// The client closes the channel when the step is done, so all signals are queued up front.
+		receivedSignals := make(chan schema.Input, len(signalIDs))
+		for _, signalID := range signalIDs {
+			signalData, ok := s.config.SignalInputs[stepID][signalID]
+			if !ok {
+				signalData = map[string]any{}
+			}
+			receivedSignals <- schema.Input{ID: signalID, InputData: signalData}
+		}
+		emittedSignals := make(chan schema.Input)
+		var emitted []string
+		emittedDone := make(chan struct{})
+		go func() {
+			defer close(emittedDone)
+			for signal := range emittedSignals {
+				emitted = append(emitted, signal.ID)
+			}
+		}()
+
+		var result atp.ExecutionResult
+		var executeErr error
+		timedOut, _, err := s.session(ctx, func(channel atp.ClientChannel) {
+			result, executeErr = s.execute(channel, schema.Input{ID: stepID, InputData: inputData}, receivedSignals, emittedSignals)
+		})
+		close(emittedSignals)
+		<-emittedDone
+
+		switch {
+		case err != nil:
+			s.add(check, StatusFailed, "failed to start the plugin: %v", err)
+			continue
+		case timedOut:
+			s.add(check, StatusFailed, "the step did not finish within %s", s.config.Timeout)
+			continue
+		case executeErr != nil:
+			s.add(check, StatusFailed, "the step failed after receiving %d signal(s): %v", len(signalIDs), executeErr)
+			continue
+		}
+		if _, ok := step.Outputs()[result.OutputID]; !ok {
+			s.add(check, StatusFailed, "the step returned the undeclared output %s", result.OutputID)
+			continue
+		}
+		undeclared := ""
+		for _, signalID := range emitted {
+			if _, ok := step.SignalEmitters()[signalID]; !ok {
+				undeclared = signalID
+				break
+			}
+		}
+		if undeclared != "" {
+			s.add(check, StatusFailed, "the step emitted the undeclared signal %s", undeclared)
+			continue
+		}
+		s.add(check, StatusPassed, "sent %d signal(s), received %d signal(s), output %s",
+			len(signalIDs), len(emitted), result.OutputID)
+	}
+}
+
+func (s *suite) execute(
+	channel atp.ClientChannel,
+	input schema.Input,
+	receivedSignals chan schema.Input,
+	emittedSignals chan<- schema.Input,
+) (atp.ExecutionResult, error) {
+	cli := s.newClient(channel)
+	if _, err := cli.ReadSchema(); err != nil {
+		return atp.ExecutionResult{}, err
+	}
+	return cli.ExecuteWithMetrics(input, receivedSignals, emittedSignals)
+}
+
+func (s *suite) isErrorOutput(stepID string, outputID string) bool {
+	output, ok := s.schema.Steps()[stepID].Outputs()[outputID]
+	return ok && output.Error()
+}
@@ -0,0 +1,195 @@
+package conformance_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"go.arcalot.io/assert"
+	"go.arcalot.io/log/v2"
+	"go.flow.arcalot.io/pluginsdk/atp"
+	"go.flow.arcalot.io/pluginsdk/conformance"
+	"go.flow.arcalot.io/pluginsdk/plugin"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+type waitInput struct {
+	Seconds int64 `json:"seconds"`
+}
+
+type waitData struct {
+	cancelled chan struct{}
+}
+
+var waitSchema = schema.NewCallableSchema(
+	schema.NewCallableStepWithSignals[*waitData, waitInput](
+		"wait",
+		schema.NewScopeSchema(
+			schema.NewStructMappedObjectSchema[waitInput](
+				"Input",
+				map[string]*schema.PropertySchema{
+					"seconds": schema.NewPropertySchema(
+						schema.NewIntSchema(schema.IntPointer(0), nil, nil),
+						nil,
+						true,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+					),
+				},
+			),
+		),
+		map[string]*schema.StepOutputSchema{
+			"done": schema.NewStepOutputSchema(
+				schema.NewScopeSchema(schema.NewObjectSchema("Done", map[string]*schema.PropertySchema{})),
+				nil,
+				false,
+			),
+			"cancelled": schema.NewStepOutputSchema(
+				schema.NewScopeSchema(schema.NewObjectSchema("Cancelled", map[string]*schema.PropertySchema{})),
+				nil,
+				true,
+			),
+		},
+		map[string]schema.CallableSignal{
+			plugin.CancellationSignalSchema.ID(): schema.NewCallableSignalFromSchema(
+				plugin.CancellationSignalSchema,
+				func(_ context.Context, data *waitData, _ plugin.CancelInput) {
+					close(data.cancelled)
+				},
+			),
+		},
+		nil,
+		nil,
+		func() *waitData {
+			return &waitData{cancelled: make(chan struct{})}
+		},
+		func(_ context.Context, data *waitData, input waitInput) (string, any) {
+			select {
+			case <-time.After(time.Duration(input.Seconds) * time.Second):
+				return "done", map[string]any{}
+			case <-data.cancelled:
+				return "cancelled", map[string]any{}
+			}
+		},
+	),
+)
+
+func TestRun_SchemaTarget(t *testing.T) {
+	report := conformance.Run(
+		context.Background(),
+		conformance.SchemaTarget{Schema: waitSchema},
+		conformance.Config{
+			StepInputs: map[string]any{"wait": map[string]any{"seconds": 10}},
+			Timeout:    5 * time.Second,
+			Logger:     log.NewTestLogger(t),
+		},
+	)
+	for _, result := range report.Results {
+		t.Logf("%s %s: %s", result.Status, result.Check, result.Message)
+	}
+	assert.Equals(t, report.Failed(), false)
+	checks := map[string]conformance.Status{}
+	for _, result := range report.Results {
+		checks[result.Check] = result.Status
+	}
+	assert.Equals(t, checks, map[string]conformance.Status{
+		"handshake":            conformance.StatusPassed,
+		"shutdown on EOF":      conformance.StatusPassed,
+		"schema":               conformance.StatusPassed,
+		"unknown step":         conformance.StatusPassed,
+		"invalid input: wait":  conformance.StatusPassed,
+		"signal routing: wait": conformance.StatusPassed,
+	})
+}
+
+func TestRun_SchemaTargetTwice(t *testing.T) {
+	// Both runs cancel the same callable step, which only works if every execution gets its own step data.
+	for i := 0; i < 2; i++ {
+		report := conformance.Run(
+			context.Background(),
+			conformance.SchemaTarget{Schema: waitSchema},
+			conformance.Config{
+				StepInputs: map[string]any{"wait": map[string]any{"seconds": 10}},
+				Timeout:    5 * time.Second,
+				Logger:     log.NewTestLogger(t),
+			},
+		)
+		assert.Equals(t, report.Failed(), false)
+	}
+}
+
+func TestRun_SkipsSignalRoutingWithoutInput(t *testing.T) {
+	report := conformance.Run(
+		context.Background(),
+		conformance.SchemaTarget{Schema: waitSchema},
+		conformance.Config{Logger: log.NewTestLogger(t)},
+	)
+	assert.Equals(t, report.Failed(), false)
+	last := report.Results[len(report.Results)-1]
+	assert.Equals(t, last.Check, "signal routing: wait")
+	assert.Equals(t, last.Status, conformance.StatusSkipped)
+}
+
+// hangingChannel pretends the plugin did not shut down after its input was closed.
+type hangingChannel struct {
+	atp.ClientChannel
+}
+
+func (h hangingChannel) Close() error {
+	_ = h.ClientChannel.Close()
+	return conformance.ShutdownTimeoutError{Timeout: time.Second}
+}
+
+func TestRun_ShutdownTimeout(t *testing.T) {
+	target := conformance.TargetFunc(func(ctx context.Context) (atp.ClientChannel, error) {
+		channel, err := conformance.SchemaTarget{Schema: waitSchema}.Open(ctx)
+		return hangingChannel{channel}, err
+	})
+	report := conformance.Run(context.Background(), target, conformance.Config{Logger: log.NewTestLogger(t)})
+	assert.Equals(t, report.Failed(), true)
+	assert.Equals(t, report.Results[1].Check, "shutdown on EOF")
+	assert.Equals(t, report.Results[1].Status, conformance.StatusFailed)
+}
+
+func TestRun_UnsupportedVersion(t *testing.T) {
+	target := conformance.TargetFunc(func(ctx context.Context) (atp.ClientChannel, error) {
+		stdinReader, stdinWriter := io.Pipe()
+		stdoutReader, stdoutWriter := io.Pipe()
+		go func() {
+			var clientHello any
+			_ = cbor.NewDecoder(stdinReader).Decode(&clientHello)
+			_ = cbor.NewEncoder(stdoutWriter).Encode(atp.HelloMessage{Version: 99})
+		}()
+		return pipeChannel{stdoutReader, stdinWriter}, nil
+	})
+	report := conformance.Run(context.Background(), target, conformance.Config{
+		Timeout: time.Second,
+		Logger:  log.NewTestLogger(t),
+	})
+	assert.Equals(t, report.Failed(), true)
+	assert.Equals(t, len(report.Results), 1)
+	assert.Equals(t, report.Results[0].Check, "handshake")
+}
+
+type pipeChannel struct {
+	*io.PipeReader
+	*io.PipeWriter
+}
+
+func (p pipeChannel) Read(b []byte) (int, error) {
+	return p.PipeReader.Read(b)
+}
+
+func (p pipeChannel) Write(b []byte) (int, error) {
+	return p.PipeWriter.Write(b)
+}
+
+func (p pipeChannel) Close() error {
+	_ = p.PipeReader.Close()
+	return p.PipeWriter.Close()
+}
@@ -0,0 +1,194 @@
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+
+	"go.flow.arcalot.io/pluginsdk/atp"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// DefaultShutdownTimeout is the time a plugin has to shut down after its input is closed, unless configured
+// otherwise.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// Target starts ATP sessions with the plugin under test. Each check opens a new session, as an ATP session can only
+// execute a single step.
+//
+// Closing the returned channel closes the input of the plugin. Close must return an error if the plugin does not
+// shut down cleanly afterwards, and a ShutdownTimeoutError if it does not shut down at all within a timeout.
+type Target interface {
+	Open(ctx context.Context) (atp.ClientChannel, error)
+}
+
+// ShutdownTimeoutError is returned by the channels of the targets in this package when the plugin did not shut down
+// within the shutdown timeout after its input was closed.
+type ShutdownTimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e ShutdownTimeoutError) Error() string {
+	return fmt.Sprintf("the plugin did not shut down within %s after its input was closed", e.Timeout)
+}
+
+// TargetFunc adapts a function to the Target interface. Use it to test plugins reachable through any ClientChannel.
+type TargetFunc func(ctx context.Context) (atp.ClientChannel, error)
+
+// Open calls the function.
+func (t TargetFunc) Open(ctx context.Context) (atp.ClientChannel, error) {
+	return t(ctx)
+}
+
+// ExecutableTarget starts the plugin executable for each session and talks ATP over its standard input and output.
+// This works with plugins written with any SDK.
+type ExecutableTarget struct {
+	// Path is the path of the plugin executable.
+	Path string
+	// Args are the arguments to start the ATP server with. Defaults to --atp.
+	Args []string
+	// Stderr receives the standard error of the plugin. If nil, it is discarded.
+	Stderr io.Writer
+	// ShutdownTimeout is the time the plugin has to exit after its input is closed. Defaults to
+	// DefaultShutdownTimeout.
+	ShutdownTimeout time.Duration
+}
+
+// Open starts the plugin executable.
+func (e ExecutableTarget) Open(_ context.Context) (atp.ClientChannel, error) {
+	args := e.Args
+	if len(args) == 0 {
+		args = []string{"--atp"}
+	}
+	cmd := exec.Command(e.Path, args...) //nolint:gosec
+	cmd.Stderr = e.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s (%w)", e.Path, err)
+	}
+	return &processChannel{
+		cmd:             cmd,
+		stdin:           stdin,
+		stdout:          stdout,
+		shutdownTimeout: shutdownTimeout(e.ShutdownTimeout),
+	}, nil
+}
+
+type processChannel struct {
+	cmd             *exec.Cmd
+	stdin           io.WriteCloser
+	stdout          io.ReadCloser
+	shutdownTimeout time.Duration
+}
+
+func (p *processChannel) Read(b []byte) (int, error) {
+	return p.stdout.Read(b)
+}
+
+func (p *processChannel) Write(b []byte) (int, error) {
+	return p.stdin.Write(b)
+}
+
+// Close closes the standard input of the plugin and waits for it to exit. The plugin is killed if it doesn't exit
+// within the shutdown timeout.
+func (p *processChannel) Close() error {
+	_ = p.stdin.Close()
+	done := make(chan error, 1)
+	go func() {
+		done <- p.cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("plugin exited with an error after its input was closed (%w)", err)
+		}
+		return nil
+	case <-time.After(p.shutdownTimeout):
+		_ = p.cmd.Process.Kill()
+		<-done
+		return ShutdownTimeoutError{p.shutdownTimeout}
+	}
+}
+
+// SchemaTarget runs the ATP server for a callable schema in the current process. Use it to check Go plugins from
+// their unit tests.
+type SchemaTarget struct {
+	// Schema is the schema of the plugin.
+	Schema *schema.CallableSchema
+	// Options are passed to the ATP server.
+	Options []atp.Option
+	// ShutdownTimeout is the time the server has to return after its input is closed. Defaults to
+	// DefaultShutdownTimeout.
+	ShutdownTimeout time.Duration
+}
+
+// Open starts the ATP server.
+func (s SchemaTarget) Open(ctx context.Context) (atp.ClientChannel, error) {
+	stdinReader, stdinWriter := io.Pipe()
+	stdoutReader, stdoutWriter := io.Pipe()
+	channel := &schemaChannel{
+		stdin:           stdinWriter,
+		stdout:          stdoutReader,
+		done:            make(chan error, 1),
+		shutdownTimeout: shutdownTimeout(s.ShutdownTimeout),
+	}
+	opts := append(append([]atp.Option{}, s.Options...), atp.WithoutOSSignalHandling())
+	go func() {
+		err := atp.RunATPServer(ctx, stdinReader, stdoutWriter, s.Schema, opts...)
+		if err != nil {
+			_ = stdoutWriter.CloseWithError(err)
+		} else {
+			_ = stdoutWriter.Close()
+		}
+		channel.done <- err
+	}()
+	return channel, nil
+}
+
+type schemaChannel struct {
+	stdin           *io.PipeWriter
+	stdout          *io.PipeReader
+	done            chan error
+	shutdownTimeout time.Duration
+}
+
+func (s *schemaChannel) Read(b []byte) (int, error) {
+	return s.stdout.Read(b)
+}
+
+func (s *schemaChannel) Write(b []byte) (int, error) {
+	return s.stdin.Write(b)
+}
+
+// Close closes the input of the server and waits for it to return.
+func (s *schemaChannel) Close() error {
+	_ = s.stdin.Close()
+	defer func() {
+		_ = s.stdout.Close()
+	}()
+	select {
+	case err := <-s.done:
+		if err != nil {
+			return fmt.Errorf("ATP server failed (%w)", err)
+		}
+		return nil
+	case <-time.After(s.shutdownTimeout):
+		return ShutdownTimeoutError{s.shutdownTimeout}
+	}
+}
+
+func shutdownTimeout(timeout time.Duration) time.Duration {
+	if timeout == 0 {
+		return DefaultShutdownTimeout
+	}
+	return timeout
+}
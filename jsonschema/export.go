@@ -0,0 +1,600 @@
+// Package jsonschema converts between the Arcaflow schema system and JSON Schema (draft 2020-12).
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// Draft is the JSON Schema dialect of the exported documents.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// defsPrefix is the JSON pointer prefix of references to object definitions.
+const defsPrefix = "#/$defs/"
+
+// FromScope exports a scope as a JSON Schema document. Every object reachable from the root object is placed in
+// $defs, and the document references the root object. String patterns are converted from the Go RE2 syntax to the
+// ECMA-262 syntax JSON Schema uses. Patterns that cannot be converted, like classes of characters outside the basic
+// multilingual plane, cause an error.
+func FromScope(scope schema.Scope) (map[string]any, error) {
+	e := newExporter()
+	ref, err := e.scope(scope)
+	if err != nil {
+		return nil, err
+	}
+	return e.document(ref), nil
+}
+
+// FromStepInput exports the input of a step as a JSON Schema document, titled after the step.
+func FromStepInput(step schema.Step) (map[string]any, error) {
+	result, err := FromScope(step.Input())
+	if err != nil {
+		return nil, fmt.Errorf("failed to export the input of step %s (%w)", step.ID(), err)
+	}
+	addDisplay(result, step.Display())
+	return result, nil
+}
+
+// FromStepOutput exports one output of a step as a JSON Schema document, titled after the output.
+func FromStepOutput(step schema.Step, outputID string) (map[string]any, error) {
+	output, ok := step.Outputs()[outputID]
+	if !ok {
+		outputIDs := make([]string, 0, len(step.Outputs()))
+		for id := range step.Outputs() {
+			outputIDs = append(outputIDs, id)
+		}
+		sort.Strings(outputIDs)
+		return nil, fmt.Errorf(
+			"step %s has no output %s (available outputs: %s)",
+			step.ID(),
+			outputID,
+			strings.Join(outputIDs, ", "),
+		)
+	}
+	result, err := FromScope(output.Schema())
+	if err != nil {
+		return nil, fmt.Errorf("failed to export output %s of step %s (%w)", outputID, step.ID(), err)
+	}
+	if display := output.Display(); display != nil {
+		addDisplay(result, display)
+	}
+	return result, nil
+}
+
+// Definitions exports several scopes into one set of shared definitions, for documents embedding more than one
+// schema, such as OpenAPI documents. Objects shared between the scopes are exported only once.
+type Definitions struct {
+	e *exporter
+}
+
+// NewDefinitions creates an empty set of definitions. The references to the definitions are prefixed with refPrefix,
+// for example "#/components/schemas/".
+func NewDefinitions(refPrefix string) *Definitions {
+	e := newExporter()
+	e.refPrefix = refPrefix
+	return &Definitions{e: e}
+}
+
+// Scope exports the objects of the scope into the definitions and returns a reference to the root object.
+func (d *Definitions) Scope(scope schema.Scope) (map[string]any, error) {
+	return d.e.scope(scope)
+}
+
+// Add adds a definition under the given name, or under a free variant of it if the name is taken, and returns a
+// reference to it.
+func (d *Definitions) Add(name string, definition map[string]any) map[string]any {
+	name = d.e.defName(name)
+	d.e.defs[name] = definition
+	return map[string]any{"$ref": d.e.refPrefix + name}
+}
+
+// Map returns the definitions by name.
+func (d *Definitions) Map() map[string]any {
+	return d.e.defs
+}
+
+// exporter collects the definitions of a single document.
+type exporter struct {
+	refPrefix string
+	defs      map[string]any
+	// names holds the definition name of each exported object. Objects of nested scopes may share an ID with objects
+	// of the outer scope, so objects are tracked by identity rather than by ID.
+	names map[schema.Object]string
+}
+
+func newExporter() *exporter {
+	return &exporter{
+		refPrefix: defsPrefix,
+		defs:      map[string]any{},
+		names:     map[schema.Object]string{},
+	}
+}
+
+func (e *exporter) document(root map[string]any) map[string]any {
+	result := map[string]any{
+		"$schema": Draft,
+	}
+	for key, value := range root {
+		result[key] = value
+	}
+	if len(e.defs) > 0 {
+		result["$defs"] = e.defs
+	}
+	return result
+}
+
+func (e *exporter) convert(t schema.Type) (map[string]any, error) {
+	switch t.TypeID() {
+	case schema.TypeIDString:
+		return e.string(t.(schema.String))
+	case schema.TypeIDPattern:
+		// The values are parsed by Go, so unlike patterns they are not converted to ECMA-262.
+		return map[string]any{
+			"type":     "string",
+			"format":   "regex",
+			"$comment": "The value is a regular expression in the Go RE2 syntax.",
+		}, nil
+	case schema.TypeIDInt:
+		i := t.(schema.Int)
+		return numeric("integer", i.Min(), i.Max(), i.Units()), nil
+	case schema.TypeIDFloat:
+		f := t.(schema.Float)
+		return numeric("number", f.Min(), f.Max(), f.Units()), nil
+	case schema.TypeIDBool:
+		return map[string]any{"type": "boolean"}, nil
+	case schema.TypeIDStringEnum:
+		return enum("string", t.(schema.Enum[string]).ValidValues(), nil), nil
+	case schema.TypeIDIntEnum:
+		intEnum := t.(schema.Enum[int64])
+		var units *schema.UnitsDefinition
+		if withUnits, ok := t.(interface {
+			Units() *schema.UnitsDefinition
+		}); ok {
+			units = withUnits.Units()
+		}
+		return enum("integer", intEnum.ValidValues(), units), nil
+	case schema.TypeIDList:
+		return e.list(t)
+	case schema.TypeIDMap:
+		return e.mapType(t)
+	case schema.TypeIDObject:
+		return e.object(t.(schema.Object))
+	case schema.TypeIDRef:
+		ref := t.(schema.Ref)
+		result, err := e.object(ref.GetObject())
+		if err != nil {
+			return nil, err
+		}
+		addDisplay(result, ref.Display())
+		return result, nil
+	case schema.TypeIDScope:
+		return e.scope(t.(schema.Scope))
+	case schema.TypeIDOneOfString:
+		return oneOf(e, t.(schema.OneOf[string]))
+	case schema.TypeIDOneOfInt:
+		return oneOf(e, t.(schema.OneOf[int64]))
+	case schema.TypeIDAny:
+		return map[string]any{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type: %s", t.TypeID())
+	}
+}
+
+func (e *exporter) string(s schema.String) (map[string]any, error) {
+	result := map[string]any{"type": "string"}
+	if s.Min() != nil {
+		result["minLength"] = *s.Min()
+	}
+	if s.Max() != nil {
+		result["maxLength"] = *s.Max()
+	}
+	if s.Pattern() != nil {
+		pattern, err := ecmaPattern(s.Pattern())
+		if err != nil {
+			return nil, err
+		}
+		result["pattern"] = pattern
+	}
+	return result, nil
+}
+
+// numeric exports integers and floats. Numbers with units are also accepted as strings holding the amount with
+// units, for example 5m30s, so those are allowed as strings matching the units.
+func numeric[T int64 | float64](jsonType string, min *T, max *T, units *schema.UnitsDefinition) map[string]any {
+	result := map[string]any{"type": jsonType}
+	if min != nil {
+		result["minimum"] = *min
+	}
+	if max != nil {
+		result["maximum"] = *max
+	}
+	if units != nil {
+		result["type"] = []any{jsonType, "string"}
+		result["pattern"] = unitsPattern(units)
+		result["description"] = unitsDescription(units)
+	}
+	return result
+}
+
+// unitsPattern returns a regular expression matching amounts in the given units. It is less strict than the parser
+// of the units, which also enforces the order of the multipliers.
+func unitsPattern(units *schema.UnitsDefinition) string {
+	var names []string
+	addNames := func(unit *schema.UnitDefinition) {
+		names = append(
+			names,
+			regexp.QuoteMeta(unit.NameShortSingular()),
+			regexp.QuoteMeta(unit.NameShortPlural()),
+			regexp.QuoteMeta(unit.NameLongSingular()),
+			regexp.QuoteMeta(unit.NameLongPlural()),
+		)
+	}
+	for _, multiplier := range sortedMultipliers(units) {
+		addNames(units.Multipliers()[multiplier])
+	}
+	addNames(units.BaseUnit())
+	// Longer names first, so that the alternation prefers them.
+	sort.SliceStable(names, func(i, j int) bool {
+		return len(names[i]) > len(names[j])
+	})
+	return fmt.Sprintf(`^\s*(?:[0-9]+(?:\.[0-9]+)?\s*(?:%s)?\s*)+$`, strings.Join(dedupe(names), "|"))
+}
+
+func unitsDescription(units *schema.UnitsDefinition) string {
+	unitNames := []string{describeUnit(units.BaseUnit())}
+	for _, multiplier := range sortedMultipliers(units) {
+		unitNames = append(unitNames, describeUnit(units.Multipliers()[multiplier]))
+	}
+	return fmt.Sprintf(
+		"Amount in %s, either as a number or as a string with units: %s.",
+		units.BaseUnit().NameLongPlural(),
+		strings.Join(unitNames, ", "),
+	)
+}
+
+func describeUnit(unit *schema.UnitDefinition) string {
+	return fmt.Sprintf("%s (%s)", unit.NameShortPlural(), unit.NameLongPlural())
+}
+
+func sortedMultipliers(units *schema.UnitsDefinition) []int64 {
+	multipliers := make([]int64, 0, len(units.Multipliers()))
+	for multiplier := range units.Multipliers() {
+		multipliers = append(multipliers, multiplier)
+	}
+	sort.Slice(multipliers, func(i, j int) bool {
+		return multipliers[i] < multipliers[j]
+	})
+	return multipliers
+}
+
+func dedupe(values []string) []string {
+	seen := map[string]bool{}
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// enum exports an enum as a oneOf of constants, which carries the display name and description of each value.
+func enum[T int64 | string](
+	jsonType string,
+	validValues map[T]*schema.DisplayValue,
+	units *schema.UnitsDefinition,
+) map[string]any {
+	values := make([]T, 0, len(validValues))
+	for value := range validValues {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i] < values[j]
+	})
+	options := make([]any, len(values))
+	for i, value := range values {
+		option := map[string]any{"const": value}
+		if display := validValues[value]; display != nil {
+			addDisplay(option, display)
+		}
+		options[i] = option
+	}
+	result := map[string]any{
+		"type":  jsonType,
+		"oneOf": options,
+	}
+	if units != nil {
+		result["description"] = fmt.Sprintf("Amount in %s.", units.BaseUnit().NameLongPlural())
+	}
+	return result
+}
+
+func (e *exporter) list(t schema.Type) (map[string]any, error) {
+	items, err := e.convert(schema.ListItems(t))
+	if err != nil {
+		return nil, fmt.Errorf("failed to export list items (%w)", err)
+	}
+	result := map[string]any{
+		"type":  "array",
+		"items": items,
+	}
+	addLimits(result, t, "minItems", "maxItems")
+	return result, nil
+}
+
+func (e *exporter) mapType(t schema.Type) (map[string]any, error) {
+	keys, valueType := schema.MapKeysValues(t)
+	values, err := e.convert(valueType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export map values (%w)", err)
+	}
+	result := map[string]any{
+		"type":                 "object",
+		"additionalProperties": values,
+	}
+	// JSON object keys are always strings, so only string constraints can be kept for the keys.
+	switch keys.TypeID() {
+	case schema.TypeIDString:
+		propertyNames, err := e.string(keys.(schema.String))
+		if err != nil {
+			return nil, fmt.Errorf("failed to export map keys (%w)", err)
+		}
+		delete(propertyNames, "type")
+		if len(propertyNames) > 0 {
+			result["propertyNames"] = propertyNames
+		}
+	case schema.TypeIDInt, schema.TypeIDIntEnum:
+		result["propertyNames"] = map[string]any{"pattern": "^-?[0-9]+$"}
+	case schema.TypeIDStringEnum:
+		propertyNames := enum("string", keys.(schema.Enum[string]).ValidValues(), nil)
+		delete(propertyNames, "type")
+		result["propertyNames"] = propertyNames
+	}
+	addLimits(result, t, "minProperties", "maxProperties")
+	return result, nil
+}
+
+func addLimits(result map[string]any, t schema.Type, minKeyword string, maxKeyword string) {
+	limits, ok := t.(interface {
+		Min() *int64
+		Max() *int64
+	})
+	if !ok {
+		return
+	}
+	if limits.Min() != nil {
+		result[minKeyword] = *limits.Min()
+	}
+	if limits.Max() != nil {
+		result[maxKeyword] = *limits.Max()
+	}
+}
+
+func (e *exporter) scope(scope schema.Scope) (map[string]any, error) {
+	root, ok := scope.Objects()[scope.Root()]
+	if !ok {
+		return nil, fmt.Errorf("root object %s not found in scope", scope.Root())
+	}
+	return e.object(root)
+}
+
+// object places the object in $defs, if it isn't there yet, and returns a reference to it.
+func (e *exporter) object(object schema.Object) (map[string]any, error) {
+	name, ok := e.names[object]
+	if !ok {
+		name = e.defName(object.ID())
+		// Register the name before exporting the properties to support recursive objects.
+		e.names[object] = name
+		e.defs[name] = nil
+		definition, err := e.objectDefinition(object, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		e.defs[name] = definition
+	}
+	return map[string]any{"$ref": e.refPrefix + name}, nil
+}
+
+// defName returns a free definition name for the given object ID.
+func (e *exporter) defName(id string) string {
+	name := id
+	for i := 2; ; i++ {
+		if _, taken := e.defs[name]; !taken {
+			return name
+		}
+		name = fmt.Sprintf("%s_%d", id, i)
+	}
+}
+
+// objectDefinition exports the properties of an object. If discriminatorField is set, the field is added as a
+// required constant for use in a oneOf.
+func (e *exporter) objectDefinition(
+	object schema.Object,
+	discriminatorField string,
+	discriminatorValue any,
+) (map[string]any, error) {
+	properties := object.Properties()
+	propertyIDs := make([]string, 0, len(properties))
+	for id := range properties {
+		propertyIDs = append(propertyIDs, id)
+	}
+	sort.Strings(propertyIDs)
+
+	jsonProperties := make(map[string]any, len(properties))
+	required := []any{}
+	dependentRequired := map[string][]any{}
+	var allOf []any
+	for _, id := range propertyIDs {
+		property := properties[id]
+		jsonProperty, err := e.property(property)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export property %s of object %s (%w)", id, object.ID(), err)
+		}
+		jsonProperties[id] = jsonProperty
+		if property.Required() {
+			required = append(required, id)
+		}
+		for _, requiredIf := range property.RequiredIf() {
+			dependentRequired[requiredIf] = append(dependentRequired[requiredIf], id)
+		}
+		if len(property.RequiredIfNot()) > 0 {
+			anyOf := []any{map[string]any{"required": []any{id}}}
+			for _, requiredIfNot := range property.RequiredIfNot() {
+				anyOf = append(anyOf, map[string]any{"required": []any{requiredIfNot}})
+			}
+			allOf = append(allOf, map[string]any{"anyOf": anyOf})
+		}
+		for _, conflict := range property.Conflicts() {
+			allOf = append(allOf, map[string]any{"not": map[string]any{"required": []any{id, conflict}}})
+		}
+	}
+
+	if discriminatorField != "" {
+		discriminator, ok := jsonProperties[discriminatorField].(map[string]any)
+		if !ok {
+			discriminator = map[string]any{}
+			jsonProperties[discriminatorField] = discriminator
+		}
+		discriminator["const"] = discriminatorValue
+		if !containsValue(required, discriminatorField) {
+			required = append(required, discriminatorField)
+		}
+	}
+
+	result := map[string]any{
+		"type":                 "object",
+		"properties":           jsonProperties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		result["required"] = required
+	}
+	if len(dependentRequired) > 0 {
+		result["dependentRequired"] = dependentRequired
+	}
+	if len(allOf) > 0 {
+		result["allOf"] = allOf
+	}
+	return result, nil
+}
+
+func containsValue(values []any, value any) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *exporter) property(property *schema.PropertySchema) (map[string]any, error) {
+	result, err := e.convert(property.Type())
+	if err != nil {
+		return nil, err
+	}
+	if _, isRef := result["$ref"]; isRef {
+		// Keep the definition shared, the annotations below apply to this property only.
+		result = map[string]any{"$ref": result["$ref"]}
+	}
+	unitsDescription, _ := result["description"].(string)
+	delete(result, "description")
+	if display := property.Display(); display != nil {
+		addDisplay(result, display)
+	}
+	if unitsDescription != "" {
+		if description, ok := result["description"].(string); ok {
+			result["description"] = description + "\n\n" + unitsDescription
+		} else {
+			result["description"] = unitsDescription
+		}
+	}
+	if property.Default() != nil {
+		result["default"] = decodeSerialized(*property.Default())
+	}
+	if len(property.Examples()) > 0 {
+		examples := make([]any, len(property.Examples()))
+		for i, example := range property.Examples() {
+			examples[i] = decodeSerialized(example)
+		}
+		result["examples"] = examples
+	}
+	if property.Secret() {
+		result["writeOnly"] = true
+	}
+	if property.Disabled {
+		result["deprecated"] = true
+	}
+	return result, nil
+}
+
+// decodeSerialized decodes a default value or example, which are stored as JSON. Values that aren't valid JSON are
+// kept as strings.
+func decodeSerialized(value string) any {
+	var result any
+	if err := json.Unmarshal([]byte(value), &result); err != nil {
+		return value
+	}
+	return result
+}
+
+// oneOf exports a one-of type as a oneOf of the possible objects, each with a constant discriminator field.
+func oneOf[KeyType int64 | string](e *exporter, t schema.OneOf[KeyType]) (map[string]any, error) {
+	keys := make([]KeyType, 0, len(t.Types()))
+	for key := range t.Types() {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	discriminatorField := t.DiscriminatorFieldName()
+	options := make([]any, len(keys))
+	for i, key := range keys {
+		object := t.Types()[key]
+		var display schema.Display
+		if ref, ok := object.(schema.Ref); ok {
+			display = ref.Display()
+			object = ref.GetObject()
+		}
+		var option map[string]any
+		if _, ok := object.Properties()[discriminatorField]; ok {
+			// The object holds the discriminator itself, so the shared definition can be extended with the constant.
+			ref, err := e.object(object)
+			if err != nil {
+				return nil, err
+			}
+			option = map[string]any{
+				"$ref":       ref["$ref"],
+				"properties": map[string]any{discriminatorField: map[string]any{"const": key}},
+				"required":   []any{discriminatorField},
+			}
+		} else {
+			// The discriminator is removed before the object is unserialized, so the shared definition, which
+			// doesn't allow additional properties, can't be used here.
+			var err error
+			option, err = e.objectDefinition(object, discriminatorField, key)
+			if err != nil {
+				return nil, err
+			}
+		}
+		addDisplay(option, display)
+		options[i] = option
+	}
+	return map[string]any{"oneOf": options}, nil
+}
+
+// addDisplay stores the name and the description of a display as title and description.
+func addDisplay(result map[string]any, display schema.Display) {
+	name, description := schema.DisplayText(display)
+	if name != "" {
+		result["title"] = name
+	}
+	if description != "" {
+		result["description"] = description
+	}
+}
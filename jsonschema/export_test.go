@@ -0,0 +1,296 @@
+package jsonschema_test
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/jsonschema"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+var testScope = schema.NewScopeSchema(
+	schema.NewObjectSchema(
+		"Config",
+		map[string]*schema.PropertySchema{
+			"name": schema.NewPropertySchema(
+				schema.NewStringSchema(schema.IntPointer(1), nil, regexp.MustCompile("^[a-z]+$")),
+				schema.NewDisplayValue(schema.PointerTo("Name"), schema.PointerTo("Name of the thing."), nil),
+				true,
+				nil,
+				nil,
+				nil,
+				nil,
+				[]string{`"foo"`},
+			),
+			"size": schema.NewPropertySchema(
+				schema.NewIntSchema(schema.IntPointer(0), nil, schema.UnitBytes),
+				nil,
+				false,
+				nil,
+				nil,
+				[]string{"name_only"},
+				schema.PointerTo("1024"),
+				nil,
+			),
+			"name_only": schema.NewPropertySchema(
+				schema.NewBoolSchema(),
+				nil,
+				false,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+			"level": schema.NewPropertySchema(
+				schema.NewStringEnumSchema(map[string]*schema.DisplayValue{
+					"debug": {NameValue: schema.PointerTo("Debug")},
+					"info":  {NameValue: schema.PointerTo("Info")},
+				}),
+				nil,
+				false,
+				[]string{"name"},
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+			"mode": schema.NewPropertySchema(
+				schema.NewOneOfStringSchema[any](
+					map[string]schema.Object{
+						"fast":     schema.NewRefSchema("Fast", nil),
+						"thorough": schema.NewRefSchema("Thorough", nil),
+					},
+					"kind",
+				),
+				nil,
+				false,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+			"labels": schema.NewPropertySchema(
+				schema.NewMapSchema(
+					schema.NewStringSchema(nil, nil, regexp.MustCompile("^[a-z]+$")),
+					schema.NewListSchema(schema.NewStringSchema(nil, nil, nil), nil, schema.IntPointer(3)),
+					nil,
+					nil,
+				),
+				nil,
+				false,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+		},
+	),
+	schema.NewObjectSchema(
+		"Fast",
+		map[string]*schema.PropertySchema{
+			"threads": schema.NewPropertySchema(
+				schema.NewIntSchema(nil, schema.IntPointer(16), nil),
+				nil,
+				false,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+		},
+	),
+	schema.NewObjectSchema(
+		"Thorough",
+		map[string]*schema.PropertySchema{
+			"kind": schema.NewPropertySchema(
+				schema.NewStringSchema(nil, nil, nil),
+				nil,
+				true,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+			"next": schema.NewPropertySchema(
+				schema.NewRefSchema("Thorough", nil),
+				nil,
+				false,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+		},
+	),
+)
+
+// exportJSON exports the scope and decodes it again, so that the expected values can be written as plain JSON types.
+func exportJSON(t *testing.T, scope schema.Scope) map[string]any {
+	document, err := jsonschema.FromScope(scope)
+	assert.NoError(t, err)
+	data, err := json.Marshal(document)
+	assert.NoError(t, err)
+	var result map[string]any
+	assert.NoError(t, json.Unmarshal(data, &result))
+	return result
+}
+
+func lookup(t *testing.T, data any, path ...string) any {
+	for _, segment := range path {
+		object, ok := data.(map[string]any)
+		if !ok {
+			t.Fatalf("%s is not an object: %v", segment, data)
+		}
+		data, ok = object[segment]
+		if !ok {
+			t.Fatalf("%s not found in %v", segment, object)
+		}
+	}
+	return data
+}
+
+func TestFromScope(t *testing.T) {
+	document := exportJSON(t, testScope)
+	assert.Equals(t, document["$schema"], any(jsonschema.Draft))
+	assert.Equals(t, document["$ref"], any("#/$defs/Config"))
+
+	config := lookup(t, document, "$defs", "Config")
+	assert.Equals(t, lookup(t, config, "additionalProperties"), any(false))
+	assert.Equals(t, lookup(t, config, "required"), any([]any{"name"}))
+	assert.Equals(t, lookup(t, config, "dependentRequired"), any(map[string]any{"name": []any{"level"}}))
+	assert.Equals(t, lookup(t, config, "allOf"), any([]any{
+		map[string]any{"not": map[string]any{"required": []any{"size", "name_only"}}},
+	}))
+
+	name := lookup(t, config, "properties", "name")
+	assert.Equals(t, name, any(map[string]any{
+		"type":        "string",
+		"minLength":   float64(1),
+		"pattern":     "^[a-z]+$",
+		"title":       "Name",
+		"description": "Name of the thing.",
+		"examples":    []any{"foo"},
+	}))
+}
+
+func TestFromScope_Units(t *testing.T) {
+	size := lookup(t, exportJSON(t, testScope), "$defs", "Config", "properties", "size")
+	assert.Equals(t, lookup(t, size, "type"), any([]any{"integer", "string"}))
+	assert.Equals(t, lookup(t, size, "minimum"), any(float64(0)))
+	assert.Equals(t, lookup(t, size, "default"), any(float64(1024)))
+	assert.Contains(t, lookup(t, size, "description").(string), "kB (kilobytes)")
+
+	pattern := regexp.MustCompile(lookup(t, size, "pattern").(string))
+	assert.Equals(t, pattern.MatchString("5MB"), true)
+	assert.Equals(t, pattern.MatchString("1 kilobyte 12B"), true)
+	assert.Equals(t, pattern.MatchString("5 parsecs"), false)
+}
+
+func TestFromScope_Enum(t *testing.T) {
+	level := lookup(t, exportJSON(t, testScope), "$defs", "Config", "properties", "level")
+	assert.Equals(t, level, any(map[string]any{
+		"type": "string",
+		"oneOf": []any{
+			map[string]any{"const": "debug", "title": "Debug"},
+			map[string]any{"const": "info", "title": "Info"},
+		},
+	}))
+}
+
+func TestFromScope_OneOf(t *testing.T) {
+	document := exportJSON(t, testScope)
+	options := lookup(t, document, "$defs", "Config", "properties", "mode", "oneOf").([]any)
+	assert.Equals(t, len(options), 2)
+
+	// Fast doesn't hold the discriminator, so it is inlined with the discriminator added.
+	fast := options[0]
+	assert.Equals(t, lookup(t, fast, "properties", "kind"), any(map[string]any{"const": "fast"}))
+	assert.Equals(t, lookup(t, fast, "required"), any([]any{"kind"}))
+	assert.Equals(t, lookup(t, fast, "properties", "threads", "maximum"), any(float64(16)))
+
+	// Thorough holds the discriminator, so the shared definition is referenced.
+	thorough := options[1]
+	assert.Equals(t, lookup(t, thorough, "$ref"), any("#/$defs/Thorough"))
+	assert.Equals(t, lookup(t, thorough, "properties", "kind"), any(map[string]any{"const": "thorough"}))
+	assert.Equals(t, lookup(t, document, "$defs", "Thorough", "properties", "next", "$ref"), any("#/$defs/Thorough"))
+}
+
+func TestFromScope_Map(t *testing.T) {
+	labels := lookup(t, exportJSON(t, testScope), "$defs", "Config", "properties", "labels")
+	assert.Equals(t, lookup(t, labels, "type"), any("object"))
+	assert.Equals(t, lookup(t, labels, "propertyNames"), any(map[string]any{"pattern": "^[a-z]+$"}))
+	assert.Equals(t, lookup(t, labels, "additionalProperties", "type"), any("array"))
+	assert.Equals(t, lookup(t, labels, "additionalProperties", "maxItems"), any(float64(3)))
+}
+
+func TestFromStepOutput(t *testing.T) {
+	step := schema.NewCallableStep[map[string]any](
+		"test",
+		testScope,
+		map[string]*schema.StepOutputSchema{
+			"success": schema.NewStepOutputSchema(
+				testScope,
+				schema.NewDisplayValue(schema.PointerTo("Success"), nil, nil),
+				false,
+			),
+		},
+		nil,
+		func(_ context.Context, _ map[string]any) (string, any) {
+			return "success", nil
+		},
+	)
+	document, err := jsonschema.FromStepOutput(step, "success")
+	assert.NoError(t, err)
+	assert.Equals(t, document["title"], any("Success"))
+
+	_, err = jsonschema.FromStepOutput(step, "nonexistent")
+	assert.Error(t, err)
+}
+
+func TestFromScope_Pattern(t *testing.T) {
+	patterns := map[string]string{
+		`^[a-z]+$`:              `^[a-z]+$`,
+		`\A(?i)ab\z`:            `^[Aa][Bb]$`,
+		`^(?P<key>\w+)=.*$`:     `^(?<key>[0-9A-Z_a-z]+)=[^\n]*$`,
+		`[^a]{2,}`:              `[^a]{2,}`,
+		`(?m)^x$`:               `(?<=^|\n)x(?=\n|$)`,
+		`(?s)a.b|c`:             `a[\s\S]b|c`,
+		`[[:digit:]]+?/`:        `[0-9]+?\/`,
+		`(?:ab)*é`:              `(?:ab)*\u00E9`,
+		`x(?:ab|cd)`:            `x(?:ab|cd)`,
+		"\U0001F600":            `\uD83D\uDE00`,
+		`[-\]^]{3}`:             `[\-\]-\^]{3}`,
+		`\pL`:                   "",
+		`[\x{10000}-\x{1FFFF}]`: "",
+	}
+	for goPattern, expected := range patterns {
+		scope := schema.NewScopeSchema(schema.NewObjectSchema("Root", map[string]*schema.PropertySchema{
+			"value": schema.NewPropertySchema(
+				schema.NewStringSchema(nil, nil, regexp.MustCompile(goPattern)),
+				nil,
+				true,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+		}))
+		document, err := jsonschema.FromScope(scope)
+		if expected == "" {
+			assert.Error(t, err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equals(t, lookup(t, document, "$defs", "Root", "properties", "value", "pattern"), any(expected))
+	}
+}
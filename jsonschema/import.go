@@ -0,0 +1,939 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// RootObjectID is the ID of the root object of imported documents that describe the root object inline rather than
+// referencing one of their definitions.
+const RootObjectID = "root"
+
+// unsupportedKeywords are the validation keywords that have no equivalent in the Arcaflow schema. Annotations and
+// unknown keywords are ignored.
+var unsupportedKeywords = []string{
+	"$dynamicRef",
+	"anyOf",
+	"contains",
+	"dependentSchemas",
+	"else",
+	"if",
+	"maxContains",
+	"minContains",
+	"multipleOf",
+	"not",
+	"patternProperties",
+	"prefixItems",
+	"then",
+	"unevaluatedItems",
+	"unevaluatedProperties",
+	"uniqueItems",
+}
+
+// ImportProblem is a construct of a JSON Schema document that cannot be mapped to the Arcaflow schema.
+type ImportProblem struct {
+	// Pointer is the JSON pointer of the construct within the document, for example #/properties/name.
+	Pointer string
+	// Message describes the problem.
+	Message string
+}
+
+func (p ImportProblem) String() string {
+	return p.Pointer + ": " + p.Message
+}
+
+// ImportError lists every construct of a JSON Schema document that cannot be mapped to the Arcaflow schema.
+type ImportError struct {
+	Problems []ImportProblem
+}
+
+func (e *ImportError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		lines[i] = "  " + problem.String()
+	}
+	return "the JSON Schema cannot be imported:\n" + strings.Join(lines, "\n")
+}
+
+// ParseScope decodes a JSON Schema document and imports it with ToScope.
+func ParseScope(data []byte) (*schema.ScopeSchema, error) {
+	var document map[string]any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to decode the JSON Schema document (%w)", err)
+	}
+	return ToScope(document)
+}
+
+// ToScope imports a JSON Schema document (draft 2020-12, the definitions keyword of older drafts is accepted too) as
+// a scope, so schemas shipped with third-party APIs can be used as step inputs. The document must describe an object,
+// either inline or by referencing one of its definitions.
+//
+// Object definitions in $defs become objects of the scope under their definition name and $ref becomes a RefSchema.
+// Inline objects become objects of the scope too, named after their title or their location. Other definitions are
+// inlined where they are referenced. Strings, numbers, booleans, arrays, maps (objects with additionalProperties), enum
+// and const are mapped to the matching types, oneOf of objects with a const discriminator property to a one-of type
+// and oneOf of constants to an enum. Titles, descriptions, defaults, examples, required, dependentRequired and
+// writeOnly are kept. A type of ["string", "null"] is imported as an optional string, but null is not accepted as a
+// value.
+//
+// Constructs that cannot be mapped, like anyOf, if/then/else or uniqueItems, are not ignored. ToScope returns an
+// *ImportError listing each of them with its location instead. The imported scope is verified with schema.CheckScope,
+// and the problems it finds are listed under the location of the document.
+func ToScope(document map[string]any) (*schema.ScopeSchema, error) {
+	i := &importer{
+		defs:         map[string]any{},
+		defPointers:  map[string]string{},
+		objects:      map[string]*schema.ObjectSchema{},
+		reservedIDs:  map[string]bool{},
+		inliningDefs: map[string]bool{},
+	}
+	i.collectDefs(document)
+
+	var rootID string
+	if ref, ok := document["$ref"]; ok {
+		name, ok := i.resolveRef("#/$ref", ref)
+		switch {
+		case !ok:
+		case !isObjectSchema(i.defs[name]):
+			i.problem("#/$ref", "the document must describe an object, %s is not an object", ref)
+		default:
+			rootID = name
+			i.defObject(name)
+		}
+	} else if isObjectSchema(document) {
+		rootID = i.newObjectID(RootObjectID)
+		i.object("#", document, rootID)
+	} else {
+		i.problem("#", "the document must describe an object")
+	}
+	if len(i.problems) > 0 {
+		return nil, &ImportError{i.problems}
+	}
+
+	objects := make([]*schema.ObjectSchema, 0, len(i.objects)-1)
+	for _, id := range sortedKeys(i.objects) {
+		if id != rootID {
+			objects = append(objects, i.objects[id])
+		}
+	}
+	scope := schema.NewScopeSchema(i.objects[rootID], objects...)
+	// The checks catch what the import cannot see locally, for example a default value that does not match its type.
+	for _, problem := range schema.CheckScope(scope) {
+		i.problem("#", "%s", problem.Error())
+	}
+	if len(i.problems) > 0 {
+		return nil, &ImportError{i.problems}
+	}
+	return scope, nil
+}
+
+// importer collects the objects of a single document.
+type importer struct {
+	// defs holds the raw definitions by name, defPointers their JSON pointers.
+	defs        map[string]any
+	defPointers map[string]string
+	// objects holds the imported objects by ID.
+	objects map[string]*schema.ObjectSchema
+	// reservedIDs holds the IDs of objects that are imported already, are being imported, or are definition names.
+	reservedIDs map[string]bool
+	// inliningDefs holds the non-object definitions being inlined, to detect recursion.
+	inliningDefs map[string]bool
+	problems     []ImportProblem
+}
+
+func (i *importer) problem(pointer string, format string, args ...any) {
+	i.problems = append(i.problems, ImportProblem{pointer, fmt.Sprintf(format, args...)})
+}
+
+func (i *importer) collectDefs(document map[string]any) {
+	for _, keyword := range []string{"$defs", "definitions"} {
+		rawDefs, ok := document[keyword]
+		if !ok {
+			continue
+		}
+		defs, ok := rawDefs.(map[string]any)
+		if !ok {
+			i.problem("#/"+keyword, "%s must be an object", keyword)
+			continue
+		}
+		for name, def := range defs {
+			pointer := pointerTo("#/"+keyword, name)
+			if _, ok := i.defs[name]; ok {
+				i.problem(pointer, "the definition %s is defined in both $defs and definitions", name)
+				continue
+			}
+			i.defs[name] = def
+			i.defPointers[name] = pointer
+			if isObjectSchema(def) {
+				i.reservedIDs[name] = true
+			}
+		}
+	}
+}
+
+// resolveRef returns the name of the definition the reference points to.
+func (i *importer) resolveRef(pointer string, ref any) (string, bool) {
+	refString, _ := ref.(string)
+	for _, prefix := range []string{defsPrefix, "#/definitions/"} {
+		if !strings.HasPrefix(refString, prefix) {
+			continue
+		}
+		name := strings.NewReplacer("~1", "/", "~0", "~").Replace(strings.TrimPrefix(refString, prefix))
+		if _, ok := i.defs[name]; !ok {
+			i.problem(pointer, "the reference %s points to a missing definition", refString)
+			return "", false
+		}
+		return name, true
+	}
+	i.problem(pointer, "only references to the definitions of the same document are supported, %v given", ref)
+	return "", false
+}
+
+// defObject imports the object definition with the given name, unless it is imported already.
+func (i *importer) defObject(name string) {
+	if _, ok := i.objects[name]; ok {
+		return
+	}
+	i.object(i.defPointers[name], i.defs[name].(map[string]any), name)
+}
+
+// newObjectID returns a free object ID based on the given name.
+func (i *importer) newObjectID(name string) string {
+	id := name
+	for n := 2; i.reservedIDs[id]; n++ {
+		id = fmt.Sprintf("%s_%d", name, n)
+	}
+	i.reservedIDs[id] = true
+	return id
+}
+
+// schemaMap returns the schema as a map. The true schema accepts anything, so it is returned as an empty map.
+func (i *importer) schemaMap(pointer string, raw any) (map[string]any, bool) {
+	switch typedRaw := raw.(type) {
+	case map[string]any:
+		return typedRaw, true
+	case bool:
+		if typedRaw {
+			return map[string]any{}, true
+		}
+		i.problem(pointer, "the false schema cannot be mapped")
+	default:
+		i.problem(pointer, "a schema must be an object or a boolean, %T given", raw)
+	}
+	return nil, false
+}
+
+// isObjectSchema returns true if the schema describes an object with fixed properties rather than a map.
+func isObjectSchema(raw any) bool {
+	s, ok := raw.(map[string]any)
+	if !ok {
+		return false
+	}
+	typeName := nonNullType(s["type"])
+	_, hasProperties := s["properties"]
+	if typeName != "object" && !(typeName == "" && hasProperties) {
+		return false
+	}
+	additionalProperties, hasAdditionalProperties := s["additionalProperties"]
+	if _, isSchema := additionalProperties.(map[string]any); isSchema {
+		return false
+	}
+	return hasProperties || (hasAdditionalProperties && additionalProperties == false)
+}
+
+// object imports an object schema under the given ID.
+func (i *importer) object(pointer string, raw map[string]any, id string) {
+	i.reservedIDs[id] = true
+	// Register the object before importing the properties to support recursive objects.
+	i.objects[id] = nil
+	i.checkKeywords(pointer, raw)
+	for _, keyword := range []string{"minProperties", "maxProperties", "propertyNames"} {
+		if _, ok := raw[keyword]; ok {
+			i.problem(pointerTo(pointer, keyword), "%s is only supported on maps", keyword)
+		}
+	}
+
+	properties := map[string]*schema.PropertySchema{}
+	rawProperties, _ := raw["properties"].(map[string]any)
+	for _, propertyID := range sortedKeys(rawProperties) {
+		propertyPointer := pointerTo(pointer, "properties", propertyID)
+		rawProperty, ok := i.schemaMap(propertyPointer, rawProperties[propertyID])
+		if !ok {
+			continue
+		}
+		if property := i.property(propertyPointer, rawProperty, id+"_"+propertyID); property != nil {
+			properties[propertyID] = property
+		}
+	}
+
+	getProperty := func(pointer string, propertyID any) *schema.PropertySchema {
+		id, _ := propertyID.(string)
+		if _, ok := rawProperties[id]; !ok {
+			i.problem(pointer, "%v is not a property of the object", propertyID)
+			return nil
+		}
+		// The property may have failed to import, that problem is reported already.
+		return properties[id]
+	}
+	for _, propertyID := range anyList(raw["required"]) {
+		if property := getProperty(pointerTo(pointer, "required"), propertyID); property != nil {
+			property.RequiredValue = true
+		}
+	}
+	dependentRequired, _ := raw["dependentRequired"].(map[string]any)
+	for _, condition := range sortedKeys(dependentRequired) {
+		dependentPointer := pointerTo(pointer, "dependentRequired", condition)
+		if getProperty(dependentPointer, condition) == nil {
+			continue
+		}
+		for _, propertyID := range anyList(dependentRequired[condition]) {
+			if property := getProperty(dependentPointer, propertyID); property != nil {
+				property.RequiredIfValue = append(property.RequiredIfValue, condition)
+			}
+		}
+	}
+	for n, rawCondition := range anyList(raw["allOf"]) {
+		i.propertyCondition(pointerTo(pointer, "allOf", fmt.Sprintf("%d", n)), rawCondition, getProperty)
+	}
+	i.objects[id] = schema.NewObjectSchema(id, properties)
+}
+
+// propertyCondition imports an allOf entry of an object. Only the forms the exporter uses for RequiredIfNot and
+// Conflicts are supported.
+func (i *importer) propertyCondition(
+	pointer string,
+	rawCondition any,
+	getProperty func(pointer string, propertyID any) *schema.PropertySchema,
+) {
+	condition, _ := rawCondition.(map[string]any)
+	requiredProperties := func(raw any) []any {
+		s, _ := raw.(map[string]any)
+		if len(s) != 1 {
+			return nil
+		}
+		return anyList(s["required"])
+	}
+	if anyOf := anyList(condition["anyOf"]); len(condition) == 1 && len(anyOf) > 1 {
+		// {"anyOf": [{"required": ["a"]}, {"required": ["b"]}]}: a is required if b is not set.
+		var ids []any
+		for _, option := range anyOf {
+			if required := requiredProperties(option); len(required) == 1 {
+				ids = append(ids, required[0])
+			}
+		}
+		if len(ids) == len(anyOf) {
+			if property := getProperty(pointer, ids[0]); property != nil {
+				for _, id := range ids[1:] {
+					if getProperty(pointer, id) != nil {
+						property.RequiredIfNotValue = append(property.RequiredIfNotValue, id.(string))
+					}
+				}
+			}
+			return
+		}
+	}
+	if required := requiredProperties(condition["not"]); len(condition) == 1 && len(required) == 2 {
+		// {"not": {"required": ["a", "b"]}}: a conflicts with b.
+		property := getProperty(pointer, required[0])
+		if property != nil && getProperty(pointer, required[1]) != nil {
+			property.ConflictsValue = append(property.ConflictsValue, required[1].(string))
+		}
+		return
+	}
+	i.problem(pointer, "allOf is only supported to require a property if another one is not set, or to forbid "+
+		"setting two properties together")
+}
+
+// property imports the schema of a property with its annotations.
+func (i *importer) property(pointer string, raw map[string]any, nameHint string) *schema.PropertySchema {
+	t := i.convert(pointer, raw, nameHint)
+	if t == nil {
+		return nil
+	}
+	var display schema.Display
+	if displayValue := importDisplay(raw); displayValue != nil {
+		display = displayValue
+	}
+	var defaultValue *string
+	if value, ok := raw["default"]; ok {
+		encodedValue, err := json.Marshal(value)
+		if err != nil {
+			i.problem(pointerTo(pointer, "default"), "the default value cannot be encoded (%v)", err)
+		} else {
+			defaultValue = schema.PointerTo(string(encodedValue))
+		}
+	}
+	var examples []string
+	for _, example := range anyList(raw["examples"]) {
+		encodedExample, err := json.Marshal(example)
+		if err != nil {
+			i.problem(pointerTo(pointer, "examples"), "the example cannot be encoded (%v)", err)
+			continue
+		}
+		examples = append(examples, string(encodedExample))
+	}
+	property := schema.NewPropertySchema(t, display, false, nil, nil, nil, defaultValue, examples)
+	if raw["writeOnly"] == true {
+		property.MarkSecret()
+	}
+	if raw["deprecated"] == true {
+		property.Disable("The property is deprecated.")
+	}
+	return property
+}
+
+func (i *importer) checkKeywords(pointer string, raw map[string]any) {
+	for _, keyword := range unsupportedKeywords {
+		if _, ok := raw[keyword]; ok {
+			i.problem(pointerTo(pointer, keyword), "%s cannot be mapped", keyword)
+		}
+	}
+}
+
+// convert imports a schema as a type. Inline objects are added to the scope under an ID based on the name hint. It
+// returns nil if the schema cannot be mapped, after reporting the problem.
+func (i *importer) convert(pointer string, raw map[string]any, nameHint string) schema.Type {
+	if !isObjectSchema(raw) {
+		// Objects are checked when they are imported.
+		i.checkKeywords(pointer, raw)
+	}
+	if ref, ok := raw["$ref"]; ok {
+		return i.ref(pointer, ref)
+	}
+	if oneOf, ok := raw["oneOf"]; ok {
+		return i.oneOf(pointerTo(pointer, "oneOf"), oneOf, raw, nameHint)
+	}
+	if values, ok := raw["enum"]; ok {
+		options := make([]enumOption, len(anyList(values)))
+		for n, value := range anyList(values) {
+			options[n] = enumOption{value: value}
+		}
+		return i.enum(pointerTo(pointer, "enum"), options)
+	}
+	if value, ok := raw["const"]; ok {
+		return i.enum(pointerTo(pointer, "const"), []enumOption{{value: value}})
+	}
+	if _, ok := raw["allOf"]; ok && !isObjectSchema(raw) {
+		i.problem(pointerTo(pointer, "allOf"), "allOf is only supported on objects")
+		return nil
+	}
+
+	typeName, ok := i.typeName(pointer, raw)
+	if !ok {
+		return nil
+	}
+	if typeName == "object" && isObjectSchema(raw) {
+		name := nameHint
+		if title, ok := raw["title"].(string); ok && title != "" {
+			name = title
+		}
+		id := i.newObjectID(name)
+		i.object(pointer, raw, id)
+		return schema.NewRefSchema(id, nil)
+	}
+	switch typeName {
+	case "string":
+		return i.string(pointer, raw)
+	case "integer":
+		minimum, maximum := i.intBounds(pointer, raw)
+		return schema.NewIntSchema(minimum, maximum, nil)
+	case "number":
+		minimum, maximum := i.floatBounds(pointer, raw)
+		return schema.NewFloatSchema(minimum, maximum, nil)
+	case "boolean":
+		return schema.NewBoolSchema()
+	case "array":
+		return i.list(pointer, raw, nameHint)
+	case "object":
+		return i.mapType(pointer, raw, nameHint)
+	case "":
+		return schema.NewAnySchema()
+	default:
+		i.problem(pointerTo(pointer, "type"), "the type %s cannot be mapped", typeName)
+		return nil
+	}
+}
+
+// typeName returns the type of the schema, inferred from its keywords if the type keyword is missing, or an empty
+// string if the schema accepts anything.
+func (i *importer) typeName(pointer string, raw map[string]any) (string, bool) {
+	switch typeValue := raw["type"].(type) {
+	case nil:
+	case string:
+		return typeValue, true
+	case []any:
+		var types []string
+		for _, t := range typeValue {
+			if t != "null" {
+				types = append(types, fmt.Sprintf("%v", t))
+			}
+		}
+		if len(types) == 1 {
+			return types[0], true
+		}
+		i.problem(pointerTo(pointer, "type"), "multiple types cannot be mapped, only a single type or null")
+		return "", false
+	default:
+		i.problem(pointerTo(pointer, "type"), "the type must be a string or an array, %T given", typeValue)
+		return "", false
+	}
+	for keyword, typeName := range map[string]string{
+		"properties":           "object",
+		"additionalProperties": "object",
+		"items":                "array",
+		"pattern":              "string",
+		"minLength":            "string",
+		"maxLength":            "string",
+		"minimum":              "number",
+		"maximum":              "number",
+	} {
+		if _, ok := raw[keyword]; ok {
+			return typeName, true
+		}
+	}
+	return "", true
+}
+
+func (i *importer) ref(pointer string, ref any) schema.Type {
+	name, ok := i.resolveRef(pointerTo(pointer, "$ref"), ref)
+	if !ok {
+		return nil
+	}
+	if isObjectSchema(i.defs[name]) {
+		i.defObject(name)
+		return schema.NewRefSchema(name, nil)
+	}
+	// Only objects can be referenced, other definitions are inlined.
+	if i.inliningDefs[name] {
+		i.problem(pointerTo(pointer, "$ref"), "the definition %s references itself, only objects can be recursive", name)
+		return nil
+	}
+	def, ok := i.schemaMap(i.defPointers[name], i.defs[name])
+	if !ok {
+		return nil
+	}
+	i.inliningDefs[name] = true
+	defer delete(i.inliningDefs, name)
+	return i.convert(i.defPointers[name], def, name)
+}
+
+func (i *importer) string(pointer string, raw map[string]any) schema.Type {
+	if raw["format"] == "regex" {
+		return schema.NewPatternSchema()
+	}
+	minLength, maxLength := i.intLimits(pointer, raw, "minLength", "maxLength")
+	var pattern *regexp.Regexp
+	if rawPattern, ok := raw["pattern"]; ok {
+		patternString, _ := rawPattern.(string)
+		var err error
+		if pattern, err = regexp.Compile(patternString); err != nil {
+			i.problem(pointerTo(pointer, "pattern"), "the pattern %v is not supported (%v)", rawPattern, err)
+			return nil
+		}
+	}
+	return schema.NewStringSchema(minLength, maxLength, pattern)
+}
+
+func (i *importer) list(pointer string, raw map[string]any, nameHint string) schema.Type {
+	var items schema.Type = schema.NewAnySchema()
+	if rawItems, ok := raw["items"]; ok {
+		itemsPointer := pointerTo(pointer, "items")
+		itemsSchema, ok := i.schemaMap(itemsPointer, rawItems)
+		if !ok {
+			return nil
+		}
+		if items = i.convert(itemsPointer, itemsSchema, nameHint+"_item"); items == nil {
+			return nil
+		}
+	}
+	minItems, maxItems := i.intLimits(pointer, raw, "minItems", "maxItems")
+	return schema.NewListSchema(items, minItems, maxItems)
+}
+
+func (i *importer) mapType(pointer string, raw map[string]any, nameHint string) schema.Type {
+	keys := schema.NewStringSchema(nil, nil, nil)
+	if rawPropertyNames, ok := raw["propertyNames"]; ok {
+		propertyNamesPointer := pointerTo(pointer, "propertyNames")
+		rawKeys, ok := i.schemaMap(propertyNamesPointer, rawPropertyNames)
+		if !ok {
+			return nil
+		}
+		// The keys are strings even if the schema does not say so. The schema belongs to the caller, so the type is
+		// added to a copy.
+		propertyNames := make(map[string]any, len(rawKeys)+1)
+		for key, value := range rawKeys {
+			propertyNames[key] = value
+		}
+		propertyNames["type"] = "string"
+		stringKeys, ok := i.string(propertyNamesPointer, propertyNames).(*schema.StringSchema)
+		if !ok {
+			return nil
+		}
+		keys = stringKeys
+	}
+	var values schema.Type = schema.NewAnySchema()
+	if rawValues, ok := raw["additionalProperties"]; ok && rawValues != true {
+		valuesPointer := pointerTo(pointer, "additionalProperties")
+		valuesSchema, ok := i.schemaMap(valuesPointer, rawValues)
+		if !ok {
+			return nil
+		}
+		if values = i.convert(valuesPointer, valuesSchema, nameHint+"_value"); values == nil {
+			return nil
+		}
+	}
+	minItems, maxItems := i.intLimits(pointer, raw, "minProperties", "maxProperties")
+	return schema.NewMapSchema(keys, values, minItems, maxItems)
+}
+
+type enumOption struct {
+	value   any
+	display *schema.DisplayValue
+}
+
+// enum imports enum values as a string or integer enum.
+func (i *importer) enum(pointer string, options []enumOption) schema.Type {
+	stringValues := map[string]*schema.DisplayValue{}
+	intValues := map[int64]*schema.DisplayValue{}
+	for _, option := range options {
+		switch value := option.value.(type) {
+		case string:
+			stringValues[value] = option.display
+		default:
+			intValue, ok := asInt(value)
+			if !ok {
+				i.problem(pointer, "only string and integer values are supported, %v given", value)
+				return nil
+			}
+			intValues[intValue] = option.display
+		}
+	}
+	switch {
+	case len(stringValues) > 0 && len(intValues) > 0:
+		i.problem(pointer, "string and integer values cannot be mixed")
+		return nil
+	case len(intValues) > 0:
+		return schema.NewIntEnumSchema(intValues, nil)
+	case len(stringValues) > 0:
+		return schema.NewStringEnumSchema(stringValues)
+	default:
+		i.problem(pointer, "at least one value is required")
+		return nil
+	}
+}
+
+// oneOf imports a oneOf of constants as an enum, and a oneOf of objects with a const discriminator property as a
+// one-of type.
+func (i *importer) oneOf(pointer string, rawOneOf any, parent map[string]any, nameHint string) schema.Type {
+	rawOptions := anyList(rawOneOf)
+	options := make([]map[string]any, 0, len(rawOptions))
+	for n, rawOption := range rawOptions {
+		option, ok := i.schemaMap(pointerTo(pointer, fmt.Sprintf("%d", n)), rawOption)
+		if !ok {
+			return nil
+		}
+		options = append(options, option)
+	}
+	if len(options) == 0 {
+		i.problem(pointer, "oneOf needs at least one option")
+		return nil
+	}
+
+	allConstants := true
+	for _, option := range options {
+		if _, ok := option["const"]; !ok {
+			allConstants = false
+		}
+	}
+	if allConstants {
+		enumOptions := make([]enumOption, len(options))
+		for n, option := range options {
+			enumOptions[n] = enumOption{option["const"], importDisplay(option)}
+		}
+		return i.enum(pointer, enumOptions)
+	}
+	if _, ok := parent["properties"]; ok {
+		i.problem(pointer, "oneOf of objects cannot be combined with properties")
+		return nil
+	}
+
+	discriminator := i.discriminator(options)
+	if discriminator == "" {
+		i.problem(pointer, "oneOf is only supported with constants or with objects that share a property with a "+
+			"const value to tell them apart")
+		return nil
+	}
+	stringTypes := map[string]schema.Object{}
+	intTypes := map[int64]schema.Object{}
+	for n, option := range options {
+		optionPointer := pointerTo(pointer, fmt.Sprintf("%d", n))
+		properties, _ := option["properties"].(map[string]any)
+		discriminatorSchema, _ := properties[discriminator].(map[string]any)
+		key := discriminatorSchema["const"]
+		object := i.oneOfObject(optionPointer, option, discriminator, fmt.Sprintf("%s_%v", nameHint, key))
+		if object == nil {
+			return nil
+		}
+		if stringKey, ok := key.(string); ok {
+			stringTypes[stringKey] = object
+		} else if intKey, ok := asInt(key); ok {
+			intTypes[intKey] = object
+		} else {
+			i.problem(optionPointer, "the discriminator must be a string or an integer, %v given", key)
+			return nil
+		}
+	}
+	switch {
+	case len(stringTypes) > 0 && len(intTypes) > 0:
+		i.problem(pointer, "string and integer discriminators cannot be mixed")
+		return nil
+	case len(stringTypes)+len(intTypes) != len(options):
+		i.problem(pointer, "the discriminator values must be unique")
+		return nil
+	case len(intTypes) > 0:
+		return schema.NewOneOfIntSchema[any](intTypes, discriminator)
+	default:
+		return schema.NewOneOfStringSchema[any](stringTypes, discriminator)
+	}
+}
+
+// discriminator returns the first property that has a const value in every option, or an empty string.
+func (i *importer) discriminator(options []map[string]any) string {
+	var candidates []string
+	for n, option := range options {
+		properties, _ := option["properties"].(map[string]any)
+		var optionCandidates []string
+		for _, propertyID := range sortedKeys(properties) {
+			property, _ := properties[propertyID].(map[string]any)
+			if _, ok := property["const"]; ok && (n == 0 || containsString(candidates, propertyID)) {
+				optionCandidates = append(optionCandidates, propertyID)
+			}
+		}
+		candidates = optionCandidates
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	return candidates[0]
+}
+
+// oneOfObject imports an option of a oneOf. Options referencing a definition, like {"$ref": "#/$defs/a",
+// "properties": {"type": {"const": "a"}}}, reference the object of the definition. Inline options are added to the
+// scope without the discriminator property, which is removed before the object is unserialized.
+func (i *importer) oneOfObject(
+	pointer string,
+	option map[string]any,
+	discriminator string,
+	nameHint string,
+) schema.Object {
+	if ref, ok := option["$ref"]; ok {
+		name, ok := i.resolveRef(pointerTo(pointer, "$ref"), ref)
+		if !ok {
+			return nil
+		}
+		if !isObjectSchema(i.defs[name]) {
+			i.problem(pointerTo(pointer, "$ref"), "the options of oneOf must be objects, %s is not an object", ref)
+			return nil
+		}
+		i.defObject(name)
+		return schema.NewRefSchema(name, importDisplay(option))
+	}
+	if !isObjectSchema(option) {
+		i.problem(pointer, "the options of oneOf must be objects")
+		return nil
+	}
+	inlineOption := make(map[string]any, len(option))
+	for key, value := range option {
+		inlineOption[key] = value
+	}
+	properties := map[string]any{}
+	for propertyID, property := range option["properties"].(map[string]any) {
+		if propertyID != discriminator {
+			properties[propertyID] = property
+		}
+	}
+	inlineOption["properties"] = properties
+	var required []any
+	for _, propertyID := range anyList(option["required"]) {
+		if propertyID != discriminator {
+			required = append(required, propertyID)
+		}
+	}
+	inlineOption["required"] = required
+
+	name := nameHint
+	if title, ok := option["title"].(string); ok && title != "" {
+		name = title
+	}
+	id := i.newObjectID(name)
+	i.object(pointer, inlineOption, id)
+	return schema.NewRefSchema(id, nil)
+}
+
+func (i *importer) intLimits(pointer string, raw map[string]any, minKeyword string, maxKeyword string) (
+	*int64,
+	*int64,
+) {
+	var result [2]*int64
+	for n, keyword := range []string{minKeyword, maxKeyword} {
+		if value, ok := raw[keyword]; ok {
+			intValue, ok := asInt(value)
+			if !ok {
+				i.problem(pointerTo(pointer, keyword), "%s must be an integer, %v given", keyword, value)
+				continue
+			}
+			result[n] = &intValue
+		}
+	}
+	return result[0], result[1]
+}
+
+// intBounds returns the bounds of an integer. Exclusive bounds are converted to inclusive ones.
+func (i *importer) intBounds(pointer string, raw map[string]any) (*int64, *int64) {
+	minimum, maximum := i.intLimits(pointer, raw, "minimum", "maximum")
+	for keyword, apply := range map[string]func(int64){
+		"exclusiveMinimum": func(value int64) {
+			value++
+			if minimum == nil || *minimum < value {
+				minimum = &value
+			}
+		},
+		"exclusiveMaximum": func(value int64) {
+			value--
+			if maximum == nil || *maximum > value {
+				maximum = &value
+			}
+		},
+	} {
+		if rawValue, ok := raw[keyword]; ok {
+			value, ok := asInt(rawValue)
+			if !ok {
+				i.problem(pointerTo(pointer, keyword), "%s must be an integer, %v given", keyword, rawValue)
+				continue
+			}
+			apply(value)
+		}
+	}
+	return minimum, maximum
+}
+
+func (i *importer) floatBounds(pointer string, raw map[string]any) (*float64, *float64) {
+	var result [2]*float64
+	for n, keyword := range []string{"minimum", "maximum"} {
+		if rawValue, ok := raw[keyword]; ok {
+			value, ok := asFloat(rawValue)
+			if !ok {
+				i.problem(pointerTo(pointer, keyword), "%s must be a number, %v given", keyword, rawValue)
+				continue
+			}
+			result[n] = &value
+		}
+	}
+	for _, keyword := range []string{"exclusiveMinimum", "exclusiveMaximum"} {
+		if _, ok := raw[keyword]; ok {
+			i.problem(pointerTo(pointer, keyword), "%s cannot be mapped for numbers, only for integers", keyword)
+		}
+	}
+	return result[0], result[1]
+}
+
+// importDisplay returns the title and the description of a schema as display value, or nil if there are none.
+func importDisplay(raw map[string]any) *schema.DisplayValue {
+	title, _ := raw["title"].(string)
+	description, _ := raw["description"].(string)
+	if title == "" && description == "" {
+		return nil
+	}
+	display := &schema.DisplayValue{}
+	if title != "" {
+		display.NameValue = &title
+	}
+	if description != "" {
+		display.DescriptionValue = &description
+	}
+	return display
+}
+
+// nonNullType returns the type of a type keyword, ignoring null, or an empty string if there is no single type.
+func nonNullType(typeValue any) string {
+	switch typedValue := typeValue.(type) {
+	case string:
+		return typedValue
+	case []any:
+		result := ""
+		for _, t := range typedValue {
+			if t == "null" {
+				continue
+			}
+			if result != "" {
+				return ""
+			}
+			result, _ = t.(string)
+		}
+		return result
+	default:
+		return ""
+	}
+}
+
+func asFloat(value any) (float64, bool) {
+	switch number := value.(type) {
+	case float64:
+		return number, true
+	case int:
+		return float64(number), true
+	case int64:
+		return float64(number), true
+	case json.Number:
+		result, err := number.Float64()
+		return result, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func asInt(value any) (int64, bool) {
+	number, ok := asFloat(value)
+	if !ok || number != math.Trunc(number) || math.Abs(number) > math.MaxInt64 {
+		return 0, false
+	}
+	return int64(number), true
+}
+
+func anyList(value any) []any {
+	list, _ := value.([]any)
+	return list
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// pointerTo appends the tokens to a JSON pointer, escaping them.
+func pointerTo(pointer string, tokens ...string) string {
+	escaper := strings.NewReplacer("~", "~0", "/", "~1")
+	for _, token := range tokens {
+		pointer += "/" + escaper.Replace(token)
+	}
+	return pointer
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
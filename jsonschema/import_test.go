@@ -0,0 +1,195 @@
+package jsonschema_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/jsonschema"
+	"go.flow.arcalot.io/pluginsdk/schema"
+	"go.flow.arcalot.io/pluginsdk/schema/build"
+)
+
+func TestToScope_RoundTrip(t *testing.T) {
+	scope := build.Scope(
+		build.Object("Config").
+			Property("name", build.Property(build.String().MinLen(1).Pattern("^[a-z]+$")).
+				Required().
+				Name("Name").
+				Description("Name of the thing.").
+				Examples("foo")).
+			Property("retries", build.Property(build.Int().Min(0).Max(5)).Default(3).Conflicts("forever")).
+			Property("forever", build.Property(build.Bool()).RequiredIfNot("retries")).
+			Property("ratio", build.Property(build.Float().Max(1))).
+			Property("token", build.Property(build.String()).Secret().RequiredIf("name")).
+			Property("level", build.Property(build.Enum("debug", "info"))).
+			Property("labels", build.Property(build.Map(build.String(), build.List(build.Int()).MaxItems(3)))).
+			Property("mode", build.Property(build.Type(schema.NewOneOfStringSchema[any](
+				map[string]schema.Object{
+					"fast":     schema.NewRefSchema("Fast", nil),
+					"thorough": schema.NewRefSchema("Thorough", nil),
+				},
+				"kind",
+			)))),
+		build.Object("Fast").
+			Property("threads", build.Property(build.Int().Max(16))),
+		build.Object("Thorough").
+			Property("kind", build.Property(build.String()).Required()).
+			Property("next", build.Property(build.Ref("Thorough"))),
+	).Build()
+
+	document := exportJSON(t, scope)
+	imported, err := jsonschema.ToScope(document)
+	assert.NoError(t, err)
+	assert.Equals(t, exportJSON(t, imported), document)
+}
+
+func TestToScope(t *testing.T) {
+	scope, err := jsonschema.ParseScope([]byte(`{
+		"title": "Create instance request",
+		"type": "object",
+		"properties": {
+			"name": {"$ref": "#/definitions/name", "description": "Name of the instance."},
+			"size": {"type": "integer", "exclusiveMinimum": 0, "exclusiveMaximum": 65},
+			"region": {"type": ["string", "null"], "enum": ["eu", "us"], "default": "eu"},
+			"tier": {"const": "standard"},
+			"disk": {
+				"type": "object",
+				"properties": {"gigabytes": {"type": "integer"}},
+				"required": ["gigabytes"]
+			},
+			"tags": {"type": "object", "additionalProperties": {"type": "string"}, "maxProperties": 10},
+			"metadata": {}
+		},
+		"required": ["name"],
+		"definitions": {
+			"name": {"type": "string", "minLength": 1, "maxLength": 63}
+		}
+	}`))
+	assert.NoError(t, err)
+	assert.Equals(t, scope.Root(), jsonschema.RootObjectID)
+	assert.Equals(t, len(scope.Objects()), 2)
+
+	properties := scope.Properties()
+	assert.Equals(t, properties["name"].Required(), true)
+	assert.Equals(t, *properties["name"].Display().Description(), "Name of the instance.")
+	assert.Equals(t, *properties["name"].Type().(*schema.StringSchema).Max(), int64(63))
+	size := properties["size"].Type().(*schema.IntSchema)
+	assert.Equals(t, *size.Min(), int64(1))
+	assert.Equals(t, *size.Max(), int64(64))
+	assert.Equals(t, properties["region"].TypeID(), schema.TypeIDStringEnum)
+	assert.Equals(t, *properties["region"].Default(), `"eu"`)
+	assert.Equals(t, properties["tier"].TypeID(), schema.TypeIDStringEnum)
+	assert.Equals(t, properties["disk"].TypeID(), schema.TypeIDRef)
+	assert.Equals(t, properties["tags"].TypeID(), schema.TypeIDMap)
+	assert.Equals(t, properties["metadata"].TypeID(), schema.TypeIDAny)
+
+	data, err := scope.Unserialize(map[string]any{
+		"name": "web",
+		"size": 8,
+		"disk": map[string]any{"gigabytes": 20},
+		"tags": map[string]any{"team": "arcaflow"},
+	})
+	assert.NoError(t, err)
+	assert.Equals(t, data.(map[string]any)["region"], any("eu"))
+	_, err = scope.Unserialize(map[string]any{"name": "web", "size": 65})
+	assert.Error(t, err)
+	_, err = scope.Unserialize(map[string]any{"name": "web", "disk": map[string]any{}})
+	assert.Error(t, err)
+}
+
+func TestToScope_OneOfInline(t *testing.T) {
+	scope, err := jsonschema.ParseScope([]byte(`{
+		"$ref": "#/$defs/Shape",
+		"$defs": {
+			"Shape": {
+				"type": "object",
+				"properties": {
+					"shape": {
+						"oneOf": [
+							{"title": "Circle", "properties": {"type": {"const": 1}, "radius": {"type": "number"}}},
+							{"title": "Square", "properties": {"type": {"const": 2}, "side": {"type": "number"}}}
+						]
+					}
+				}
+			}
+		}
+	}`))
+	assert.NoError(t, err)
+	assert.Equals(t, scope.Root(), "Shape")
+	assert.Equals(t, len(scope.Objects()), 3)
+	assert.Equals(t, scope.Properties()["shape"].TypeID(), schema.TypeIDOneOfInt)
+	data, err := scope.Unserialize(map[string]any{"shape": map[string]any{"type": 2, "side": 1.5}})
+	assert.NoError(t, err)
+	// The discriminator is not a property of the inline options, so it is removed when unserializing.
+	assert.Equals(t, data.(map[string]any)["shape"], any(map[string]any{"side": 1.5}))
+}
+
+func TestToScope_Unsupported(t *testing.T) {
+	_, err := jsonschema.ParseScope([]byte(`{
+		"type": "object",
+		"properties": {
+			"id": {"anyOf": [{"type": "string"}, {"type": "integer"}]},
+			"tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true},
+			"owner": {"$ref": "https://example.com/owner.json"},
+			"ratio": {"type": "number", "exclusiveMinimum": 0},
+			"value": {"type": ["string", "integer"]},
+			"pet": {"oneOf": [{"$ref": "#/$defs/Cat"}, {"$ref": "#/$defs/Dog"}]},
+			"never": false
+		},
+		"required": ["missing"],
+		"$defs": {
+			"Cat": {"type": "object", "properties": {"name": {"type": "string"}}},
+			"Dog": {"type": "object", "properties": {"name": {"type": "string"}}}
+		}
+	}`))
+	var importError *jsonschema.ImportError
+	assert.Equals(t, errors.As(err, &importError), true)
+	pointers := make([]string, len(importError.Problems))
+	for i, problem := range importError.Problems {
+		pointers[i] = problem.Pointer
+	}
+	assert.Equals(t, pointers, []string{
+		"#/properties/id/anyOf",
+		"#/properties/never",
+		"#/properties/owner/$ref",
+		"#/properties/pet/oneOf",
+		"#/properties/ratio/exclusiveMinimum",
+		"#/properties/tags/uniqueItems",
+		"#/properties/value/type",
+		"#/required",
+	})
+	assert.Contains(t, err.Error(), "#/properties/id/anyOf: anyOf cannot be mapped")
+	assert.Contains(t, err.Error(), "only references to the definitions of the same document are supported")
+
+	_, err = jsonschema.ParseScope([]byte(`{"type": "string"}`))
+	assert.Contains(t, err.Error(), "#: the document must describe an object")
+}
+
+func TestToScope_InvalidValues(t *testing.T) {
+	propertyNames := map[string]any{"pattern": "^[a-z]+$"}
+	document := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"labels": map[string]any{
+				"type":                 "object",
+				"propertyNames":        propertyNames,
+				"additionalProperties": map[string]any{"type": "string"},
+			},
+			"count": map[string]any{"type": "integer", "default": "many"},
+			"ratio": map[string]any{"type": "number", "examples": []any{math.Inf(1)}},
+		},
+	}
+	_, err := jsonschema.ToScope(document)
+	var importError *jsonschema.ImportError
+	assert.Equals(t, errors.As(err, &importError), true)
+	assert.Equals(t, len(importError.Problems), 1)
+	assert.Equals(t, importError.Problems[0].Pointer, "#/properties/ratio/examples")
+	// The document is not modified by the import.
+	assert.Equals(t, propertyNames, map[string]any{"pattern": "^[a-z]+$"})
+
+	delete(document["properties"].(map[string]any), "ratio")
+	_, err = jsonschema.ToScope(document)
+	assert.Contains(t, err.Error(), "#: object root -> count: the default value \"many\" is invalid")
+}
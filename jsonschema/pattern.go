@@ -0,0 +1,231 @@
+package jsonschema
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// maxBMPRune is the largest rune ECMA-262 patterns can match as a single character without the unicode flag, which
+// JSON Schema does not set.
+const maxBMPRune = 0xFFFF
+
+// ecmaPattern converts a Go RE2 expression to the ECMA-262 dialect JSON Schema uses for the pattern keyword. The
+// constructs without an ECMA-262 equivalent are rewritten: flags become explicit character classes, \A and \z become
+// ^ and $, named groups use the (?<name>) syntax and line anchors become lookarounds. The dot is exported as [^\n],
+// because the ECMA-262 dot also rejects \r and the Unicode line separators. It returns an error if a character class
+// matches characters outside the basic multilingual plane, which ECMA-262 cannot express without the unicode flag.
+func ecmaPattern(pattern *regexp.Regexp) (string, error) {
+	parsed, err := syntax.Parse(pattern.String(), syntax.Perl)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern %s (%w)", pattern.String(), err)
+	}
+	var builder strings.Builder
+	if err := writeECMA(&builder, parsed); err != nil {
+		return "", fmt.Errorf("the pattern %s cannot be converted to ECMA-262 (%w)", pattern.String(), err)
+	}
+	return builder.String(), nil
+}
+
+func writeECMA(b *strings.Builder, re *syntax.Regexp) error {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		b.WriteString(`[^\s\S]`)
+	case syntax.OpEmptyMatch:
+		b.WriteString(`(?:)`)
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			writeLiteral(b, r, re.Flags&syntax.FoldCase != 0)
+		}
+	case syntax.OpCharClass:
+		return writeClass(b, re.Rune)
+	case syntax.OpAnyCharNotNL:
+		b.WriteString(`[^\n]`)
+	case syntax.OpAnyChar:
+		b.WriteString(`[\s\S]`)
+	case syntax.OpBeginLine:
+		b.WriteString(`(?<=^|\n)`)
+	case syntax.OpEndLine:
+		b.WriteString(`(?=\n|$)`)
+	case syntax.OpBeginText:
+		b.WriteString(`^`)
+	case syntax.OpEndText:
+		b.WriteString(`$`)
+	case syntax.OpWordBoundary:
+		b.WriteString(`\b`)
+	case syntax.OpNoWordBoundary:
+		b.WriteString(`\B`)
+	case syntax.OpCapture:
+		if re.Name != "" {
+			b.WriteString("(?<" + re.Name + ">")
+		} else {
+			b.WriteString("(")
+		}
+		if err := writeECMA(b, re.Sub[0]); err != nil {
+			return err
+		}
+		b.WriteString(")")
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		if err := writeAtom(b, re.Sub[0]); err != nil {
+			return err
+		}
+		switch re.Op {
+		case syntax.OpStar:
+			b.WriteString("*")
+		case syntax.OpPlus:
+			b.WriteString("+")
+		case syntax.OpQuest:
+			b.WriteString("?")
+		default:
+			b.WriteString("{" + strconv.Itoa(re.Min))
+			if re.Max != re.Min {
+				b.WriteString(",")
+				if re.Max >= 0 {
+					b.WriteString(strconv.Itoa(re.Max))
+				}
+			}
+			b.WriteString("}")
+		}
+		if re.Flags&syntax.NonGreedy != 0 {
+			b.WriteString("?")
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if sub.Op == syntax.OpAlternate {
+				if err := writeGroup(b, sub); err != nil {
+					return err
+				}
+			} else if err := writeECMA(b, sub); err != nil {
+				return err
+			}
+		}
+	case syntax.OpAlternate:
+		for i, sub := range re.Sub {
+			if i > 0 {
+				b.WriteString("|")
+			}
+			if err := writeECMA(b, sub); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported construct %s", re)
+	}
+	return nil
+}
+
+// writeAtom writes the operand of a repetition, grouping it unless it is a single character or group.
+func writeAtom(b *strings.Builder, re *syntax.Regexp) error {
+	switch {
+	case re.Op == syntax.OpLiteral && len(re.Rune) == 1,
+		re.Op == syntax.OpCharClass,
+		re.Op == syntax.OpAnyChar,
+		re.Op == syntax.OpAnyCharNotNL,
+		re.Op == syntax.OpCapture:
+		return writeECMA(b, re)
+	default:
+		return writeGroup(b, re)
+	}
+}
+
+func writeGroup(b *strings.Builder, re *syntax.Regexp) error {
+	b.WriteString("(?:")
+	if err := writeECMA(b, re); err != nil {
+		return err
+	}
+	b.WriteString(")")
+	return nil
+}
+
+// writeLiteral writes a literal character. Case-insensitive letters become a class of their case variants.
+func writeLiteral(b *strings.Builder, r rune, foldCase bool) {
+	if foldCase {
+		variants := []rune{r}
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			variants = append(variants, f)
+		}
+		if len(variants) > 1 {
+			sort.Slice(variants, func(i, j int) bool { return variants[i] < variants[j] })
+			b.WriteString("[")
+			for _, variant := range variants {
+				writeClassRune(b, variant)
+			}
+			b.WriteString("]")
+			return
+		}
+	}
+	switch {
+	case strings.ContainsRune(`\^$.|?*+()[]{}/`, r):
+		b.WriteString(`\` + string(r))
+	case r > maxBMPRune:
+		// Outside of classes, characters of the other planes can be written as their surrogate pair.
+		r1, r2 := utf16.EncodeRune(r)
+		b.WriteString(fmt.Sprintf(`\u%04X\u%04X`, r1, r2))
+	case r < ' ' || r > '~':
+		b.WriteString(fmt.Sprintf(`\u%04X`, r))
+	default:
+		b.WriteRune(r)
+	}
+}
+
+// writeClass writes a character class given as sorted pairs of inclusive ranges. Classes reaching the end of the
+// Unicode range, which is how negated classes are parsed, are written negated.
+func writeClass(b *strings.Builder, ranges []rune) error {
+	negated := len(ranges) > 0 && ranges[len(ranges)-1] == unicode.MaxRune
+	if negated {
+		ranges = complementRanges(ranges)
+		if len(ranges) == 0 {
+			b.WriteString(`[\s\S]`)
+			return nil
+		}
+	}
+	b.WriteString("[")
+	if negated {
+		b.WriteString("^")
+	}
+	for i := 0; i < len(ranges); i += 2 {
+		low, high := ranges[i], ranges[i+1]
+		if high > maxBMPRune {
+			return fmt.Errorf("the character class matches characters outside of the basic multilingual plane")
+		}
+		writeClassRune(b, low)
+		if high != low {
+			b.WriteString("-")
+			writeClassRune(b, high)
+		}
+	}
+	b.WriteString("]")
+	return nil
+}
+
+func writeClassRune(b *strings.Builder, r rune) {
+	switch {
+	case strings.ContainsRune(`\]^-[`, r):
+		b.WriteString(`\` + string(r))
+	case r < ' ' || r > '~':
+		b.WriteString(fmt.Sprintf(`\u%04X`, r))
+	default:
+		b.WriteRune(r)
+	}
+}
+
+// complementRanges returns the ranges not covered by the given sorted ranges.
+func complementRanges(ranges []rune) []rune {
+	var result []rune
+	next := rune(0)
+	for i := 0; i < len(ranges); i += 2 {
+		if ranges[i] > next {
+			result = append(result, next, ranges[i]-1)
+		}
+		next = ranges[i+1] + 1
+	}
+	if next <= unicode.MaxRune {
+		result = append(result, next, unicode.MaxRune)
+	}
+	return result
+}
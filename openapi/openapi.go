@@ -0,0 +1,283 @@
+// Package openapi describes the HTTP endpoints of a plugin, see plugin.NewHTTPHandler, as an OpenAPI 3.1 document.
+// API gateways, client generators and documentation portals can consume plugins through this document.
+package openapi
+
+import (
+	"fmt"
+	"sort"
+
+	"go.flow.arcalot.io/pluginsdk/jsonschema"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// Version is the OpenAPI version of the generated documents.
+const Version = "3.1.0"
+
+// ErrorExtension marks the response variants of error outputs.
+const ErrorExtension = "x-arcaflow-error"
+
+// signalOperationSeparator separates the step ID and the signal ID in the operation IDs of signals. IDs cannot contain
+// dots, so signal operation IDs never collide with each other or with the operation IDs of steps.
+const signalOperationSeparator = "."
+
+// componentsPrefix is the JSON pointer prefix of references to schemas in the components.
+const componentsPrefix = "#/components/schemas/"
+
+// Info describes the plugin in the info section of the document.
+type Info struct {
+	// Title is the name of the plugin. It defaults to "Arcaflow plugin".
+	Title string
+	// Version is the version of the plugin. It defaults to "0.0.0".
+	Version string
+	// Description describes the plugin.
+	Description string
+}
+
+// Generate creates an OpenAPI document describing the HTTP endpoints of the steps in the schema:
+//
+//   - Each step becomes a POST /steps/{step} operation with the step ID as operation ID and the step input as request
+//     body. The response is a oneOf of the step outputs, discriminated by output_id. Error outputs are marked with
+//     the x-arcaflow-error extension.
+//   - Each signal handler becomes a POST /steps/{step}/runs/{run}/signals/{signal} operation with the operation ID
+//     {step}.{signal} and the signal data as request body.
+//
+// The objects of all scopes are placed in the schemas of the components. Display names become summaries or titles,
+// and descriptions are kept as descriptions.
+func Generate(s *schema.CallableSchema, info Info) (map[string]any, error) {
+	g := &generator{
+		defs:  jsonschema.NewDefinitions(componentsPrefix),
+		paths: map[string]any{},
+	}
+	g.httpError = g.defs.Add("HTTPError", httpErrorSchema())
+
+	stepIDs := make([]string, 0, len(s.StepsValue))
+	for id := range s.StepsValue {
+		stepIDs = append(stepIDs, id)
+	}
+	sort.Strings(stepIDs)
+	for _, id := range stepIDs {
+		if err := g.step(s.StepsValue[id]); err != nil {
+			return nil, err
+		}
+	}
+	g.paths["/schema"] = map[string]any{
+		"get": map[string]any{
+			"operationId": "getSchema",
+			"summary":     "Arcaflow schema",
+			"description": "Returns the serialized Arcaflow schema of the plugin.",
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "The schema of the plugin.",
+					"content":     jsonContent(map[string]any{"type": "object"}),
+				},
+			},
+		},
+	}
+
+	infoSection := map[string]any{
+		"title":   info.Title,
+		"version": info.Version,
+	}
+	if info.Title == "" {
+		infoSection["title"] = "Arcaflow plugin"
+	}
+	if info.Version == "" {
+		infoSection["version"] = "0.0.0"
+	}
+	if info.Description != "" {
+		infoSection["description"] = info.Description
+	}
+	return map[string]any{
+		"openapi":           Version,
+		"jsonSchemaDialect": jsonschema.Draft,
+		"info":              infoSection,
+		"paths":             g.paths,
+		"components": map[string]any{
+			"schemas": g.defs.Map(),
+		},
+	}, nil
+}
+
+// generator collects the paths and the shared schemas of a single document.
+type generator struct {
+	defs      *jsonschema.Definitions
+	paths     map[string]any
+	httpError map[string]any
+}
+
+func (g *generator) step(step schema.Step) error {
+	input, err := g.defs.Scope(step.Input())
+	if err != nil {
+		return fmt.Errorf("failed to export the input of step %s (%w)", step.ID(), err)
+	}
+	response, err := g.stepResponse(step)
+	if err != nil {
+		return err
+	}
+	operation := map[string]any{
+		"operationId": step.ID(),
+		"parameters": []any{
+			map[string]any{
+				// The name matches plugin.RunIDParameter, which can't be imported here as the plugin package
+				// serves these documents.
+				"name":        "run",
+				"in":          "query",
+				"required":    false,
+				"description": "Run ID of the execution, needed to send signals to it. Generated if not set.",
+				"schema":      map[string]any{"type": "string"},
+			},
+		},
+		"requestBody": map[string]any{
+			"required": true,
+			"content":  jsonContent(input),
+		},
+		"responses": map[string]any{
+			"200": map[string]any{
+				"description": "The step finished with one of its outputs.",
+				"content":     jsonContent(response),
+			},
+			"400": g.errorResponse("The input is invalid."),
+			"409": g.errorResponse("The run ID is in use, or the step already has an active run."),
+			"500": g.errorResponse("The step failed without an output."),
+		},
+	}
+	addDisplay(operation, step.Display(), "summary")
+	g.paths["/steps/"+step.ID()] = map[string]any{"post": operation}
+
+	signalIDs := make([]string, 0, len(step.SignalHandlers()))
+	for id := range step.SignalHandlers() {
+		signalIDs = append(signalIDs, id)
+	}
+	sort.Strings(signalIDs)
+	for _, id := range signalIDs {
+		if err := g.signal(step, step.SignalHandlers()[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stepResponse returns the schema of the step response, which is a oneOf of the outputs of the step. Each variant is
+// placed in the components, so the discriminator can map the output IDs to them.
+func (g *generator) stepResponse(step schema.Step) (map[string]any, error) {
+	outputIDs := make([]string, 0, len(step.Outputs()))
+	for id := range step.Outputs() {
+		outputIDs = append(outputIDs, id)
+	}
+	sort.Strings(outputIDs)
+	variants := make([]any, len(outputIDs))
+	mapping := make(map[string]any, len(outputIDs))
+	for i, id := range outputIDs {
+		output := step.Outputs()[id]
+		data, err := g.defs.Scope(output.Schema())
+		if err != nil {
+			return nil, fmt.Errorf("failed to export output %s of step %s (%w)", id, step.ID(), err)
+		}
+		variant := map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"run_id":      map[string]any{"type": "string"},
+				"output_id":   map[string]any{"const": id},
+				"output_data": data,
+				"error":       map[string]any{"const": output.Error()},
+			},
+			"required":             []any{"run_id", "output_id", "output_data", "error"},
+			"additionalProperties": false,
+			"title":                id,
+		}
+		if output.Error() {
+			variant[ErrorExtension] = true
+		}
+		addDisplay(variant, output.Display(), "title")
+		ref := g.defs.Add(step.ID()+"_"+id+"_output", variant)
+		variants[i] = ref
+		mapping[id] = ref["$ref"]
+	}
+	return map[string]any{
+		"oneOf": variants,
+		"discriminator": map[string]any{
+			"propertyName": "output_id",
+			"mapping":      mapping,
+		},
+	}, nil
+}
+
+func (g *generator) signal(step schema.Step, signal *schema.SignalSchema) error {
+	data, err := g.defs.Scope(signal.DataSchema())
+	if err != nil {
+		return fmt.Errorf("failed to export the data of signal %s of step %s (%w)", signal.ID(), step.ID(), err)
+	}
+	operation := map[string]any{
+		"operationId": step.ID() + signalOperationSeparator + signal.ID(),
+		"parameters": []any{
+			map[string]any{
+				"name":        "run",
+				"in":          "path",
+				"required":    true,
+				"description": "Run ID of the execution to send the signal to.",
+				"schema":      map[string]any{"type": "string"},
+			},
+		},
+		"requestBody": map[string]any{
+			"required": true,
+			"content":  jsonContent(data),
+		},
+		"responses": map[string]any{
+			"204": map[string]any{"description": "The signal was handled."},
+			"400": g.errorResponse("The signal data is invalid."),
+			"404": g.errorResponse("The run or the signal does not exist."),
+			"500": g.errorResponse("The signal handler failed."),
+		},
+	}
+	addDisplay(operation, signal.Display(), "summary")
+	g.paths["/steps/"+step.ID()+"/runs/{run}/signals/"+signal.ID()] = map[string]any{"post": operation}
+	return nil
+}
+
+func (g *generator) errorResponse(description string) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content":     jsonContent(g.httpError),
+	}
+}
+
+// httpErrorSchema describes the response of failed requests.
+func httpErrorSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"error": map[string]any{"type": "string"},
+			"constraint_errors": map[string]any{
+				"type":        "array",
+				"description": "Problems with the input data, if the input was invalid.",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"path":    map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+						"message": map[string]any{"type": "string"},
+					},
+					"required": []any{"path", "message"},
+				},
+			},
+		},
+		"required": []any{"error"},
+	}
+}
+
+func jsonContent(schemaValue map[string]any) map[string]any {
+	return map[string]any{
+		"application/json": map[string]any{"schema": schemaValue},
+	}
+}
+
+// addDisplay stores the name of a display under nameKey, for example summary or title, and its description as
+// description.
+func addDisplay(result map[string]any, display schema.Display, nameKey string) {
+	name, description := schema.DisplayText(display)
+	if name != "" {
+		result[nameKey] = name
+	}
+	if description != "" {
+		result["description"] = description
+	}
+}
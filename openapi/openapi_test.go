@@ -0,0 +1,182 @@
+package openapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/jsonschema"
+	"go.flow.arcalot.io/pluginsdk/openapi"
+	"go.flow.arcalot.io/pluginsdk/schema"
+	"go.flow.arcalot.io/pluginsdk/schema/build"
+)
+
+type greetInput struct {
+	Name string `json:"name"`
+}
+
+type stopInput struct {
+	Reason string `json:"reason"`
+}
+
+var errorOutput = build.Output(
+	build.Scope(
+		build.Object("Error").Property("reason", build.Property(build.String()).Required()),
+	).Build(),
+).Error().Name("Error").Description("The step failed.")
+
+var greetStep = build.Step("greet", func(_ context.Context, input greetInput) (string, any) {
+	return "success", map[string]any{"message": "Hello, " + input.Name + "!"}
+}).
+	Name("Greet").
+	Description("Greets someone.").
+	Input(
+		build.Scope(
+			build.StructObject[greetInput]("Input").
+				Property("name", build.Property(build.String().MinLen(1)).Required()),
+		).Build(),
+	).
+	Output(
+		"success",
+		build.Output(
+			build.Scope(
+				build.Object("Output").Property("message", build.Property(build.String()).Required()),
+			).Build(),
+		).Name("Success"),
+	).
+	Output("error", errorOutput).
+	SignalHandler(schema.NewCallableSignal(
+		"stop",
+		build.Scope(
+			build.StructObject[stopInput]("Stop").Property("reason", build.Property(build.String())),
+		).Build(),
+		schema.NewDisplayValue(schema.PointerTo("Stop"), nil, nil),
+		func(_ context.Context, _ any, _ stopInput) {},
+	)).
+	Build()
+
+// countStep has an error output with the same object ID as the one of the greet step, but a different definition.
+var countStep = build.Step("count", func(_ context.Context, _ map[string]any) (string, any) {
+	return "error", map[string]any{"code": 1}
+}).
+	Input(build.Scope(build.Object("CountInput")).Build()).
+	Output(
+		"error",
+		build.Output(
+			build.Scope(
+				build.Object("Error").Property("code", build.Property(build.Int()).Required()),
+			).Build(),
+		).Error(),
+	).
+	Build()
+
+func TestGenerate(t *testing.T) {
+	document, err := openapi.Generate(
+		schema.NewCallableSchema(greetStep, countStep),
+		openapi.Info{Title: "Greeter", Version: "1.2.3"},
+	)
+	assert.NoError(t, err)
+	document = roundTrip(t, document)
+
+	assert.Equals(t, document["openapi"], any(openapi.Version))
+	assert.Equals(t, document["jsonSchemaDialect"], any(jsonschema.Draft))
+	assert.Equals(t, document["info"], any(map[string]any{"title": "Greeter", "version": "1.2.3"}))
+
+	paths := document["paths"].(map[string]any)
+	assert.Equals(t, len(paths), 4)
+	operation := paths["/steps/greet"].(map[string]any)["post"].(map[string]any)
+	assert.Equals(t, operation["operationId"], any("greet"))
+	assert.Equals(t, operation["summary"], any("Greet"))
+	assert.Equals(t, operation["description"], any("Greets someone."))
+	assert.Equals(
+		t,
+		schemaOf(operation["requestBody"]),
+		map[string]any{"$ref": "#/components/schemas/Input"},
+	)
+
+	responses := operation["responses"].(map[string]any)
+	response := schemaOf(responses["200"])
+	assert.Equals(t, response["discriminator"], any(map[string]any{
+		"propertyName": "output_id",
+		"mapping": map[string]any{
+			"error":   "#/components/schemas/greet_error_output",
+			"success": "#/components/schemas/greet_success_output",
+		},
+	}))
+	assert.Equals(t, schemaOf(responses["400"]), map[string]any{"$ref": "#/components/schemas/HTTPError"})
+
+	schemas := document["components"].(map[string]any)["schemas"].(map[string]any)
+	errorVariant := schemas["greet_error_output"].(map[string]any)
+	assert.Equals(t, errorVariant["title"], any("Error"))
+	assert.Equals(t, errorVariant["description"], any("The step failed."))
+	assert.Equals(t, errorVariant[openapi.ErrorExtension], any(true))
+	errorProperties := errorVariant["properties"].(map[string]any)
+	assert.Equals(t, errorProperties["output_id"], any(map[string]any{"const": "error"}))
+	assert.Equals(t, errorProperties["error"], any(map[string]any{"const": true}))
+	assert.Equals(t, errorProperties["output_data"], any(map[string]any{"$ref": "#/components/schemas/Error_2"}))
+
+	successVariant := schemas["greet_success_output"].(map[string]any)
+	assert.Equals(t, successVariant["title"], any("Success"))
+	_, marked := successVariant[openapi.ErrorExtension]
+	assert.Equals(t, marked, false)
+
+	// The objects of different steps sharing an ID are kept apart. The steps are exported in order of their IDs.
+	countErrorVariant := schemas["count_error_output"].(map[string]any)
+	assert.Equals(
+		t,
+		countErrorVariant["properties"].(map[string]any)["output_data"],
+		any(map[string]any{"$ref": "#/components/schemas/Error"}),
+	)
+	assert.Equals(t, countErrorVariant["title"], any("error"))
+
+	signal := paths["/steps/greet/runs/{run}/signals/stop"].(map[string]any)["post"].(map[string]any)
+	assert.Equals(t, signal["operationId"], any("greet.stop"))
+	assert.Equals(t, signal["summary"], any("Stop"))
+	assert.Equals(t, schemaOf(signal["requestBody"]), map[string]any{"$ref": "#/components/schemas/Stop"})
+}
+
+func TestGenerate_DefaultInfo(t *testing.T) {
+	document, err := openapi.Generate(schema.NewCallableSchema(countStep), openapi.Info{})
+	assert.NoError(t, err)
+	assert.Equals(t, document["info"], any(map[string]any{"title": "Arcaflow plugin", "version": "0.0.0"}))
+}
+
+// roundTrip passes the document through JSON, so that it can be compared with decoded values.
+func TestGenerate_UniqueOperationIDs(t *testing.T) {
+	// The step ID matches the step and the signal ID of the greet signal joined with an underscore.
+	greetStopStep := build.Step("greet_stop", func(_ context.Context, _ map[string]any) (string, any) {
+		return "success", map[string]any{}
+	}).
+		Input(build.Scope(build.Object("GreetStopInput")).Build()).
+		Output("success", build.Output(build.Scope(build.Object("GreetStopOutput")).Build())).
+		Build()
+	document, err := openapi.Generate(schema.NewCallableSchema(greetStep, greetStopStep), openapi.Info{})
+	assert.NoError(t, err)
+
+	operationIDs := map[any]string{}
+	for path, item := range document["paths"].(map[string]any) {
+		for _, operation := range item.(map[string]any) {
+			operationID := operation.(map[string]any)["operationId"]
+			if other, ok := operationIDs[operationID]; ok {
+				t.Fatalf("the operations of %s and %s share the operation ID %v", other, path, operationID)
+			}
+			operationIDs[operationID] = path
+		}
+	}
+	assert.Equals(t, len(operationIDs), 4)
+}
+
+func roundTrip(t *testing.T, document map[string]any) map[string]any {
+	encoded, err := json.Marshal(document)
+	assert.NoError(t, err)
+	var result map[string]any
+	assert.NoError(t, json.Unmarshal(encoded, &result))
+	return result
+}
+
+// schemaOf returns the JSON schema of a request body or a response.
+func schemaOf(content any) map[string]any {
+	mediaTypes := content.(map[string]any)["content"].(map[string]any)
+	return mediaTypes["application/json"].(map[string]any)["schema"].(map[string]any)
+}
@@ -0,0 +1,127 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go.flow.arcalot.io/pluginsdk/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnvironmentVariable holds the name of the environment variable pointing to the plugin configuration
+// file. The --config global option takes precedence over it.
+const ConfigFileEnvironmentVariable = "ARCAFLOW_PLUGIN_CONFIG_FILE"
+
+// WithConfig declares the deployment-level configuration of the plugin, like API endpoints or a cache directory, that
+// is not part of the step input. Before running steps, Run loads the configuration with LoadConfig from the file set
+// with --config or ConfigFileEnvironmentVariable and from the environment variables with the given prefix, and
+// refuses to start if it is invalid. The unserialized configuration is passed to the step initializers of steps
+// created with schema.NewCallableStepWithConfig and is available to the step handlers through
+// schema.PluginConfigFromContext.
+func WithConfig(config *schema.ScopeSchema, environmentPrefix string) Option {
+	return func(r *runOptions) {
+		r.config = config
+		r.configEnvironmentPrefix = environmentPrefix
+	}
+}
+
+// ConfigError lists every problem found in the plugin configuration.
+type ConfigError struct {
+	Errors []*schema.ConstraintError
+}
+
+func (c *ConfigError) Error() string {
+	lines := make([]string, len(c.Errors))
+	for i, err := range c.Errors {
+		lines[i] = "  " + err.Error()
+	}
+	return "invalid plugin configuration:\n" + strings.Join(lines, "\n")
+}
+
+// ConfigEnvironmentVariable returns the name of the environment variable setting the root property of the plugin
+// configuration, for example MYPLUGIN_API_ENDPOINT for the property api_endpoint and the prefix MYPLUGIN_.
+func ConfigEnvironmentVariable(environmentPrefix string, propertyID string) string {
+	return environmentPrefix + strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return '_'
+		}
+		return unicode.ToUpper(r)
+	}, propertyID)
+}
+
+// LoadConfig loads the plugin configuration from the YAML or JSON file, if the file name is not empty, and from the
+// environment variables with the given prefix, see ConfigEnvironmentVariable. The environment variables take
+// precedence over the file. Scalar values are taken from the environment variables as they are, lists, maps and
+// objects are parsed as YAML, for example [a, b]. It returns the unserialized configuration, or a *ConfigError
+// listing every problem found.
+func LoadConfig(config *schema.ScopeSchema, environmentPrefix string, file string) (any, error) {
+	data := map[string]any{}
+	if file != "" {
+		fileContents, err := os.ReadFile(file) //nolint:gosec
+		if err != nil {
+			return nil, fmt.Errorf("failed to read configuration file %s (%w)", file, err)
+		}
+		var fileData any
+		if err := yaml.Unmarshal(fileContents, &fileData); err != nil {
+			return nil, fmt.Errorf("failed to parse configuration file %s (%w)", file, err)
+		}
+		if fileData != nil {
+			fileMap, ok := fileData.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("configuration file %s must hold an object, %T given", file, fileData)
+			}
+			data = fileMap
+		}
+	}
+
+	var errs []*schema.ConstraintError
+	properties := config.Properties()
+	propertyIDs := make([]string, 0, len(properties))
+	for propertyID := range properties {
+		propertyIDs = append(propertyIDs, propertyID)
+	}
+	sort.Strings(propertyIDs)
+	for _, propertyID := range propertyIDs {
+		name := ConfigEnvironmentVariable(environmentPrefix, propertyID)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		parsedValue, err := parseTextValue(properties[propertyID].Type(), value)
+		if err != nil {
+			errs = append(errs, &schema.ConstraintError{
+				Message: fmt.Sprintf("Invalid value in environment variable %s", name),
+				Path:    []string{propertyID},
+				Cause:   err,
+			})
+			continue
+		}
+		data[propertyID] = parsedValue
+	}
+	if len(errs) == 0 {
+		errs = schema.CollectConstraintErrors(config, data)
+	}
+	if len(errs) > 0 {
+		return nil, &ConfigError{errs}
+	}
+	return config.Unserialize(data)
+}
+
+// parseTextValue parses a value typed in as text, like the value of an environment variable. Scalars are unserialized
+// from strings by their schema, so only lists, maps and objects need to be parsed, which are written as YAML.
+func parseTextValue(t schema.Type, value string) (any, error) {
+	switch t.TypeID() {
+	case schema.TypeIDList, schema.TypeIDMap, schema.TypeIDObject, schema.TypeIDRef, schema.TypeIDScope,
+		schema.TypeIDOneOfString, schema.TypeIDOneOfInt, schema.TypeIDAny:
+		var result any
+		if err := yaml.Unmarshal([]byte(value), &result); err != nil {
+			return nil, err
+		}
+		return result, nil
+	default:
+		return value, nil
+	}
+}
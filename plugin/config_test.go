@@ -0,0 +1,214 @@
+package plugin_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/plugin"
+	"go.flow.arcalot.io/pluginsdk/schema"
+	"gopkg.in/yaml.v3"
+)
+
+type testConfig struct {
+	Greeting string   `json:"greeting"`
+	Retries  int64    `json:"retries"`
+	Names    []string `json:"names"`
+}
+
+var testConfigSchema = schema.NewScopeSchema(
+	schema.NewStructMappedObjectSchema[testConfig](
+		"Config",
+		map[string]*schema.PropertySchema{
+			"greeting": schema.NewPropertySchema(
+				schema.NewStringSchema(schema.IntPointer(1), nil, nil),
+				nil,
+				true,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+			"retries": schema.NewPropertySchema(
+				schema.NewIntSchema(schema.IntPointer(0), schema.IntPointer(10), nil),
+				nil,
+				false,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+			"names": schema.NewPropertySchema(
+				schema.NewListSchema(schema.NewStringSchema(nil, nil, nil), nil, nil),
+				nil,
+				false,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+		},
+	),
+)
+
+type configStepData struct {
+	greeting string
+}
+
+var configTestSchema = schema.NewCallableSchema(
+	schema.NewCallableStepWithConfig[testConfig, *configStepData, greetInput](
+		"greet",
+		greetInputSchema,
+		map[string]*schema.StepOutputSchema{
+			"success": greetSuccessOutputSchema,
+		},
+		nil,
+		nil,
+		nil,
+		func(config testConfig) *configStepData {
+			return &configStepData{greeting: config.Greeting}
+		},
+		func(_ context.Context, data *configStepData, input greetInput) (string, any) {
+			return "success", greetOutput{Message: data.greeting + ", " + input.Name + "!"}
+		},
+	),
+)
+
+func TestConfigEnvironmentVariable(t *testing.T) {
+	assert.Equals(t, plugin.ConfigEnvironmentVariable("MYPLUGIN_", "api_endpoint"), "MYPLUGIN_API_ENDPOINT")
+	assert.Equals(t, plugin.ConfigEnvironmentVariable("", "cache-dir"), "CACHE_DIR")
+}
+
+func TestLoadConfig(t *testing.T) {
+	file := writeFile(t, "config.yaml", "greeting: Hi\nretries: 1\n")
+	t.Setenv("TEST_RETRIES", "3")
+	t.Setenv("TEST_NAMES", "[a, b]")
+
+	config, err := plugin.LoadConfig(testConfigSchema, "TEST_", file)
+	assert.NoError(t, err)
+	assert.Equals(t, config.(testConfig), testConfig{Greeting: "Hi", Retries: 3, Names: []string{"a", "b"}})
+
+	t.Setenv("TEST_GREETING", "Hello")
+	config, err = plugin.LoadConfig(testConfigSchema, "TEST_", "")
+	assert.NoError(t, err)
+	assert.Equals(t, config.(testConfig).Greeting, "Hello")
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	t.Setenv("TEST_RETRIES", "11")
+	t.Setenv("TEST_NAMES", "[a, 1]")
+	_, err := plugin.LoadConfig(testConfigSchema, "TEST_", "")
+	var configError *plugin.ConfigError
+	assert.Equals(t, errors.As(err, &configError), true)
+	assert.Equals(t, len(configError.Errors), 2)
+	assert.Equals(t, configError.Errors[0].Path, []string{"greeting"})
+	assert.Equals(t, configError.Errors[1].Path, []string{"retries"})
+
+	t.Setenv("TEST_NAMES", "[a")
+	_, err = plugin.LoadConfig(testConfigSchema, "TEST_", "")
+	assert.Equals(t, errors.As(err, &configError), true)
+	assert.Contains(t, configError.Error(), "Invalid value in environment variable TEST_NAMES")
+
+	_, err = plugin.LoadConfig(testConfigSchema, "TEST_", writeFile(t, "config.yaml", "- greeting\n"))
+	assert.Error(t, err)
+}
+
+func TestRunWithArgs_Config(t *testing.T) {
+	run := func(args ...string) (string, string, int) {
+		stdout := &bytes.Buffer{}
+		stderr := &bytes.Buffer{}
+		exitCode := plugin.RunWithArgs(
+			context.Background(),
+			configTestSchema,
+			args,
+			strings.NewReader("name: Arca Lot\n"),
+			stdout,
+			stderr,
+			plugin.WithConfig(testConfigSchema, "TEST_"),
+		)
+		return stdout.String(), stderr.String(), exitCode
+	}
+
+	_, stderr, exitCode := run("run", "--input", "-")
+	assert.Equals(t, exitCode, plugin.ExitCodeInvalidInput)
+	assert.Contains(t, stderr, "invalid plugin configuration")
+	assert.Contains(t, stderr, "'greeting'")
+
+	// Commands that don't call steps work without a valid configuration.
+	_, _, exitCode = run("schema")
+	assert.Equals(t, exitCode, plugin.ExitCodeSuccess)
+
+	// Option values are not mistaken for the command, even if they match a command name.
+	stdout, _, exitCode := run("--config", "atp", "schema")
+	assert.Equals(t, exitCode, plugin.ExitCodeSuccess)
+	assert.Contains(t, stdout, "serialized_schema:")
+
+	// The --config option takes precedence over the environment variable.
+	t.Setenv(plugin.ConfigFileEnvironmentVariable, writeFile(t, "config.yaml", "greeting: Hi\n"))
+	stdout, stderr, exitCode = run("--config", writeFile(t, "other.yaml", "greeting: Hey\n"), "run", "--input", "-")
+	assert.Equals(t, stderr, "")
+	assert.Equals(t, exitCode, plugin.ExitCodeSuccess)
+	var result map[string]any
+	assert.NoError(t, yaml.Unmarshal([]byte(stdout), &result))
+	assert.Equals(t, result["output_data"], any(map[string]any{"message": "Hey, Arca Lot!"}))
+}
+
+func TestRunWithArgs_ConfigPerRun(t *testing.T) {
+	// Every run creates new step data, so it sees the configuration of that run.
+	for _, greeting := range []string{"Hi", "Hey"} {
+		stdout := &bytes.Buffer{}
+		exitCode := plugin.RunWithArgs(
+			context.Background(),
+			configTestSchema,
+			[]string{"--config", writeFile(t, "config.yaml", "greeting: "+greeting+"\n"), "run", "--input", "-"},
+			strings.NewReader("name: Arca Lot\n"),
+			stdout,
+			&bytes.Buffer{},
+			plugin.WithConfig(testConfigSchema, "TEST_"),
+		)
+		assert.Equals(t, exitCode, plugin.ExitCodeSuccess)
+		var result map[string]any
+		assert.NoError(t, yaml.Unmarshal(stdout.Bytes(), &result))
+		assert.Equals(t, result["output_data"], any(map[string]any{"message": greeting + ", Arca Lot!"}))
+	}
+}
+
+func TestNewCallableStepWithConfig_MissingConfig(t *testing.T) {
+	ctx := schema.ContextWithExecution(context.Background())
+	_, _, err := configTestSchema.CallStep(ctx, "greet", map[string]any{"name": "Arca Lot"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "requires a plugin configuration of type plugin_test.testConfig")
+
+	ctx = schema.ContextWithPluginConfig(schema.ContextWithExecution(context.Background()), "Hi")
+	_, _, err = configTestSchema.CallStep(ctx, "greet", map[string]any{"name": "Arca Lot"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "but the context holds string")
+}
+
+func TestNewCallableStepWithConfig_NilInitializer(t *testing.T) {
+	s := schema.NewCallableSchema(
+		schema.NewCallableStepWithConfig[testConfig, *configStepData, greetInput](
+			"greet",
+			greetInputSchema,
+			map[string]*schema.StepOutputSchema{
+				"success": greetSuccessOutputSchema,
+			},
+			nil,
+			nil,
+			nil,
+			nil,
+			func(_ context.Context, data *configStepData, input greetInput) (string, any) {
+				return "success", greetOutput{Message: "Hello, " + input.Name + "!"}
+			},
+		),
+	)
+	outputID, _, err := s.CallStep(context.Background(), "greet", map[string]any{"name": "Arca Lot"})
+	assert.NoError(t, err)
+	assert.Equals(t, outputID, "success")
+}
@@ -0,0 +1,446 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// DocsFormat is the output format of WriteDocs.
+type DocsFormat string
+
+const (
+	// DocsFormatMarkdown renders the documentation as Markdown, for example for a README.
+	DocsFormatMarkdown DocsFormat = "markdown"
+	// DocsFormatText renders the documentation as plain text for reading in a terminal.
+	DocsFormatText DocsFormat = "text"
+)
+
+// WriteDocs writes human-readable documentation of the steps of a plugin, including their inputs, outputs and
+// signals, generated from the schema.
+func WriteDocs(w io.Writer, s *schema.CallableSchema, format DocsFormat) error {
+	var d docWriter
+	switch format {
+	case DocsFormatMarkdown:
+		d = &markdownWriter{}
+	case DocsFormatText:
+		d = &textWriter{}
+	default:
+		return fmt.Errorf("unsupported documentation format: %s (expected markdown or text)", format)
+	}
+	for _, id := range stepIDs(s) {
+		describeStep(d, s.StepsValue[id])
+	}
+	_, err := io.WriteString(w, strings.TrimLeft(d.String(), "\n"))
+	return err
+}
+
+// writeDocs implements the docs command.
+func writeDocs(_ context.Context, env *environment, args []string) int {
+	flags := env.newFlagSet("docs")
+	format := flags.String("format", string(DocsFormatMarkdown), "Output format, markdown or text.")
+	if err := flags.Parse(args); err != nil {
+		return parseErrorExitCode(err)
+	}
+	if err := WriteDocs(env.stdout, env.schema, DocsFormat(*format)); err != nil {
+		_, _ = fmt.Fprintln(env.stderr, err.Error())
+		return ExitCodeUsage
+	}
+	return ExitCodeSuccess
+}
+
+// docWriter renders the elements of the documentation in a specific format.
+type docWriter interface {
+	heading(level int, text string)
+	paragraph(text string)
+	// item writes a list item, nested items have a higher level.
+	item(level int, text string)
+	code(text string) string
+	strong(text string) string
+	String() string
+}
+
+type markdownWriter struct {
+	strings.Builder
+	inList bool
+}
+
+func (m *markdownWriter) heading(level int, text string) {
+	m.endList()
+	_, _ = fmt.Fprintf(m, "\n%s %s\n", strings.Repeat("#", level), text)
+}
+
+func (m *markdownWriter) paragraph(text string) {
+	m.endList()
+	_, _ = fmt.Fprintf(m, "\n%s\n", text)
+}
+
+func (m *markdownWriter) item(level int, text string) {
+	if !m.inList {
+		m.WriteString("\n")
+		m.inList = true
+	}
+	_, _ = fmt.Fprintf(m, "%s- %s\n", strings.Repeat("  ", level), text)
+}
+
+func (m *markdownWriter) endList() {
+	m.inList = false
+}
+
+func (m *markdownWriter) code(text string) string {
+	return "`" + text + "`"
+}
+
+func (m *markdownWriter) strong(text string) string {
+	return "**" + text + "**"
+}
+
+type textWriter struct {
+	strings.Builder
+	inList bool
+}
+
+func (t *textWriter) heading(level int, text string) {
+	t.inList = false
+	underline := map[int]string{1: "=", 2: "-"}[level]
+	if underline == "" {
+		_, _ = fmt.Fprintf(t, "\n%s\n", text)
+		return
+	}
+	_, _ = fmt.Fprintf(t, "\n%s\n%s\n", text, strings.Repeat(underline, len(text)))
+}
+
+func (t *textWriter) paragraph(text string) {
+	t.inList = false
+	_, _ = fmt.Fprintf(t, "\n%s\n", text)
+}
+
+func (t *textWriter) item(level int, text string) {
+	if !t.inList {
+		t.WriteString("\n")
+		t.inList = true
+	}
+	_, _ = fmt.Fprintf(t, "%s* %s\n", strings.Repeat("    ", level), text)
+}
+
+func (t *textWriter) code(text string) string {
+	return text
+}
+
+func (t *textWriter) strong(text string) string {
+	return text
+}
+
+func describeStep(d docWriter, step schema.CallableStep) {
+	name, description := schema.DisplayText(step.Display())
+	if name == "" {
+		name = step.ID()
+	}
+	d.heading(1, fmt.Sprintf("Step: %s (%s)", name, d.code(step.ID())))
+	if description != "" {
+		d.paragraph(description)
+	}
+
+	d.heading(2, "Input")
+	describeScope(d, 3, step.Input())
+
+	d.heading(2, "Outputs")
+	outputIDs := sortedKeys(step.Outputs())
+	for _, id := range outputIDs {
+		output := step.Outputs()[id]
+		title := "Output " + d.code(id)
+		if output.Error() {
+			title += " (error)"
+		}
+		d.heading(3, title)
+		describeDisplay(d, output.Display())
+		describeScope(d, 4, output.Schema())
+	}
+
+	describeSignals(d, "Signal handlers", step.SignalHandlers())
+	describeSignals(d, "Signal emitters", step.SignalEmitters())
+}
+
+func describeSignals(d docWriter, title string, signals map[string]*schema.SignalSchema) {
+	if len(signals) == 0 {
+		return
+	}
+	d.heading(2, title)
+	for _, id := range sortedKeys(signals) {
+		signal := signals[id]
+		d.heading(3, "Signal "+d.code(id))
+		describeDisplay(d, signal.Display())
+		if signal.DataSchema() != nil {
+			describeScope(d, 4, signal.DataSchema())
+		}
+	}
+}
+
+func describeDisplay(d docWriter, display schema.Display) {
+	name, description := schema.DisplayText(display)
+	if name != "" && description != "" {
+		d.paragraph(name + ": " + description)
+	} else if name+description != "" {
+		d.paragraph(name + description)
+	}
+}
+
+// describeScope lists the properties of the root object, followed by the other objects of the scope under headings
+// of the given level.
+func describeScope(d docWriter, objectLevel int, scope schema.Scope) {
+	objects := scope.Objects()
+	describeProperties(d, objects[scope.Root()])
+	for _, id := range sortedKeys(objects) {
+		if id == scope.Root() {
+			continue
+		}
+		d.heading(objectLevel, "Object "+d.code(id))
+		describeProperties(d, objects[id])
+	}
+}
+
+func describeProperties(d docWriter, object schema.Object) {
+	properties := object.Properties()
+	if len(properties) == 0 {
+		d.paragraph("No properties.")
+		return
+	}
+	for _, id := range sortedKeys(properties) {
+		property := properties[id]
+		attributes := []string{describeType(d, property.Type())}
+		if property.Required() {
+			attributes = append(attributes, "required")
+		} else {
+			attributes = append(attributes, "optional")
+		}
+		if property.Secret() {
+			attributes = append(attributes, "secret")
+		}
+		text := fmt.Sprintf("%s (%s)", d.strong(d.code(id)), strings.Join(attributes, ", "))
+		name, description := schema.DisplayText(property.Display())
+		switch {
+		case name != "" && description != "":
+			text += ": " + name + ". " + description
+		case name+description != "":
+			text += ": " + name + description
+		}
+		d.item(0, text)
+		for _, detail := range propertyDetails(d, property) {
+			d.item(1, detail)
+		}
+	}
+}
+
+func propertyDetails(d docWriter, property *schema.PropertySchema) []string {
+	details := typeDetails(d, property.Type())
+	if len(property.RequiredIf()) > 0 {
+		details = append(details, fmt.Sprintf("Required if %s is set.", joinCode(d, property.RequiredIf(), "or")))
+	}
+	switch len(property.RequiredIfNot()) {
+	case 0:
+	case 1:
+		details = append(details, fmt.Sprintf("Required if %s is not set.", d.code(property.RequiredIfNot()[0])))
+	default:
+		details = append(details, fmt.Sprintf(
+			"Required if none of %s is set.",
+			joinCode(d, property.RequiredIfNot(), ""),
+		))
+	}
+	if len(property.Conflicts()) > 0 {
+		details = append(details, fmt.Sprintf("Conflicts with %s.", joinCode(d, property.Conflicts(), "and")))
+	}
+	if property.Default() != nil {
+		details = append(details, "Default: "+d.code(*property.Default()))
+	}
+	if len(property.Examples()) > 0 {
+		details = append(details, "Examples: "+joinCode(d, property.Examples(), ""))
+	}
+	if property.Disabled {
+		reason := ""
+		if property.DisabledReason != nil {
+			reason = ": " + *property.DisabledReason
+		}
+		details = append(details, "Disabled"+reason)
+	}
+	return details
+}
+
+// joinCode formats the values as code and joins them, with the conjunction before the last value if set.
+func joinCode(d docWriter, values []string, conjunction string) string {
+	formatted := make([]string, len(values))
+	for i, value := range values {
+		formatted[i] = d.code(value)
+	}
+	if conjunction == "" || len(formatted) == 1 {
+		return strings.Join(formatted, ", ")
+	}
+	return strings.Join(formatted[:len(formatted)-1], ", ") + " " + conjunction + " " + formatted[len(formatted)-1]
+}
+
+// describeType returns a short name of the type, for example "list of string".
+func describeType(d docWriter, t schema.Type) string {
+	switch t.TypeID() {
+	case schema.TypeIDString:
+		return "string"
+	case schema.TypeIDPattern:
+		return "regular expression"
+	case schema.TypeIDInt:
+		return "integer"
+	case schema.TypeIDFloat:
+		return "float"
+	case schema.TypeIDBool:
+		return "boolean"
+	case schema.TypeIDStringEnum:
+		return "enum of strings"
+	case schema.TypeIDIntEnum:
+		return "enum of integers"
+	case schema.TypeIDList:
+		return "list of " + describeType(d, schema.ListItems(t))
+	case schema.TypeIDMap:
+		keys, values := schema.MapKeysValues(t)
+		return fmt.Sprintf("map of %s to %s", describeType(d, keys), describeType(d, values))
+	case schema.TypeIDObject, schema.TypeIDRef, schema.TypeIDScope:
+		return "object " + d.code(t.(schema.Object).ID())
+	case schema.TypeIDOneOfString:
+		return describeOneOf(d, t.(schema.OneOf[string]))
+	case schema.TypeIDOneOfInt:
+		return describeOneOf(d, t.(schema.OneOf[int64]))
+	case schema.TypeIDAny:
+		return "any"
+	default:
+		return string(t.TypeID())
+	}
+}
+
+func describeOneOf[KeyType int64 | string](d docWriter, t schema.OneOf[KeyType]) string {
+	keys := make([]KeyType, 0, len(t.Types()))
+	for key := range t.Types() {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	options := make([]string, len(keys))
+	for i, key := range keys {
+		options[i] = fmt.Sprintf("%s for %v", d.code(t.Types()[key].ID()), key)
+	}
+	return fmt.Sprintf(
+		"one of %s, selected by %s",
+		strings.Join(options, ", "),
+		d.code(t.DiscriminatorFieldName()),
+	)
+}
+
+// typeDetails lists the constraints of a type, the item types of lists and maps are described as well.
+func typeDetails(d docWriter, t schema.Type) []string {
+	var details []string
+	switch t.TypeID() {
+	case schema.TypeIDString:
+		s := t.(schema.String)
+		details = appendLimits(details, "length", s.Min(), s.Max())
+		if s.Pattern() != nil {
+			details = append(details, "Must match: "+d.code(s.Pattern().String()))
+		}
+	case schema.TypeIDInt:
+		i := t.(schema.Int)
+		details = appendLimits(details, "value", i.Min(), i.Max())
+		details = appendUnits(d, details, i.Units())
+	case schema.TypeIDFloat:
+		f := t.(schema.Float)
+		details = appendLimits(details, "value", f.Min(), f.Max())
+		details = appendUnits(d, details, f.Units())
+	case schema.TypeIDStringEnum:
+		details = append(details, describeEnumValues(d, t.(schema.Enum[string]).ValidValues()))
+	case schema.TypeIDIntEnum:
+		details = append(details, describeEnumValues(d, t.(schema.Enum[int64]).ValidValues()))
+		if withUnits, ok := t.(interface {
+			Units() *schema.UnitsDefinition
+		}); ok {
+			details = appendUnits(d, details, withUnits.Units())
+		}
+	case schema.TypeIDList:
+		details = appendLimits(details, "items", getMin(t), getMax(t))
+		for _, detail := range typeDetails(d, schema.ListItems(t)) {
+			details = append(details, "Items: "+detail)
+		}
+	case schema.TypeIDMap:
+		details = appendLimits(details, "entries", getMin(t), getMax(t))
+		keys, values := schema.MapKeysValues(t)
+		for _, detail := range typeDetails(d, keys) {
+			details = append(details, "Keys: "+detail)
+		}
+		for _, detail := range typeDetails(d, values) {
+			details = append(details, "Values: "+detail)
+		}
+	}
+	return details
+}
+
+func appendLimits[T int64 | float64](details []string, what string, min *T, max *T) []string {
+	if min != nil {
+		details = append(details, fmt.Sprintf("Minimum %s: %v", what, *min))
+	}
+	if max != nil {
+		details = append(details, fmt.Sprintf("Maximum %s: %v", what, *max))
+	}
+	return details
+}
+
+func appendUnits(d docWriter, details []string, units *schema.UnitsDefinition) []string {
+	if units == nil {
+		return details
+	}
+	names := []string{d.code(units.BaseUnit().NameShortPlural())}
+	multipliers := sortedKeys(units.Multipliers())
+	for _, multiplier := range multipliers {
+		names = append(names, d.code(units.Multipliers()[multiplier].NameShortPlural()))
+	}
+	return append(details, fmt.Sprintf(
+		"Units: %s, may also be written with units (%s)",
+		units.BaseUnit().NameLongPlural(),
+		strings.Join(names, ", "),
+	))
+}
+
+func describeEnumValues[T int64 | string](d docWriter, validValues map[T]*schema.DisplayValue) string {
+	values := sortedKeys(validValues)
+	descriptions := make([]string, len(values))
+	for i, value := range values {
+		descriptions[i] = d.code(fmt.Sprintf("%v", value))
+		if display := validValues[value]; display != nil {
+			if name, _ := schema.DisplayText(display); name != "" {
+				descriptions[i] += " (" + name + ")"
+			}
+		}
+	}
+	return "Values: " + strings.Join(descriptions, ", ")
+}
+
+func getMin(t schema.Type) *int64 {
+	if limits, ok := t.(interface{ Min() *int64 }); ok {
+		return limits.Min()
+	}
+	return nil
+}
+
+func getMax(t schema.Type) *int64 {
+	if limits, ok := t.(interface{ Max() *int64 }); ok {
+		return limits.Max()
+	}
+	return nil
+}
+
+func sortedKeys[K int64 | string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	return keys
+}
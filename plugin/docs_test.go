@@ -0,0 +1,180 @@
+package plugin_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/plugin"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+var docsTestSchema = schema.NewCallableSchema(
+	schema.NewCallableStepWithSignals[any, map[string]any](
+		"download",
+		schema.NewScopeSchema(
+			schema.NewObjectSchema(
+				"DownloadInput",
+				map[string]*schema.PropertySchema{
+					"url": schema.NewPropertySchema(
+						schema.NewStringSchema(schema.IntPointer(1), nil, nil),
+						schema.NewDisplayValue(schema.PointerTo("URL"), schema.PointerTo("Address to download."), nil),
+						false,
+						nil,
+						[]string{"path"},
+						[]string{"path"},
+						nil,
+						[]string{`"https://example.com"`},
+					),
+					"path": schema.NewPropertySchema(
+						schema.NewStringSchema(nil, nil, nil),
+						nil,
+						false,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+					),
+					"name": schema.NewPropertySchema(
+						schema.NewStringSchema(nil, nil, nil),
+						nil,
+						false,
+						nil,
+						[]string{"path", "url"},
+						nil,
+						nil,
+						nil,
+					),
+					"limit": schema.NewPropertySchema(
+						schema.NewIntSchema(schema.IntPointer(0), nil, schema.UnitBytes),
+						nil,
+						false,
+						[]string{"url"},
+						nil,
+						nil,
+						schema.PointerTo("1024"),
+						nil,
+					),
+					"mirrors": schema.NewPropertySchema(
+						schema.NewListSchema(schema.NewRefSchema("Mirror", nil), nil, schema.IntPointer(3)),
+						nil,
+						false,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+					),
+				},
+			),
+			schema.NewObjectSchema(
+				"Mirror",
+				map[string]*schema.PropertySchema{
+					"protocol": schema.NewPropertySchema(
+						schema.NewStringEnumSchema(map[string]*schema.DisplayValue{
+							"http":  {NameValue: schema.PointerTo("HTTP")},
+							"https": {NameValue: schema.PointerTo("HTTPS")},
+						}),
+						nil,
+						true,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+					),
+				},
+			),
+		),
+		map[string]*schema.StepOutputSchema{
+			"success": greetSuccessOutputSchema,
+			"error": schema.NewStepOutputSchema(
+				schema.NewScopeSchema(schema.NewObjectSchema("Error", map[string]*schema.PropertySchema{})),
+				schema.NewDisplayValue(schema.PointerTo("Failed"), nil, nil),
+				true,
+			),
+		},
+		map[string]schema.CallableSignal{
+			"pause": schema.NewCallableSignal[any, map[string]any](
+				"pause",
+				schema.NewScopeSchema(schema.NewObjectSchema("Pause", map[string]*schema.PropertySchema{})),
+				schema.NewDisplayValue(nil, schema.PointerTo("Pauses the download."), nil),
+				func(_ context.Context, _ any, _ map[string]any) {},
+			),
+		},
+		map[string]*schema.SignalSchema{
+			"progress": schema.NewSignalSchema(
+				"progress",
+				schema.NewScopeSchema(schema.NewObjectSchema("Progress", map[string]*schema.PropertySchema{
+					"percent": schema.NewPropertySchema(
+						schema.NewFloatSchema(schema.PointerTo(0.0), schema.PointerTo(100.0), nil),
+						nil,
+						true,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+					),
+				})),
+				nil,
+			),
+		},
+		schema.NewDisplayValue(schema.PointerTo("Download"), schema.PointerTo("Downloads a file."), nil),
+		nil,
+		func(_ context.Context, _ any, _ map[string]any) (string, any) {
+			return "error", map[string]any{}
+		},
+	),
+)
+
+func TestWriteDocs_Markdown(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.NoError(t, plugin.WriteDocs(buf, docsTestSchema, plugin.DocsFormatMarkdown))
+	docs := buf.String()
+	for _, expected := range []string{
+		"# Step: Download (`download`)\n\nDownloads a file.\n",
+		"- **`url`** (string, optional): URL. Address to download.\n" +
+			"  - Minimum length: 1\n" +
+			"  - Required if `path` is not set.\n" +
+			"  - Conflicts with `path`.\n" +
+			"  - Examples: `\"https://example.com\"`\n",
+		"- **`limit`** (integer, optional)\n" +
+			"  - Minimum value: 0\n" +
+			"  - Units: bytes, may also be written with units (`B`, `kB`, `MB`, `GB`, `TB`, `PB`)\n" +
+			"  - Required if `url` is set.\n" +
+			"  - Default: `1024`\n",
+		"- **`name`** (string, optional)\n  - Required if none of `path`, `url` is set.\n",
+		"- **`mirrors`** (list of object `Mirror`, optional)\n  - Maximum items: 3\n",
+		"### Object `Mirror`\n\n- **`protocol`** (enum of strings, required)\n" +
+			"  - Values: `http` (HTTP), `https` (HTTPS)\n",
+		"### Output `error` (error)\n\nFailed\n",
+		"### Output `success`\n",
+		"## Signal handlers\n\n### Signal `pause`\n\nPauses the download.\n",
+		"## Signal emitters\n\n### Signal `progress`\n\n- **`percent`** (float, required)\n",
+	} {
+		assert.Contains(t, docs, expected)
+	}
+}
+
+func TestWriteDocs_Text(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.NoError(t, plugin.WriteDocs(buf, docsTestSchema, plugin.DocsFormatText))
+	docs := buf.String()
+	assert.Contains(t, docs, "Step: Download (download)\n=========================\n")
+	assert.Contains(t, docs, "* url (string, optional): URL. Address to download.\n    * Minimum length: 1\n")
+	assert.Contains(t, docs, "Output error (error)\n")
+}
+
+func TestWriteDocs_InvalidFormat(t *testing.T) {
+	assert.Error(t, plugin.WriteDocs(&bytes.Buffer{}, docsTestSchema, "html"))
+}
+
+func TestRun_Docs(t *testing.T) {
+	stdout, _, exitCode := runInProcess(nil, "--describe", "--format", "text")
+	assert.Equals(t, exitCode, plugin.ExitCodeSuccess)
+	assert.Contains(t, stdout, "Step: greet (greet)")
+	assert.Contains(t, stdout, "Output error (error)")
+}
@@ -0,0 +1,63 @@
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// HostStepSeparator separates the plugin name from the original step ID in the step IDs exposed by a host.
+const HostStepSeparator = "_"
+
+var hostedPluginNamePattern = regexp.MustCompile("^[a-zA-Z0-9-]+$")
+
+// HostedPlugin is a single plugin to bundle into a host.
+type HostedPlugin struct {
+	// Name prefixes the IDs of the steps of the plugin. It may contain letters, numbers and dashes, but no
+	// HostStepSeparator, so that the prefixed step IDs can't collide.
+	Name string
+	// Schema is the schema of the plugin.
+	Schema *schema.CallableSchema
+}
+
+// NewHost bundles several plugins into a single callable schema that can be passed to Run or atp.RunATPServer. Each
+// step is exposed as <plugin name>_<step ID>, so a step "create" of the plugin "kubernetes" becomes
+// "kubernetes_create". The hello message then contains the steps of all plugins.
+func NewHost(plugins ...HostedPlugin) (*schema.CallableSchema, error) {
+	var steps []schema.CallableStep
+	pluginNames := map[string]struct{}{}
+	for _, plugin := range plugins {
+		if !hostedPluginNamePattern.MatchString(plugin.Name) {
+			return nil, fmt.Errorf("invalid hosted plugin name: '%s' (must match %s)", plugin.Name, hostedPluginNamePattern)
+		}
+		if _, ok := pluginNames[plugin.Name]; ok {
+			return nil, fmt.Errorf("duplicate hosted plugin name: %s", plugin.Name)
+		}
+		pluginNames[plugin.Name] = struct{}{}
+		if plugin.Schema == nil {
+			return nil, fmt.Errorf("hosted plugin %s has no schema", plugin.Name)
+		}
+		for stepID, step := range plugin.Schema.StepsValue {
+			steps = append(steps, hostedStep{step, plugin.Name + HostStepSeparator + stepID})
+		}
+	}
+	return schema.NewCallableSchema(steps...), nil
+}
+
+// hostedStep exposes a step of a hosted plugin under its prefixed ID. Everything else is passed through unchanged.
+type hostedStep struct {
+	schema.CallableStep
+
+	id string
+}
+
+func (h hostedStep) ID() string {
+	return h.id
+}
+
+func (h hostedStep) ToStepSchema() *schema.StepSchema {
+	stepSchema := *h.CallableStep.ToStepSchema()
+	stepSchema.IDValue = h.id
+	return &stepSchema
+}
@@ -0,0 +1,116 @@
+package plugin_test
+
+import (
+	"context"
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.arcalot.io/log/v2"
+	"go.flow.arcalot.io/pluginsdk/atp"
+	"go.flow.arcalot.io/pluginsdk/plugin"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+type greetInput struct {
+	Name string `json:"name"`
+}
+
+type greetOutput struct {
+	Message string `json:"message"`
+}
+
+func newGreetSchema(stepID string, greeting string) *schema.CallableSchema {
+	return schema.NewCallableSchema(
+		schema.NewCallableStep[greetInput](
+			stepID,
+			schema.NewScopeSchema(
+				schema.NewStructMappedObjectSchema[greetInput](
+					"Input",
+					map[string]*schema.PropertySchema{
+						"name": schema.NewPropertySchema(
+							schema.NewStringSchema(nil, nil, nil),
+							nil,
+							true,
+							nil,
+							nil,
+							nil,
+							nil,
+							nil,
+						),
+					},
+				),
+			),
+			map[string]*schema.StepOutputSchema{
+				"success": schema.NewStepOutputSchema(
+					schema.NewScopeSchema(
+						schema.NewStructMappedObjectSchema[greetOutput](
+							"Output",
+							map[string]*schema.PropertySchema{
+								"message": schema.NewPropertySchema(
+									schema.NewStringSchema(nil, nil, nil),
+									nil,
+									true,
+									nil,
+									nil,
+									nil,
+									nil,
+									nil,
+								),
+							},
+						),
+					),
+					nil,
+					false,
+				),
+			},
+			nil,
+			func(_ context.Context, input greetInput) (string, any) {
+				return "success", greetOutput{Message: greeting + ", " + input.Name + "!"}
+			},
+		),
+	)
+}
+
+func TestNewHost(t *testing.T) {
+	host, err := plugin.NewHost(
+		plugin.HostedPlugin{Name: "english", Schema: newGreetSchema("greet", "Hello")},
+		plugin.HostedPlugin{Name: "german", Schema: newGreetSchema("greet", "Hallo")},
+	)
+	assert.NoError(t, err)
+
+	cli, err := atp.NewInProcessClient(context.Background(), host, log.NewTestLogger(t))
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, cli.Close())
+	}()
+	hostSchema, err := cli.ReadSchema()
+	assert.NoError(t, err)
+	assert.Equals(t, len(hostSchema.Steps()), 2)
+	assert.Equals(t, hostSchema.Steps()["english_greet"].ID(), "english_greet")
+	assert.Equals(t, hostSchema.Steps()["german_greet"].ID(), "german_greet")
+
+	result, err := cli.ExecuteWithMetrics(schema.Input{
+		ID:        "german_greet",
+		InputData: map[string]any{"name": "Arca Lot"},
+	}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equals(t, result.OutputID, "success")
+	assert.Equals(t, result.OutputData.(map[any]any)["message"].(string), "Hallo, Arca Lot!")
+	assert.NoError(t, cli.Wait())
+}
+
+func TestNewHost_Errors(t *testing.T) {
+	_, err := plugin.NewHost(
+		plugin.HostedPlugin{Name: "english", Schema: newGreetSchema("greet", "Hello")},
+		plugin.HostedPlugin{Name: "english", Schema: newGreetSchema("greet", "Hello")},
+	)
+	assert.Error(t, err)
+
+	_, err = plugin.NewHost(
+		plugin.HostedPlugin{Name: "en_US", Schema: newGreetSchema("greet", "Hello")},
+	)
+	assert.Error(t, err)
+
+	_, err = plugin.NewHost(plugin.HostedPlugin{Name: "english"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,327 @@
+package plugin
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "go.arcalot.io/log/v2"
+
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// MaxHTTPRequestSize is the largest request body the HTTP handler accepts.
+const MaxHTTPRequestSize = 16 * 1024 * 1024
+
+// RunIDParameter is the query parameter that sets the run ID of a step execution over HTTP. Signals are sent to a run
+// by its ID, so clients that want to send signals should choose the run ID before starting the step.
+const RunIDParameter = "run"
+
+// httpShutdownTimeout is how long running requests may take to finish when the HTTP server is stopped.
+const httpShutdownTimeout = 30 * time.Second
+
+// NewHTTPHandler exposes the steps of a schema over HTTP with the following endpoints:
+//
+//   - GET /schema returns the serialized schema.
+//   - POST /steps/{step} runs a step with the JSON input in the request body and returns the run ID, the output ID
+//     and the output data. The optional run query parameter sets the run ID.
+//   - POST /steps/{step}/runs/{run}/signals/{signal} sends the JSON signal data in the request body to a running step.
+//
+// Invalid input results in a 400 response listing every problem with its path. Every run gets its own step data, and
+// signals only reach the run they are sent to.
+func NewHTTPHandler(s *schema.CallableSchema, logger log.Logger) http.Handler {
+	return &httpHandler{
+		schema: s,
+		logger: logger,
+		runs:   map[string]*httpRun{},
+	}
+}
+
+type httpHandler struct {
+	schema *schema.CallableSchema
+	logger log.Logger
+	lock   sync.Mutex
+	runs   map[string]*httpRun
+}
+
+type httpRun struct {
+	stepID string
+	// ctx is the execution context of the run. Signals are called with it so they reach the step data of this run.
+	ctx context.Context
+}
+
+// httpOutput is the response of a step execution.
+type httpOutput struct {
+	RunID      string `json:"run_id"`
+	OutputID   string `json:"output_id"`
+	OutputData any    `json:"output_data"`
+	// Error is true if the output is marked as an error output in the schema.
+	Error bool `json:"error"`
+}
+
+// httpError is the response of failed requests.
+type httpError struct {
+	Error string `json:"error"`
+	// ConstraintErrors lists the problems with the input data, if the input was invalid.
+	ConstraintErrors []httpConstraintError `json:"constraint_errors,omitempty"`
+}
+
+type httpConstraintError struct {
+	Path    []string `json:"path"`
+	Message string   `json:"message"`
+}
+
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(path) == 1 && path[0] == "schema":
+		if !requireMethod(w, r, http.MethodGet) {
+			return
+		}
+		h.serveSchema(w)
+	case len(path) == 2 && path[0] == "steps":
+		if !requireMethod(w, r, http.MethodPost) {
+			return
+		}
+		h.serveStep(w, r, path[1])
+	case len(path) == 6 && path[0] == "steps" && path[2] == "runs" && path[4] == "signals":
+		if !requireMethod(w, r, http.MethodPost) {
+			return
+		}
+		h.serveSignal(w, r, path[1], path[3], path[5])
+	default:
+		writeHTTPError(w, http.StatusNotFound, fmt.Errorf("no such endpoint: %s", r.URL.Path))
+	}
+}
+
+func requireMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeHTTPError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed, use %s", r.Method, method))
+	return false
+}
+
+func (h *httpHandler) serveSchema(w http.ResponseWriter) {
+	serializedSchema, err := h.schema.SelfSerialize()
+	if err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, fmt.Errorf("failed to serialize schema (%w)", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, jsonCompatible(serializedSchema))
+}
+
+func (h *httpHandler) serveStep(w http.ResponseWriter, r *http.Request, stepID string) {
+	step, ok := h.schema.StepsValue[stepID]
+	if !ok {
+		writeHTTPError(w, http.StatusNotFound, fmt.Errorf("no such step: %s", stepID))
+		return
+	}
+	input, ok := readJSONBody(w, r, step.Input())
+	if !ok {
+		return
+	}
+	runID := r.URL.Query().Get(RunIDParameter)
+	if runID == "" {
+		runID = newRunID()
+	}
+	ctx := schema.ContextWithExecution(r.Context())
+	if err := h.startRun(ctx, runID, stepID); err != nil {
+		writeHTTPError(w, http.StatusConflict, err)
+		return
+	}
+	defer h.finishRun(runID)
+
+	h.logger.Debugf("Running step %s as run %s.", stepID, runID)
+	outputID, outputData, err := h.schema.CallStep(ctx, stepID, input)
+	if err != nil {
+		var invalidInput schema.InvalidInputError
+		if errors.As(err, &invalidInput) {
+			writeHTTPError(w, http.StatusBadRequest, err)
+			return
+		}
+		h.logger.Errorf("Run %s of step %s failed (%v)", runID, stepID, err)
+		writeHTTPError(w, http.StatusInternalServerError, fmt.Errorf("step %s failed (%w)", stepID, err))
+		return
+	}
+	writeJSON(w, http.StatusOK, httpOutput{
+		RunID:      runID,
+		OutputID:   outputID,
+		OutputData: jsonCompatible(outputData),
+		Error:      step.Outputs()[outputID].Error(),
+	})
+}
+
+func (h *httpHandler) startRun(ctx context.Context, runID string, stepID string) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if _, ok := h.runs[runID]; ok {
+		return fmt.Errorf("run %s is already running", runID)
+	}
+	h.runs[runID] = &httpRun{stepID: stepID, ctx: ctx}
+	return nil
+}
+
+func (h *httpHandler) finishRun(runID string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	delete(h.runs, runID)
+}
+
+func (h *httpHandler) serveSignal(
+	w http.ResponseWriter,
+	r *http.Request,
+	stepID string,
+	runID string,
+	signalID string,
+) {
+	step, ok := h.schema.StepsValue[stepID]
+	if !ok {
+		writeHTTPError(w, http.StatusNotFound, fmt.Errorf("no such step: %s", stepID))
+		return
+	}
+	h.lock.Lock()
+	run, ok := h.runs[runID]
+	h.lock.Unlock()
+	if !ok || run.stepID != stepID {
+		writeHTTPError(w, http.StatusNotFound, fmt.Errorf("step %s has no active run %s", stepID, runID))
+		return
+	}
+	signal, ok := step.SignalHandlers()[signalID]
+	if !ok {
+		writeHTTPError(w, http.StatusNotFound, fmt.Errorf("step %s has no signal handler %s", stepID, signalID))
+		return
+	}
+	data, ok := readJSONBody(w, r, signal.DataSchema())
+	if !ok {
+		return
+	}
+	if err := h.schema.CallSignal(run.ctx, stepID, signalID, data); err != nil {
+		var invalidInput schema.InvalidInputError
+		if errors.As(err, &invalidInput) {
+			writeHTTPError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeHTTPError(w, http.StatusInternalServerError, fmt.Errorf("signal %s failed (%w)", signalID, err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readJSONBody decodes the request body and checks it against the schema. If it fails, the error response is written
+// and false is returned.
+func readJSONBody(w http.ResponseWriter, r *http.Request, scope schema.Scope) (any, bool) {
+	var data any
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxHTTPRequestSize))
+	// Numbers are decoded exactly, float64 would change integers above 2^53.
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil && !errors.Is(err, io.EOF) {
+		writeHTTPError(w, http.StatusBadRequest, fmt.Errorf("failed to decode request body (%w)", err))
+		return nil, false
+	}
+	if err := schema.ConvertJSONNumbers(&data); err != nil {
+		writeHTTPError(w, http.StatusBadRequest, fmt.Errorf("failed to decode request body (%w)", err))
+		return nil, false
+	}
+	if data == nil {
+		// An empty body stands for an empty object, which is convenient for signals without data.
+		data = map[string]any{}
+	}
+	if errs := schema.CollectConstraintErrors(scope, data); len(errs) > 0 {
+		response := httpError{
+			Error:            fmt.Sprintf("invalid input, %d problem(s) found", len(errs)),
+			ConstraintErrors: make([]httpConstraintError, len(errs)),
+		}
+		for i, err := range errs {
+			message := err.Message
+			if err.Cause != nil {
+				message += " (" + err.Cause.Error() + ")"
+			}
+			response.ConstraintErrors[i] = httpConstraintError{Path: append([]string{}, err.Path...), Message: message}
+		}
+		writeJSON(w, http.StatusBadRequest, response)
+		return nil, false
+	}
+	return data, true
+}
+
+func writeHTTPError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, httpError{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(data)
+}
+
+func newRunID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(fmt.Errorf("failed to generate run ID (%w)", err))
+	}
+	return hex.EncodeToString(id)
+}
+
+// serveHTTP implements the http command.
+func serveHTTP(ctx context.Context, env *environment, args []string) int {
+	flags := env.newFlagSet("http")
+	tlsCert := flags.String("tls-cert", "", "PEM file holding the server certificate.")
+	tlsKey := flags.String("tls-key", "", "PEM file holding the server key.")
+	if err := flags.Parse(args); err != nil {
+		return parseErrorExitCode(err)
+	}
+	if flags.NArg() != 1 {
+		_, _ = fmt.Fprintln(env.stderr, "Exactly one address to listen on is required, for example :8080.")
+		return ExitCodeUsage
+	}
+	if (*tlsCert == "") != (*tlsKey == "") {
+		_, _ = fmt.Fprintln(env.stderr, "--tls-cert and --tls-key must be set together.")
+		return ExitCodeUsage
+	}
+	listener, err := net.Listen("tcp", flags.Arg(0))
+	if err != nil {
+		_, _ = fmt.Fprintf(env.stderr, "Failed to listen on %s (%v)\n", flags.Arg(0), err)
+		return ExitCodeFailure
+	}
+	server := &http.Server{
+		Handler:           NewHTTPHandler(env.schema, env.logger),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+	serveResult := make(chan error, 1)
+	go func() {
+		env.logger.Infof("Serving HTTP on %s.", listener.Addr().String())
+		if *tlsCert != "" {
+			serveResult <- server.ServeTLS(listener, *tlsCert, *tlsKey)
+		} else {
+			serveResult <- server.Serve(listener)
+		}
+	}()
+	select {
+	case err = <-serveResult:
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+		err = server.Shutdown(shutdownCtx)
+		cancel()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		_, _ = fmt.Fprintf(env.stderr, "HTTP server failed (%v)\n", err)
+		return ExitCodeFailure
+	}
+	return ExitCodeSuccess
+}
@@ -0,0 +1,237 @@
+package plugin_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.arcalot.io/assert"
+	"go.arcalot.io/log/v2"
+	"go.flow.arcalot.io/pluginsdk/plugin"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+type waitStepData struct {
+	messages chan string
+}
+
+type waitSignalInput struct {
+	Message string `json:"message"`
+}
+
+var waitSignalInputSchema = schema.NewScopeSchema(
+	schema.NewStructMappedObjectSchema[waitSignalInput](
+		"Message",
+		map[string]*schema.PropertySchema{
+			"message": schema.NewPropertySchema(
+				schema.NewStringSchema(schema.IntPointer(1), nil, nil),
+				nil,
+				true,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+		},
+	),
+)
+
+// waitStep greets with the message received in the finish signal.
+var waitStep = schema.NewCallableStepWithSignals[*waitStepData, greetInput](
+	"wait",
+	greetInputSchema,
+	map[string]*schema.StepOutputSchema{
+		"success": greetSuccessOutputSchema,
+	},
+	map[string]schema.CallableSignal{
+		"finish": schema.NewCallableSignal(
+			"finish",
+			waitSignalInputSchema,
+			nil,
+			func(_ context.Context, data *waitStepData, input waitSignalInput) {
+				data.messages <- input.Message
+			},
+		),
+	},
+	nil,
+	nil,
+	func() *waitStepData {
+		return &waitStepData{messages: make(chan string, 1)}
+	},
+	func(ctx context.Context, data *waitStepData, input greetInput) (string, any) {
+		select {
+		case message := <-data.messages:
+			return "success", greetOutput{Message: message + ", " + input.Name + "!"}
+		case <-ctx.Done():
+			return "success", greetOutput{Message: "Cancelled"}
+		}
+	},
+)
+
+func newHTTPTestServer(t *testing.T) *httptest.Server {
+	s := schema.NewCallableSchema(runTestSchema.StepsValue["greet"], waitStep)
+	server := httptest.NewServer(plugin.NewHTTPHandler(s, log.NewTestLogger(t)))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func httpRequest(t *testing.T, method string, url string, body string) (int, map[string]any) {
+	request, err := http.NewRequest(method, url, bytes.NewBufferString(body)) //nolint:noctx
+	assert.NoError(t, err)
+	response, err := http.DefaultClient.Do(request)
+	assert.NoError(t, err)
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	var result map[string]any
+	if response.StatusCode != http.StatusNoContent {
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&result))
+	}
+	return response.StatusCode, result
+}
+
+func TestHTTP_Schema(t *testing.T) {
+	server := newHTTPTestServer(t)
+	status, result := httpRequest(t, http.MethodGet, server.URL+"/schema", "")
+	assert.Equals(t, status, http.StatusOK)
+	steps := result["steps"].(map[string]any)
+	assert.Equals(t, len(steps), 2)
+}
+
+func TestHTTP_Step(t *testing.T) {
+	server := newHTTPTestServer(t)
+	status, result := httpRequest(t, http.MethodPost, server.URL+"/steps/greet?run=first", `{"name": "Arca Lot"}`)
+	assert.Equals(t, status, http.StatusOK)
+	assert.Equals(t, result["run_id"], any("first"))
+	assert.Equals(t, result["output_id"], any("success"))
+	assert.Equals(t, result["output_data"], any(map[string]any{"message": "Hello, Arca Lot!"}))
+	assert.Equals(t, result["error"], any(false))
+
+	status, result = httpRequest(t, http.MethodPost, server.URL+"/steps/greet", `{"name": "nobody"}`)
+	assert.Equals(t, status, http.StatusOK)
+	assert.Equals(t, result["output_id"], any("error"))
+	assert.Equals(t, result["error"], any(true))
+}
+
+func TestHTTP_InvalidInput(t *testing.T) {
+	server := newHTTPTestServer(t)
+	status, result := httpRequest(t, http.MethodPost, server.URL+"/steps/greet", `{"nam": "Arca Lot"}`)
+	assert.Equals(t, status, http.StatusBadRequest)
+	constraintErrors := result["constraint_errors"].([]any)
+	assert.Equals(t, len(constraintErrors), 2)
+	assert.Equals(t, constraintErrors[1].(map[string]any)["path"], any([]any{"name"}))
+	// The unknown key is reported under its own path, so that clients can point at the field.
+	assert.Equals(t, constraintErrors[0].(map[string]any)["path"], any([]any{"nam"}))
+
+	status, _ = httpRequest(t, http.MethodPost, server.URL+"/steps/greet", `{"name": `)
+	assert.Equals(t, status, http.StatusBadRequest)
+	status, _ = httpRequest(t, http.MethodPost, server.URL+"/steps/greet", `{"name": 100000000000000000000}`)
+	assert.Equals(t, status, http.StatusBadRequest)
+}
+
+func TestHTTP_LargeInteger(t *testing.T) {
+	server := newHTTPTestServer(t)
+	// The integer is above 2^53, so it would change if it was decoded as float64.
+	status, result := httpRequest(t, http.MethodPost, server.URL+"/steps/greet", `{"name": 9007199254740993}`)
+	assert.Equals(t, status, http.StatusOK)
+	assert.Equals(t, result["output_data"], any(map[string]any{"message": "Hello, 9007199254740993!"}))
+}
+
+func TestHTTP_Errors(t *testing.T) {
+	server := newHTTPTestServer(t)
+	status, _ := httpRequest(t, http.MethodPost, server.URL+"/steps/wave", `{}`)
+	assert.Equals(t, status, http.StatusNotFound)
+	status, _ = httpRequest(t, http.MethodGet, server.URL+"/steps/greet", "")
+	assert.Equals(t, status, http.StatusMethodNotAllowed)
+	status, _ = httpRequest(t, http.MethodPost, server.URL+"/steps/wait/runs/none/signals/finish", `{}`)
+	assert.Equals(t, status, http.StatusNotFound)
+}
+
+type httpStepResult struct {
+	status int
+	result map[string]any
+}
+
+// startHTTPRun starts a run of the wait step in the background and waits until it accepts signals.
+func startHTTPRun(t *testing.T, server *httptest.Server, runID string, name string) <-chan httpStepResult {
+	done := make(chan httpStepResult, 1)
+	go func() {
+		status, result := httpRequest(t, http.MethodPost, server.URL+"/steps/wait?run="+runID, `{"name": "`+name+`"}`)
+		done <- httpStepResult{status, result}
+	}()
+
+	signalURL := server.URL + "/steps/wait/runs/" + runID + "/signals/finish"
+	// The run is only known once the step request has been received.
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		status, _ := httpRequest(t, http.MethodPost, signalURL, `{"message": ""}`)
+		if status == http.StatusBadRequest {
+			return done
+		}
+		assert.Equals(t, status, http.StatusNotFound)
+		if time.Now().After(deadline) {
+			t.Fatalf("run did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHTTP_Signal(t *testing.T) {
+	server := newHTTPTestServer(t)
+	done := startHTTPRun(t, server, "waiting", "Arca Lot")
+
+	// A run ID can only be used by one run at a time.
+	status, _ := httpRequest(t, http.MethodPost, server.URL+"/steps/wait?run=waiting", `{"name": "Arca Lot"}`)
+	assert.Equals(t, status, http.StatusConflict)
+
+	signalURL := server.URL + "/steps/wait/runs/waiting/signals/finish"
+	status, _ = httpRequest(t, http.MethodPost, signalURL, `{"message": "Good day"}`)
+	assert.Equals(t, status, http.StatusNoContent)
+	result := <-done
+	assert.Equals(t, result.status, http.StatusOK)
+	assert.Equals(t, result.result["output_data"], any(map[string]any{"message": "Good day, Arca Lot!"}))
+
+	status, _ = httpRequest(t, http.MethodPost, signalURL, `{"message": "Too late"}`)
+	assert.Equals(t, status, http.StatusNotFound)
+}
+
+func TestHTTP_SignalConcurrentRuns(t *testing.T) {
+	server := newHTTPTestServer(t)
+	first := startHTTPRun(t, server, "first", "Arca")
+	second := startHTTPRun(t, server, "second", "Lot")
+
+	// Each run has its own step data, so the signals reach only the run they are sent to.
+	status, _ := httpRequest(t, http.MethodPost, server.URL+"/steps/wait/runs/second/signals/finish", `{"message": "Bye"}`)
+	assert.Equals(t, status, http.StatusNoContent)
+	result := <-second
+	assert.Equals(t, result.status, http.StatusOK)
+	assert.Equals(t, result.result["output_data"], any(map[string]any{"message": "Bye, Lot!"}))
+	status, _ = httpRequest(t, http.MethodPost, server.URL+"/steps/wait/runs/first/signals/finish", `{"message": "Hi"}`)
+	assert.Equals(t, status, http.StatusNoContent)
+	result = <-first
+	assert.Equals(t, result.status, http.StatusOK)
+	assert.Equals(t, result.result["output_data"], any(map[string]any{"message": "Hi, Arca!"}))
+
+	// Running the step again starts over with new step data.
+	third := startHTTPRun(t, server, "first", "again")
+	status, _ = httpRequest(t, http.MethodPost, server.URL+"/steps/wait/runs/first/signals/finish", `{"message": "Hello"}`)
+	assert.Equals(t, status, http.StatusNoContent)
+	result = <-third
+	assert.Equals(t, result.status, http.StatusOK)
+	assert.Equals(t, result.result["output_data"], any(map[string]any{"message": "Hello, again!"}))
+}
+
+func TestRun_HTTP(t *testing.T) {
+	// The server runs until the timeout cancels it.
+	_, stderr, exitCode := runInProcess(nil, "--timeout", "100ms", "--http", "127.0.0.1:0")
+	assert.Equals(t, exitCode, plugin.ExitCodeTimeout)
+	assert.Contains(t, stderr, "Serving HTTP on 127.0.0.1:")
+
+	_, _, exitCode = runInProcess(nil, "http")
+	assert.Equals(t, exitCode, plugin.ExitCodeUsage)
+}
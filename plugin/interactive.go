@@ -0,0 +1,338 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"go.flow.arcalot.io/pluginsdk/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// runInteractive implements the interactive command. It prompts for the input of a step property by property, runs
+// the step and accepts signal commands while the step is running.
+func runInteractive(ctx context.Context, env *environment, args []string) int {
+	flags := env.newFlagSet("interactive")
+	stepID := flags.String("step", "", "ID of the step to run. Prompted for if the plugin has multiple steps.")
+	if err := flags.Parse(args); err != nil {
+		return parseErrorExitCode(err)
+	}
+	p := &prompter{
+		reader: newLineReader(env.stdin),
+		out:    env.stdout,
+		hints:  &textWriter{},
+	}
+
+	id := *stepID
+	if id == "" && len(env.schema.StepsValue) == 1 {
+		id, _ = selectStep(env.schema, "")
+	}
+	for id == "" || env.schema.StepsValue[id] == nil {
+		if id != "" {
+			p.printf("No such step: %s\n", id)
+		}
+		answer, err := p.ask(fmt.Sprintf("Step (%s)", strings.Join(stepIDs(env.schema), ", ")))
+		if err != nil {
+			return p.inputEnded(env)
+		}
+		id = answer
+	}
+	step := env.schema.StepsValue[id]
+	p.printf("Running step %s.\n", id)
+	if summary := displaySummary(step.Display()); summary != "" {
+		p.printf("%s\n", summary)
+	}
+
+	input, err := p.askObject(step.Input().Objects()[step.Input().Root()], "")
+	if err != nil {
+		return p.inputEnded(env)
+	}
+	if errs := schema.CollectConstraintErrors(step.Input(), input); len(errs) > 0 {
+		_, _ = fmt.Fprintf(env.stderr, "The input is not valid for step %s:\n", id)
+		for _, err := range errs {
+			_, _ = fmt.Fprintf(env.stderr, "  %s\n", err.Error())
+		}
+		return ExitCodeInvalidInput
+	}
+	return p.runStep(ctx, env, step, input)
+}
+
+// prompter asks for values on the terminal.
+type prompter struct {
+	reader *lineReader
+	out    io.Writer
+	hints  docWriter
+}
+
+func (p *prompter) printf(format string, args ...any) {
+	_, _ = fmt.Fprintf(p.out, format, args...)
+}
+
+// inputEnded handles the end of the input while prompting.
+func (p *prompter) inputEnded(env *environment) int {
+	_, _ = fmt.Fprintln(env.stderr, "\nThe input ended before all values were entered.")
+	return ExitCodeUsage
+}
+
+// ask prints the prompt and returns the next line without surrounding spaces.
+func (p *prompter) ask(prompt string) (string, error) {
+	p.printf("%s> ", prompt)
+	line, err := p.reader.readLine(context.Background())
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// askObject prompts for each property of the object and returns the serialized object. Nested objects are prompted
+// for property by property, with the path of the property as prefix.
+func (p *prompter) askObject(object schema.Object, prefix string) (map[string]any, error) {
+	result := map[string]any{}
+	properties := object.Properties()
+	for _, id := range sortedKeys(properties) {
+		property := properties[id]
+		if property.Disabled {
+			continue
+		}
+		path := prefix + id
+		p.describeProperty(path, property)
+		value, ok, err := p.askProperty(path, property)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result[id] = value
+		}
+	}
+	return result, nil
+}
+
+func (p *prompter) describeProperty(path string, property *schema.PropertySchema) {
+	attributes := []string{describeType(p.hints, property.Type())}
+	if property.Required() {
+		attributes = append(attributes, "required")
+	} else {
+		attributes = append(attributes, "optional")
+	}
+	text := fmt.Sprintf("\n%s (%s)", path, strings.Join(attributes, ", "))
+	if summary := displaySummary(property.Display()); summary != "" {
+		text += ": " + summary
+	}
+	p.printf("%s\n", text)
+	for _, detail := range propertyDetails(p.hints, property) {
+		p.printf("  %s\n", detail)
+	}
+	switch property.Type().TypeID() {
+	case schema.TypeIDList, schema.TypeIDMap, schema.TypeIDOneOfString, schema.TypeIDOneOfInt, schema.TypeIDAny:
+		p.printf("  Enter the value as YAML on a single line, for example [a, b] or {key: value}.\n")
+	}
+}
+
+// askProperty prompts for the value of a property until a valid value is entered. An empty answer leaves out
+// optional properties and properties with a default value.
+func (p *prompter) askProperty(path string, property *schema.PropertySchema) (any, bool, error) {
+	canSkip := !property.Required() || property.Default() != nil
+	if object, ok := property.Type().(schema.Object); ok {
+		if !property.Required() {
+			answer, err := p.ask(fmt.Sprintf("Set %s? [y/N]", path))
+			if err != nil {
+				return nil, false, err
+			}
+			if !strings.HasPrefix(strings.ToLower(answer), "y") {
+				return nil, false, nil
+			}
+		}
+		value, err := p.askObject(object, path+".")
+		return value, err == nil, err
+	}
+	for {
+		answer, err := p.ask(path)
+		if err != nil {
+			return nil, false, err
+		}
+		if answer == "" {
+			if canSkip {
+				return nil, false, nil
+			}
+			p.printf("A value is required.\n")
+			continue
+		}
+		value, err := parseTextValue(property.Type(), answer)
+		if err == nil {
+			_, err = property.Type().Unserialize(value)
+		}
+		if err != nil {
+			p.printf("Invalid value: %v\n", err)
+			continue
+		}
+		return value, true, nil
+	}
+}
+
+// runStep runs the step while accepting signal commands, then prints the output. Reading the commands stops when
+// the step finishes, so that no input is consumed after the step.
+func (p *prompter) runStep(ctx context.Context, env *environment, step schema.CallableStep, input any) int {
+	type stepResult struct {
+		outputID   string
+		outputData any
+		err        error
+	}
+	// The signals must be sent in the same execution as the step to reach its step data.
+	ctx = schema.ContextWithExecution(ctx)
+	stepCtx, stepFinished := context.WithCancel(ctx)
+	defer stepFinished()
+	done := make(chan stepResult, 1)
+	go func() {
+		defer stepFinished()
+		outputID, outputData, err := env.schema.CallStep(ctx, step.ID(), input)
+		done <- stepResult{outputID, outputData, err}
+	}()
+
+	if len(step.SignalHandlers()) > 0 {
+		p.printf("\nStep %s is running. Enter \"signal <id> [data as YAML]\" to send a signal, \"help\" to list them.\n",
+			step.ID())
+		for {
+			line, err := p.reader.readLine(stepCtx)
+			if command := strings.TrimSpace(line); command != "" {
+				p.handleCommand(ctx, env, step, command)
+			}
+			if err != nil {
+				break
+			}
+		}
+		p.reader.stop()
+	}
+	result := <-done
+	return p.printResult(env, step, result.outputID, result.outputData, result.err)
+}
+
+func (p *prompter) handleCommand(ctx context.Context, env *environment, step schema.CallableStep, command string) {
+	fields := strings.SplitN(command, " ", 3)
+	if fields[0] != "signal" || len(fields) < 2 {
+		p.printf("Signals of step %s:\n", step.ID())
+		for _, id := range sortedKeys(step.SignalHandlers()) {
+			signal := step.SignalHandlers()[id]
+			p.printf("  %s\n", id)
+			if summary := displaySummary(signal.Display()); summary != "" {
+				p.printf("    %s\n", summary)
+			}
+			p.printf("    Data: %s\n", describeType(p.hints, signal.DataSchema()))
+		}
+		return
+	}
+	signalID := fields[1]
+	var data any = map[string]any{}
+	if len(fields) == 3 {
+		if err := yaml.Unmarshal([]byte(fields[2]), &data); err != nil {
+			p.printf("Invalid signal data: %v\n", err)
+			return
+		}
+	}
+	if err := env.schema.CallSignal(ctx, step.ID(), signalID, data); err != nil {
+		p.printf("Signal %s failed: %v\n", signalID, err)
+		return
+	}
+	p.printf("Signal %s sent.\n", signalID)
+}
+
+func (p *prompter) printResult(
+	env *environment,
+	step schema.CallableStep,
+	outputID string,
+	outputData any,
+	err error,
+) int {
+	if err != nil {
+		_, _ = fmt.Fprintf(env.stderr, "Step %s failed: %v\n", step.ID(), err)
+		return ExitCodeFailure
+	}
+	result, err := marshalOutput("yaml", map[string]any{
+		"output_id":   outputID,
+		"output_data": outputData,
+	})
+	if err != nil {
+		_, _ = fmt.Fprintf(env.stderr, "Failed to marshal the output of step %s (%v)\n", step.ID(), err)
+		return ExitCodeFailure
+	}
+	p.printf("\n%s", result)
+	if step.Outputs()[outputID].Error() {
+		return ExitCodeErrorOutput
+	}
+	return ExitCodeSuccess
+}
+
+// displaySummary returns the name and the description of a display as a single text.
+func displaySummary(display schema.Display) string {
+	name, description := schema.DisplayText(display)
+	if name != "" && description != "" {
+		return name + ". " + description
+	}
+	return name + description
+}
+
+// lineReader reads lines in the background, so that waiting for a line can be cancelled. A line that arrives after
+// the wait was cancelled is returned by the next read.
+type lineReader struct {
+	input   io.Reader
+	reader  *bufio.Reader
+	results chan lineResult
+	pending bool
+	// partial holds the start of a line whose read was interrupted by stop.
+	partial string
+}
+
+type lineResult struct {
+	line string
+	err  error
+}
+
+func newLineReader(input io.Reader) *lineReader {
+	return &lineReader{
+		input:   input,
+		reader:  bufio.NewReader(input),
+		results: make(chan lineResult, 1),
+	}
+}
+
+// readLine returns the next line, or the error of the context if it is done before a line arrives.
+func (r *lineReader) readLine(ctx context.Context) (string, error) {
+	if !r.pending {
+		r.pending = true
+		go func() {
+			line, err := r.reader.ReadString('\n')
+			r.results <- lineResult{line, err}
+		}()
+	}
+	select {
+	case result := <-r.results:
+		r.pending = false
+		line := r.partial + result.line
+		r.partial = ""
+		return line, result.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// stop interrupts a pending background read if the input supports read deadlines, like pipes do. Otherwise, the
+// background read ends with the next line, which is kept for the next call to readLine.
+func (r *lineReader) stop() {
+	input, ok := r.input.(interface{ SetReadDeadline(t time.Time) error })
+	if !r.pending || !ok || input.SetReadDeadline(time.Now()) != nil {
+		return
+	}
+	result := <-r.results
+	_ = input.SetReadDeadline(time.Time{})
+	if errors.Is(result.err, os.ErrDeadlineExceeded) {
+		r.pending = false
+		r.partial += result.line
+		return
+	}
+	// The line arrived before the deadline.
+	r.results <- result
+}
@@ -0,0 +1,79 @@
+package plugin_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/plugin"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+func TestRun_Interactive(t *testing.T) {
+	stdout, stderr, exitCode := runInProcess(strings.NewReader("\nArca Lot\n"), "interactive")
+	assert.Equals(t, stderr, "")
+	assert.Equals(t, exitCode, plugin.ExitCodeSuccess)
+	assert.Contains(t, stdout, "name (string, required)")
+	assert.Contains(t, stdout, "A value is required.")
+	assert.Contains(t, stdout, "message: Hello, Arca Lot!")
+}
+
+func TestRun_Interactive_Signals(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	exitCode := plugin.RunWithArgs(
+		context.Background(),
+		schema.NewCallableSchema(runTestSchema.StepsValue["greet"], waitStep),
+		[]string{"interactive"},
+		strings.NewReader("unknown\nwait\nArca Lot\nhelp\nsignal finish {message: Hi}\n"),
+		stdout,
+		stderr,
+	)
+	assert.Equals(t, stderr.String(), "")
+	assert.Equals(t, exitCode, plugin.ExitCodeSuccess)
+	assert.Contains(t, stdout.String(), "Step (greet, wait)> No such step: unknown")
+	assert.Contains(t, stdout.String(), "Signals of step wait:\n  finish\n    Data: object Message")
+	assert.Contains(t, stdout.String(), "Signal finish sent.")
+	assert.Contains(t, stdout.String(), "message: Hi, Arca Lot!")
+}
+
+func TestRun_Interactive_StopsReadingSignals(t *testing.T) {
+	stdin, input, err := os.Pipe()
+	assert.NoError(t, err)
+	defer func() {
+		_ = stdin.Close()
+		_ = input.Close()
+	}()
+	_, err = input.WriteString("wait\nArca Lot\nsignal finish {message: Hi}\n")
+	assert.NoError(t, err)
+	stdout := &bytes.Buffer{}
+	exitCode := plugin.RunWithArgs(
+		context.Background(),
+		schema.NewCallableSchema(runTestSchema.StepsValue["greet"], waitStep),
+		[]string{"interactive"},
+		stdin,
+		stdout,
+		&bytes.Buffer{},
+	)
+	assert.Equals(t, exitCode, plugin.ExitCodeSuccess)
+	assert.Contains(t, stdout.String(), "message: Hi, Arca Lot!")
+
+	// The input after the step must be left for the next reader.
+	_, err = input.WriteString("next\n")
+	assert.NoError(t, err)
+	assert.NoError(t, stdin.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 16)
+	n, err := stdin.Read(buf)
+	assert.NoError(t, err)
+	assert.Equals(t, string(buf[:n]), "next\n")
+}
+
+func TestRun_Interactive_InputEnded(t *testing.T) {
+	_, stderr, exitCode := runInProcess(strings.NewReader(""), "interactive")
+	assert.Equals(t, exitCode, plugin.ExitCodeUsage)
+	assert.Contains(t, stderr, "The input ended")
+}
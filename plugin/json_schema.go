@@ -0,0 +1,49 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.flow.arcalot.io/pluginsdk/jsonschema"
+)
+
+// exportJSONSchema implements the json-schema command. It writes the JSON Schema of a step's input, or of one of its
+// outputs.
+func exportJSONSchema(_ context.Context, env *environment, args []string) int {
+	s, stdout, stderr := env.schema, env.stdout, env.stderr
+	flags := env.newFlagSet("json-schema")
+	stepID := flags.String("step", "", "ID of the step. May be omitted if the plugin has only one step.")
+	outputID := flags.String("output-id", "", "ID of the output to export instead of the input.")
+	if err := flags.Parse(args); err != nil {
+		return parseErrorExitCode(err)
+	}
+	id, err := selectStep(s, *stepID)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err.Error())
+		return ExitCodeUsage
+	}
+	step := s.StepsValue[id]
+
+	var document map[string]any
+	if *outputID == "" {
+		document, err = jsonschema.FromStepInput(step)
+	} else {
+		if _, ok := step.Outputs()[*outputID]; !ok {
+			_, _ = fmt.Fprintf(stderr, "Step %s has no output %s.\n", id, *outputID)
+			return ExitCodeUsage
+		}
+		document, err = jsonschema.FromStepOutput(step, *outputID)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err.Error())
+		return ExitCodeFailure
+	}
+	result, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Failed to marshal the JSON schema (%v)\n", err)
+		return ExitCodeFailure
+	}
+	_, _ = stdout.Write(append(result, '\n'))
+	return ExitCodeSuccess
+}
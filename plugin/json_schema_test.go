@@ -0,0 +1,35 @@
+package plugin_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/jsonschema"
+	"go.flow.arcalot.io/pluginsdk/plugin"
+)
+
+func TestRun_JSONSchema(t *testing.T) {
+	stdout, stderr, exitCode := runPlugin(t, "", "--json-schema", "--step", "greet")
+	assert.Equals(t, exitCode, plugin.ExitCodeSuccess)
+	assert.Equals(t, stderr, "")
+	var document map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &document))
+	assert.Equals(t, document["$schema"], any(jsonschema.Draft))
+	assert.Equals(t, document["$ref"], any("#/$defs/Input"))
+
+	stdout, _, exitCode = runPlugin(t, "", "--json-schema", "--output-id", "error")
+	assert.Equals(t, exitCode, plugin.ExitCodeSuccess)
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &document))
+	assert.Equals(t, document["$ref"], any("#/$defs/Error"))
+}
+
+func TestRun_JSONSchema_Errors(t *testing.T) {
+	_, stderr, exitCode := runPlugin(t, "", "--json-schema", "--step", "nonexistent")
+	assert.Equals(t, exitCode, plugin.ExitCodeUsage)
+	assert.Contains(t, stderr, "no such step: nonexistent")
+
+	_, stderr, exitCode = runPlugin(t, "", "--json-schema", "--output-id", "nonexistent")
+	assert.Equals(t, exitCode, plugin.ExitCodeUsage)
+	assert.Contains(t, stderr, "has no output nonexistent")
+}
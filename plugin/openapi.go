@@ -0,0 +1,39 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.flow.arcalot.io/pluginsdk/openapi"
+)
+
+// exportOpenAPI implements the openapi command. It writes the OpenAPI document of the endpoints served by the http
+// command. The title and the version default to the plugin metadata.
+func exportOpenAPI(_ context.Context, env *environment, args []string) int {
+	stdout, stderr := env.stdout, env.stderr
+	info := openapi.Info{}
+	if env.options.metadata != nil {
+		info.Title = env.options.metadata.Name
+		info.Version = env.options.metadata.Version
+	}
+	flags := env.newFlagSet("openapi")
+	flags.StringVar(&info.Title, "title", info.Title, "Title of the API. Defaults to the plugin name.")
+	flags.StringVar(&info.Version, "version", info.Version, "Version of the API. Defaults to the plugin version.")
+	flags.StringVar(&info.Description, "description", "", "Description of the API.")
+	if err := flags.Parse(args); err != nil {
+		return parseErrorExitCode(err)
+	}
+	document, err := openapi.Generate(env.schema, info)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err.Error())
+		return ExitCodeFailure
+	}
+	result, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Failed to marshal the OpenAPI document (%v)\n", err)
+		return ExitCodeFailure
+	}
+	_, _ = stdout.Write(append(result, '\n'))
+	return ExitCodeSuccess
+}
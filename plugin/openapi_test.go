@@ -0,0 +1,23 @@
+package plugin_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/openapi"
+	"go.flow.arcalot.io/pluginsdk/plugin"
+)
+
+func TestRunWithArgs_OpenAPI(t *testing.T) {
+	stdout, stderr, exitCode := runInProcess(nil, "--openapi", "--title", "Greeter", "--version", "1.0.0")
+	assert.Equals(t, exitCode, plugin.ExitCodeSuccess)
+	assert.Equals(t, stderr, "")
+	var document map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &document))
+	assert.Equals(t, document["openapi"], any(openapi.Version))
+	assert.Equals(t, document["info"], any(map[string]any{"title": "Greeter", "version": "1.0.0"}))
+	paths := document["paths"].(map[string]any)
+	_, ok := paths["/steps/greet"]
+	assert.Equals(t, ok, true)
+}
@@ -0,0 +1,50 @@
+package plugin
+
+import (
+	"go.flow.arcalot.io/pluginsdk/atp"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// Option configures how Run serves a plugin.
+type Option func(*runOptions)
+
+type runOptions struct {
+	metadata                *atp.PluginMetadata
+	extraATPOptions         []atp.Option
+	config                  *schema.ScopeSchema
+	configEnvironmentPrefix string
+}
+
+func newRunOptions(options []Option) *runOptions {
+	result := &runOptions{}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// atpOptions returns the ATP server options matching the run options.
+func (r *runOptions) atpOptions() []atp.Option {
+	result := append([]atp.Option{}, r.extraATPOptions...)
+	if r.metadata != nil {
+		result = append(result, atp.WithPluginMetadata(*r.metadata))
+	}
+	return result
+}
+
+// WithMetadata sets the plugin metadata reported over ATP and by --schema. The SDK version and build information are
+// filled in automatically.
+func WithMetadata(metadata atp.PluginMetadata) Option {
+	return func(r *runOptions) {
+		withBuildInfo := metadata.WithBuildInfo()
+		r.metadata = &withBuildInfo
+	}
+}
+
+// WithATPOptions passes options to the ATP server, for example handlers for extension messages registered with
+// atp.RegisterMessageType.
+func WithATPOptions(options ...atp.Option) Option {
+	return func(r *runOptions) {
+		r.extraATPOptions = append(r.extraATPOptions, options...)
+	}
+}
@@ -0,0 +1,169 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.flow.arcalot.io/pluginsdk/atp"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// PauseController implements the predefined pause, resume and status signals for a step. Keep it in the step data,
+// call Start at the beginning of the step handler and Checkpoint between units of work, for example between the
+// requests of a load test:
+//
+//	func(ctx context.Context, data *stepData, input Input) (string, any) {
+//	    data.pause.Start(ctx)
+//	    for i := 0; i < input.Requests; i++ {
+//	        if err := data.pause.Checkpoint(ctx); err != nil {
+//	            return "error", ...
+//	        }
+//	        ...
+//	    }
+//	}
+//
+// The signal handlers are created with PauseSignals.
+type PauseController struct {
+	lock    sync.Mutex
+	paused  bool
+	resumed chan struct{}
+	message *string
+	stepID  string
+	sender  atp.MessageSender
+}
+
+// NewPauseController creates a controller of a running step.
+func NewPauseController() *PauseController {
+	return &PauseController{}
+}
+
+// Start prepares the controller for a run of the step with the context passed to the step handler. The step is
+// running until paused. If the step runs in an ATP server, the status reports are sent over the session of the
+// context.
+func (p *PauseController) Start(ctx context.Context) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.resumeLocked()
+	p.message = nil
+	p.stepID, _ = atp.StepIDFromContext(ctx)
+	p.sender, _ = atp.MessageSenderFromContext(ctx)
+}
+
+// Pause pauses the step. Checkpoint blocks until Resume is called.
+func (p *PauseController) Pause() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if !p.paused {
+		p.paused = true
+		p.resumed = make(chan struct{})
+	}
+}
+
+// Resume resumes the paused step.
+func (p *PauseController) Resume() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.resumeLocked()
+}
+
+func (p *PauseController) resumeLocked() {
+	if p.paused {
+		p.paused = false
+		close(p.resumed)
+	}
+}
+
+// Paused returns true if the step is paused.
+func (p *PauseController) Paused() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.paused
+}
+
+// Checkpoint blocks while the step is paused. It returns the error of the context if the context is done before the
+// step is resumed, for example because the step was cancelled.
+func (p *PauseController) Checkpoint(ctx context.Context) error {
+	p.lock.Lock()
+	paused := p.paused
+	resumed := p.resumed
+	p.lock.Unlock()
+	if !paused {
+		return ctx.Err()
+	}
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SetStatusMessage sets the message of the following status reports, for example the progress of the step.
+func (p *PauseController) SetStatusMessage(message string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.message = &message
+}
+
+// Status returns the current status of the step.
+func (p *PauseController) Status() StatusReport {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.statusLocked()
+}
+
+func (p *PauseController) statusLocked() StatusReport {
+	state := StepStateRunning
+	if p.paused {
+		state = StepStatePaused
+	}
+	return StatusReport{
+		State:   state,
+		Message: p.message,
+	}
+}
+
+// ReportStatus emits the current status of the step as the status report signal. It does nothing if the step does
+// not run in an ATP server.
+func (p *PauseController) ReportStatus() error {
+	p.lock.Lock()
+	sender := p.sender
+	stepID := p.stepID
+	status := p.statusLocked()
+	p.lock.Unlock()
+	if sender == nil {
+		return nil
+	}
+	data, err := StatusReportSignalSchema.DataSchema().Serialize(status)
+	if err != nil {
+		return fmt.Errorf("failed to serialize the status report of step %s (%w)", stepID, err)
+	}
+	return atp.SendSignal(sender, stepID, StatusReportSignalID, data)
+}
+
+// PauseSignals returns the pause, resume and status signal handlers calling the controller returned for the step
+// data. Add them to the signal handlers of the step and add StatusReportSignalSchema to its signal emitters.
+func PauseSignals[StepData any](controller func(StepData) *PauseController) map[string]schema.CallableSignal {
+	return map[string]schema.CallableSignal{
+		PauseSignalID: schema.NewCallableSignalFromSchema(
+			PauseSignalSchema,
+			func(_ context.Context, data StepData, _ PauseInput) {
+				controller(data).Pause()
+			},
+		),
+		ResumeSignalID: schema.NewCallableSignalFromSchema(
+			ResumeSignalSchema,
+			func(_ context.Context, data StepData, _ ResumeInput) {
+				controller(data).Resume()
+			},
+		),
+		StatusSignalID: schema.NewCallableSignalFromSchema(
+			StatusSignalSchema,
+			func(_ context.Context, data StepData, _ StatusInput) {
+				// The signal handler has no way to report errors, the status report is best effort.
+				_ = controller(data).ReportStatus()
+			},
+		),
+	}
+}
@@ -0,0 +1,103 @@
+package plugin_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/plugin"
+	"go.flow.arcalot.io/pluginsdk/plugintest"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+func TestPauseController(t *testing.T) {
+	controller := plugin.NewPauseController()
+	controller.Start(context.Background())
+	assert.NoError(t, controller.Checkpoint(context.Background()))
+
+	controller.Pause()
+	assert.Equals(t, controller.Paused(), true)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equals(t, controller.Checkpoint(ctx), context.DeadlineExceeded)
+
+	checkpointPassed := make(chan error, 1)
+	go func() {
+		checkpointPassed <- controller.Checkpoint(context.Background())
+	}()
+	controller.Resume()
+	assert.NoError(t, <-checkpointPassed)
+	assert.Equals(t, controller.Status().State, plugin.StepStateRunning)
+
+	// Without an ATP session the status report is skipped.
+	assert.NoError(t, controller.ReportStatus())
+}
+
+type pauseStepData struct {
+	pause *plugin.PauseController
+}
+
+// pauseStep reports its status when it starts and when it notices that it is paused, then finishes once resumed.
+var pauseStep = schema.NewCallableStepWithSignals[*pauseStepData, greetInput](
+	"load",
+	greetInputSchema,
+	map[string]*schema.StepOutputSchema{
+		"success": greetSuccessOutputSchema,
+	},
+	plugin.PauseSignals(func(data *pauseStepData) *plugin.PauseController {
+		return data.pause
+	}),
+	map[string]*schema.SignalSchema{
+		plugin.StatusReportSignalID: plugin.StatusReportSignalSchema,
+	},
+	nil,
+	func() *pauseStepData {
+		return &pauseStepData{plugin.NewPauseController()}
+	},
+	func(ctx context.Context, data *pauseStepData, input greetInput) (string, any) {
+		data.pause.Start(ctx)
+		data.pause.SetStatusMessage("started")
+		if err := data.pause.ReportStatus(); err != nil {
+			panic(err)
+		}
+		for {
+			if data.pause.Paused() {
+				// Report the pause so the test only resumes the step after the step noticed it.
+				if err := data.pause.ReportStatus(); err != nil {
+					panic(err)
+				}
+				if err := data.pause.Checkpoint(ctx); err != nil {
+					panic(err)
+				}
+				return "success", greetOutput{Message: "Resumed, " + input.Name + "!"}
+			}
+			if err := data.pause.Checkpoint(ctx); err != nil {
+				panic(err)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	},
+)
+
+func TestPauseSignals(t *testing.T) {
+	session := plugintest.Start(t, schema.NewCallableSchema(pauseStep), "load", greetInput{Name: "Arca Lot"})
+	assert.Equals(t, session.WaitForSignal(plugin.StatusReportSignalID), any(map[any]any{
+		"state":   "running",
+		"message": "started",
+	}))
+
+	paused := any(map[any]any{
+		"state":   "paused",
+		"message": "started",
+	})
+	session.Signal(plugin.PauseSignalID, map[string]any{})
+	assert.Equals(t, session.WaitForSignal(plugin.StatusReportSignalID), paused)
+	session.Signal(plugin.StatusSignalID, map[string]any{})
+	assert.Equals(t, session.WaitForSignal(plugin.StatusReportSignalID), paused)
+
+	session.Signal(plugin.ResumeSignalID, map[string]any{})
+	result, err := session.Wait()
+	assert.NoError(t, err)
+	assert.Equals(t, result.OutputID, "success")
+}
@@ -2,8 +2,19 @@ package plugin
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	goLog "log"
+	"net"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	log "go.arcalot.io/log/v2"
 
 	"go.flow.arcalot.io/pluginsdk/atp"
 	"go.flow.arcalot.io/pluginsdk/schema"
@@ -11,50 +22,396 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-func printUsage() {
-	fmt.Println("At least one of --atp, --schema, or --json-schema must be specified")
-	fmt.Println("--atp runs the ATP server to interface with the arcaflow engine.")
-	fmt.Println("--schema outputs the arcaflow schema of the plugin as YAML")
-	fmt.Println("--json-schema outputs the schema of a specific step's input or output" +
-		" according to standardized formats for use with other applications, like" +
-		" editors for code autocompletion.")
-}
-
 // Run is the run interface for a plugin.
 // This is not required, but is recommended for standardization
 // of the interface between plugins.
-// Allows running ATP or exporting schema.
-func Run(s *schema.CallableSchema) {
-	if len(os.Args) != 2 {
-		printUsage()
-		os.Exit(1)
-	}
-	switch os.Args[1] {
-	case "--atp":
-		ctx, cancel := context.WithCancel(context.Background())
+// Allows running ATP or exporting schema. It runs RunWithArgs with the process arguments and exits with its exit
+// code. SIGINT and SIGTERM cancel the running command.
+func Run(s *schema.CallableSchema, options ...Option) {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	exitCode := RunWithArgs(ctx, s, os.Args[1:], os.Stdin, os.Stdout, os.Stderr, options...)
+	cancel()
+	os.Exit(exitCode)
+}
+
+// RunWithArgs runs the plugin command line with the given arguments, excluding the program name, and returns the exit
+// code. Cancelling the context stops the running command. It does not exit the process, which makes it usable for
+// testing plugins end to end.
+//
+// The arguments consist of the global options followed by a command and its options, for example
+// "--log-level debug atp --debug-json". For compatibility, commands may also be prefixed with "--", like "--atp".
+func RunWithArgs(
+	ctx context.Context,
+	s *schema.CallableSchema,
+	args []string,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	options ...Option,
+) int {
+	flags := flag.NewFlagSet("plugin", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		printUsage(stderr, flags)
+	}
+	logLevel := flags.String(
+		"log-level",
+		string(log.LevelInfo),
+		"Minimum level of log messages: debug, info, warning or error.",
+	)
+	timeout := flags.Duration("timeout", 0, "Stop the command after this duration, for example 5m. 0 means no limit.")
+	runOptions := newRunOptions(options)
+	configFile := os.Getenv(ConfigFileEnvironmentVariable)
+	if runOptions.config != nil {
+		flags.StringVar(
+			&configFile,
+			"config",
+			configFile,
+			"YAML or JSON file holding the plugin configuration. Defaults to $"+ConfigFileEnvironmentVariable+".",
+		)
+	}
+	commandIndex := findCommandIndex(flags, args)
+	globalArgs := args
+	if commandIndex >= 0 {
+		globalArgs = args[:commandIndex]
+	}
+	if err := flags.Parse(globalArgs); err != nil {
+		return parseErrorExitCode(err)
+	}
+	if flags.NArg() > 0 {
+		_, _ = fmt.Fprintf(stderr, "%q is not a supported command.\n", flags.Arg(0))
+		printUsage(stderr, flags)
+		return ExitCodeUsage
+	}
+	if commandIndex < 0 {
+		printUsage(stderr, flags)
+		return ExitCodeUsage
+	}
+	level := log.Level(*logLevel)
+	if err := level.Validate(); err != nil {
+		_, _ = fmt.Fprintln(stderr, err.Error())
+		return ExitCodeUsage
+	}
+	var timeoutCtx context.Context
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		timeoutCtx, cancel = context.WithTimeout(ctx, *timeout)
 		defer cancel()
+		ctx = timeoutCtx
+	}
+
+	env := &environment{
+		schema:  s,
+		stdin:   stdin,
+		stdout:  stdout,
+		stderr:  stderr,
+		logger:  log.NewGoLogger(level, goLog.New(stderr, "", goLog.LstdFlags)),
+		options: runOptions,
+	}
+	cmd := findCommand(args[commandIndex])
+	if cmd.runsSteps && runOptions.config != nil {
+		config, err := LoadConfig(runOptions.config, runOptions.configEnvironmentPrefix, configFile)
+		if err != nil {
+			_, _ = fmt.Fprintln(stderr, err.Error())
+			return ExitCodeInvalidInput
+		}
+		ctx = schema.ContextWithPluginConfig(ctx, config)
+	}
+	exitCode := cmd.run(ctx, env, args[commandIndex+1:])
+	if timeoutCtx != nil && errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+		_, _ = fmt.Fprintf(stderr, "The %s command was stopped after the timeout of %s.\n", cmd.name, *timeout)
+		return ExitCodeTimeout
+	}
+	return exitCode
+}
 
-		if err := atp.RunATPServer(ctx, os.Stdin, os.Stdout, s); err != nil {
-			panic(err)
+// findCommandIndex returns the index of the command in the arguments, or -1 if there is none. The command is the
+// first argument that is neither a global option nor the value of one, so option values matching a command name are
+// not mistaken for the command. Commands prefixed with "--" are recognized among the global options for
+// compatibility.
+func findCommandIndex(globalFlags *flag.FlagSet, args []string) int {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if findCommand(arg) != nil {
+			return i
+		}
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			return -1
 		}
-	case "--schema":
-		serializedSchema, err := s.SelfSerialize()
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		globalFlag := globalFlags.Lookup(name)
+		if globalFlag == nil {
+			continue
+		}
+		if boolFlag, ok := globalFlag.Value.(interface{ IsBoolFlag() bool }); ok && boolFlag.IsBoolFlag() {
+			continue
+		}
+		// Skip the value of the option.
+		i++
+	}
+	return -1
+}
+
+// environment holds everything a command works with, so that commands do not use the process globals directly.
+type environment struct {
+	schema  *schema.CallableSchema
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+	logger  log.Logger
+	options *runOptions
+}
+
+// newFlagSet creates the flag set of a command, printing the help text of the command on -h.
+func (e *environment) newFlagSet(cmd string) *flag.FlagSet {
+	flags := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	flags.SetOutput(e.stderr)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(e.stderr, "Usage: plugin [global options] %s [options]\n\n", cmd)
+		_, _ = fmt.Fprintf(e.stderr, "%s\n\nOptions:\n", findCommand(cmd).description)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseErrorExitCode returns the exit code for a failed flag parse. Asking for help is not an error.
+func parseErrorExitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitCodeSuccess
+	}
+	return ExitCodeUsage
+}
+
+type command struct {
+	name        string
+	description string
+	run         func(ctx context.Context, env *environment, args []string) int
+	// runsSteps indicates that the command calls steps, so it needs the plugin configuration.
+	runsSteps bool
+}
+
+// commands lists the commands in the order they are shown in the help text. It is filled in init to avoid an
+// initialization cycle, as the commands print their help text from this list.
+var commands []command
+
+func init() {
+	commands = []command{
+		{
+			"atp",
+			"Runs the ATP server to interface with the Arcaflow engine over stdin and stdout, or over TCP." +
+				" The " + TokenEnvironmentVariable + " environment variable sets a token clients must present.",
+			runATP,
+			true,
+		},
+		{
+			"http",
+			"Serves the steps over HTTP on the given address, for example :8080." +
+				" GET /schema returns the schema, POST /steps/{step} runs a step with a JSON input," +
+				" and POST /steps/{step}/runs/{run}/signals/{signal} sends a signal to a running step.",
+			serveHTTP,
+			true,
+		},
+		{
+			"run",
+			"Executes a single step in-process and prints its output.",
+			runStep,
+			true,
+		},
+		{
+			"interactive",
+			"Prompts for the input of a step property by property, runs the step and accepts signals while it runs.",
+			runInteractive,
+			true,
+		},
+		{
+			"validate",
+			"Checks a YAML or JSON input file without executing the step, listing all problems with their path.",
+			validateInput,
+			false,
+		},
+		{
+			"docs",
+			"Outputs documentation of the steps, their inputs, outputs and signals as Markdown or plain text.",
+			writeDocs,
+			false,
+		},
+		{
+			"schema",
+			"Outputs the Arcaflow schema of the plugin as YAML, followed by the plugin metadata if set.",
+			printSchema,
+			false,
+		},
+		{
+			"json-schema",
+			"Outputs the JSON Schema (draft 2020-12) of a step's input or output for use with other applications," +
+				" like editors for code autocompletion.",
+			exportJSONSchema,
+			false,
+		},
+		{
+			"openapi",
+			"Outputs an OpenAPI 3.1 document describing the endpoints of the http command, for use with API gateways," +
+				" client generators and documentation portals.",
+			exportOpenAPI,
+			false,
+		},
+		{
+			"self-check",
+			"Verifies the schema of the plugin: references, struct mappings, property dependencies, defaults," +
+				" examples and signal IDs. Exits with an error listing every problem found.",
+			checkSchema,
+			false,
+		},
+	}
+}
+
+// commandAliases maps alternative command names to the name of the command.
+var commandAliases = map[string]string{
+	"describe": "docs",
+}
+
+// findCommand returns the command with the given name, which may be prefixed with "--", or nil.
+func findCommand(name string) *command {
+	name = strings.TrimPrefix(name, "--")
+	if alias, ok := commandAliases[name]; ok {
+		name = alias
+	}
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func printUsage(w io.Writer, globalFlags *flag.FlagSet) {
+	_, _ = fmt.Fprintln(w, "Usage: plugin [global options] <command> [options]")
+	_, _ = fmt.Fprintln(w, "\nCommands:")
+	for _, cmd := range commands {
+		_, _ = fmt.Fprintf(w, "  %s\n    \t%s\n", cmd.name, cmd.description)
+	}
+	_, _ = fmt.Fprintln(w, "\nGlobal options:")
+	globalFlags.PrintDefaults()
+	_, _ = fmt.Fprintln(w, "\nRun \"plugin <command> -h\" for the options of a command.")
+}
+
+func printSchema(_ context.Context, env *environment, args []string) int {
+	flags := env.newFlagSet("schema")
+	if err := flags.Parse(args); err != nil {
+		return parseErrorExitCode(err)
+	}
+	serializedSchema, err := env.schema.SelfSerialize()
+	if err != nil {
+		_, _ = fmt.Fprintf(env.stderr, "Error while serializing schema (%v)\n", err)
+		return ExitCodeFailure
+	}
+	asYamlBytes, err := yaml.Marshal(serializedSchema)
+	if err != nil {
+		_, _ = fmt.Fprintf(env.stderr, "Error while marshaling schema to YAML (%v)\n", err)
+		return ExitCodeFailure
+	}
+	_, _ = fmt.Fprintf(env.stdout, "serialized_schema: %v\n", string(asYamlBytes))
+	if env.options.metadata != nil {
+		metadataYAML, err := yaml.Marshal(map[string]any{"plugin_metadata": env.options.metadata})
 		if err != nil {
-			_, _ = os.Stderr.WriteString("Error while serializing schema.\n")
-			os.Exit(1) //nolint:gocritic
+			_, _ = fmt.Fprintf(env.stderr, "Error while marshaling plugin metadata to YAML (%v)\n", err)
+			return ExitCodeFailure
+		}
+		_, _ = env.stdout.Write(metadataYAML)
+	}
+	return ExitCodeSuccess
+}
+
+func runATP(ctx context.Context, env *environment, args []string) int {
+	flags := env.newFlagSet("atp")
+	debugJSON := flags.Bool("debug-json", false, "Use newline-delimited JSON instead of CBOR, for debugging by hand.")
+	listen := flags.String(
+		"listen",
+		"",
+		"Serve ATP on this TCP address instead of stdin and stdout, one step per connection.",
+	)
+	tlsCert := flags.String("tls-cert", "", "PEM file holding the server certificate for --listen.")
+	tlsKey := flags.String("tls-key", "", "PEM file holding the server key for --listen.")
+	tlsClientCA := flags.String("tls-client-ca", "", "PEM file holding the CAs client certificates must be signed by.")
+	if err := flags.Parse(args); err != nil {
+		return parseErrorExitCode(err)
+	}
+
+	// The caller's context already handles the OS signals.
+	options := append(env.options.atpOptions(), atp.WithoutOSSignalHandling())
+	if *debugJSON {
+		options = append(options, atp.WithEncoding(atp.EncodingJSONLines))
+	}
+	// The token is read from the environment so that it doesn't show up in the process list.
+	if token := os.Getenv(TokenEnvironmentVariable); token != "" {
+		options = append(options, atp.WithToken(token))
+	}
+
+	if *listen != "" {
+		if err := serveATP(ctx, env, *listen, *tlsCert, *tlsKey, *tlsClientCA, options); err != nil {
+			_, _ = fmt.Fprintln(env.stderr, err.Error())
+			return ExitCodeFailure
 		}
-		asYamlBytes, err := yaml.Marshal(serializedSchema)
+		return ExitCodeSuccess
+	}
+
+	if err := atp.RunATPServer(ctx, readCloser(env.stdin), writeCloser(env.stdout), env.schema, options...); err != nil {
+		_, _ = fmt.Fprintf(env.stderr, "ATP server failed (%v)\n", err)
+		return ExitCodeFailure
+	}
+	return ExitCodeSuccess
+}
+
+// TokenEnvironmentVariable holds the name of the environment variable the pre-shared ATP token is read from.
+const TokenEnvironmentVariable = "ARCAFLOW_ATP_TOKEN"
+
+func serveATP(
+	ctx context.Context,
+	env *environment,
+	address string,
+	tlsCert string,
+	tlsKey string,
+	tlsClientCA string,
+	options []atp.Option,
+) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s (%w)", address, err)
+	}
+	if tlsCert != "" || tlsKey != "" || tlsClientCA != "" {
+		tlsConfig, err := atp.NewServerTLSConfig(tlsCert, tlsKey, tlsClientCA)
 		if err != nil {
-			_, _ = os.Stderr.WriteString("Error while marshaling schema to YAML.\n")
-			os.Exit(1)
-		}
-		fmt.Printf("serialized_schema: %v\n", string(asYamlBytes))
-	case "--json-schema":
-		_, _ = os.Stderr.WriteString("Json schema currently isn't supported by the Go SDK plugins.\n")
-		os.Exit(1)
-	default:
-		_, _ = os.Stderr.WriteString(fmt.Sprintf("\"%s\" is not a supported input.\n", os.Args[1]))
-		printUsage()
-		os.Exit(1)
+			_ = listener.Close()
+			return err
+		}
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	return atp.ServeATP(ctx, listener, env.schema, env.logger, options...)
+}
+
+// readCloser makes the reader closable for the ATP server, which closes its input when it is done. Readers that are
+// closable already, like os.Stdin, are closed to unblock pending reads.
+func readCloser(r io.Reader) io.ReadCloser {
+	if result, ok := r.(io.ReadCloser); ok {
+		return result
+	}
+	return io.NopCloser(r)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func writeCloser(w io.Writer) io.WriteCloser {
+	if result, ok := w.(io.WriteCloser); ok {
+		return result
 	}
+	return nopWriteCloser{w}
 }
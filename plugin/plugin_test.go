@@ -0,0 +1,88 @@
+package plugin_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/plugin"
+	"gopkg.in/yaml.v3"
+)
+
+// runInProcess runs the plugin command line without starting a new process.
+func runInProcess(stdin io.Reader, args ...string) (stdout string, stderr string, exitCode int) {
+	stdoutBuffer := &bytes.Buffer{}
+	stderrBuffer := &bytes.Buffer{}
+	exitCode = plugin.RunWithArgs(context.Background(), runTestSchema, args, stdin, stdoutBuffer, stderrBuffer)
+	return stdoutBuffer.String(), stderrBuffer.String(), exitCode
+}
+
+func TestRunWithArgs_Run(t *testing.T) {
+	stdout, stderr, exitCode := runInProcess(strings.NewReader("name: Arca Lot\n"), "run", "--input", "-")
+	assert.Equals(t, exitCode, plugin.ExitCodeSuccess)
+	assert.Equals(t, stderr, "")
+	var result map[string]any
+	assert.NoError(t, yaml.Unmarshal([]byte(stdout), &result))
+	assert.Equals(t, result["output_id"], any("success"))
+}
+
+func TestRunWithArgs_LegacyCommand(t *testing.T) {
+	stdout, _, exitCode := runInProcess(nil, "--schema")
+	assert.Equals(t, exitCode, plugin.ExitCodeSuccess)
+	assert.Contains(t, stdout, "serialized_schema:")
+}
+
+func TestRunWithArgs_Help(t *testing.T) {
+	_, stderr, exitCode := runInProcess(nil, "--help")
+	assert.Equals(t, exitCode, plugin.ExitCodeSuccess)
+	assert.Contains(t, stderr, "json-schema")
+	assert.Contains(t, stderr, "-log-level")
+
+	_, stderr, exitCode = runInProcess(nil, "run", "-h")
+	assert.Equals(t, exitCode, plugin.ExitCodeSuccess)
+	assert.Contains(t, stderr, "Usage: plugin [global options] run [options]")
+	assert.Contains(t, stderr, "-input")
+}
+
+func TestRunWithArgs_Usage(t *testing.T) {
+	_, stderr, exitCode := runInProcess(nil)
+	assert.Equals(t, exitCode, plugin.ExitCodeUsage)
+	assert.Contains(t, stderr, "Commands:")
+
+	_, stderr, exitCode = runInProcess(nil, "fly")
+	assert.Equals(t, exitCode, plugin.ExitCodeUsage)
+	assert.Contains(t, stderr, `"fly" is not a supported command.`)
+
+	_, stderr, exitCode = runInProcess(nil, "--log-level", "loud", "schema")
+	assert.Equals(t, exitCode, plugin.ExitCodeUsage)
+	assert.Contains(t, stderr, "invalid log level value: loud")
+
+	// The value of an option is never taken for the command.
+	_, stderr, exitCode = runInProcess(nil, "--log-level", "atp", "schema")
+	assert.Equals(t, exitCode, plugin.ExitCodeUsage)
+	assert.Contains(t, stderr, "invalid log level value: atp")
+
+	_, stderr, exitCode = runInProcess(nil, "unknown", "atp")
+	assert.Equals(t, exitCode, plugin.ExitCodeUsage)
+	assert.Contains(t, stderr, `"unknown" is not a supported command`)
+
+	_, _, exitCode = runInProcess(nil, "schema", "--unknown")
+	assert.Equals(t, exitCode, plugin.ExitCodeUsage)
+}
+
+func TestRunWithArgs_Timeout(t *testing.T) {
+	// The ATP server waits for a client that never sends anything, so only the timeout ends it.
+	stdinReader, stdinWriter := io.Pipe()
+	defer func() {
+		_ = stdinWriter.Close()
+	}()
+	start := time.Now()
+	_, stderr, exitCode := runInProcess(stdinReader, "--timeout", "100ms", "atp")
+	assert.Equals(t, exitCode, plugin.ExitCodeTimeout)
+	assert.Contains(t, stderr, "stopped after the timeout of 100ms")
+	assert.Equals(t, time.Since(start) < 5*time.Second, true)
+}
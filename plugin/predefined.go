@@ -2,12 +2,27 @@ package plugin
 
 import "go.flow.arcalot.io/pluginsdk/schema"
 
+// Well-known IDs of the predefined signals. The engine discovers the predefined signals of a step by these IDs, so
+// steps should only use them for the signals defined here.
+const (
+	// CancellationSignalID is the ID of CancellationSignalSchema.
+	CancellationSignalID = "cancel"
+	// PauseSignalID is the ID of PauseSignalSchema.
+	PauseSignalID = "pause"
+	// ResumeSignalID is the ID of ResumeSignalSchema.
+	ResumeSignalID = "resume"
+	// StatusSignalID is the ID of StatusSignalSchema, the signal querying the status of a step.
+	StatusSignalID = "status"
+	// StatusReportSignalID is the ID of StatusReportSignalSchema, the signal a step emits in reply to a status query.
+	StatusReportSignalID = "status_report"
+)
+
 type CancelInput struct {
 	// Possibly add a time limit input
 }
 
 var CancellationSignalSchema = schema.NewSignalSchema(
-	"cancel",
+	CancellationSignalID,
 	schema.NewScopeSchema(
 		schema.NewStructMappedObjectSchema[CancelInput](
 			"cancelInput",
@@ -20,3 +35,128 @@ var CancellationSignalSchema = schema.NewSignalSchema(
 		nil,
 	),
 )
+
+// PauseInput is the data of the pause signal.
+type PauseInput struct {
+}
+
+// PauseSignalSchema is the predefined signal pausing a running step. See PauseController for handling it.
+var PauseSignalSchema = schema.NewSignalSchema(
+	PauseSignalID,
+	schema.NewScopeSchema(
+		schema.NewStructMappedObjectSchema[PauseInput](
+			"pauseInput",
+			map[string]*schema.PropertySchema{},
+		),
+	),
+	schema.NewDisplayValue(
+		schema.PointerTo("Pause"),
+		schema.PointerTo("Pauses the running step until it is resumed."),
+		nil,
+	),
+)
+
+// ResumeInput is the data of the resume signal.
+type ResumeInput struct {
+}
+
+// ResumeSignalSchema is the predefined signal resuming a paused step. See PauseController for handling it.
+var ResumeSignalSchema = schema.NewSignalSchema(
+	ResumeSignalID,
+	schema.NewScopeSchema(
+		schema.NewStructMappedObjectSchema[ResumeInput](
+			"resumeInput",
+			map[string]*schema.PropertySchema{},
+		),
+	),
+	schema.NewDisplayValue(
+		schema.PointerTo("Resume"),
+		schema.PointerTo("Resumes the paused step."),
+		nil,
+	),
+)
+
+// StatusInput is the data of the status signal.
+type StatusInput struct {
+}
+
+// StatusSignalSchema is the predefined signal querying the status of a running step. The step replies by emitting
+// StatusReportSignalSchema. See PauseController for handling it.
+var StatusSignalSchema = schema.NewSignalSchema(
+	StatusSignalID,
+	schema.NewScopeSchema(
+		schema.NewStructMappedObjectSchema[StatusInput](
+			"statusInput",
+			map[string]*schema.PropertySchema{},
+		),
+	),
+	schema.NewDisplayValue(
+		schema.PointerTo("Status"),
+		schema.PointerTo("Queries the status of the running step, which replies with the status_report signal."),
+		nil,
+	),
+)
+
+// StepState is the state of a running step reported in StatusReport.
+type StepState string
+
+const (
+	// StepStateRunning means the step is running.
+	StepStateRunning StepState = "running"
+	// StepStatePaused means the step is paused and waits for the resume signal.
+	StepStatePaused StepState = "paused"
+)
+
+// StatusReport is the data of the status report signal.
+type StatusReport struct {
+	State   StepState `json:"state"`
+	Message *string   `json:"message,omitempty"`
+}
+
+// StatusReportSignalSchema is the predefined signal a step emits in reply to StatusSignalSchema.
+var StatusReportSignalSchema = schema.NewSignalSchema(
+	StatusReportSignalID,
+	schema.NewScopeSchema(
+		schema.NewStructMappedObjectSchema[StatusReport](
+			"statusReport",
+			map[string]*schema.PropertySchema{
+				"state": schema.NewPropertySchema(
+					schema.NewStringEnumSchema(map[string]*schema.DisplayValue{
+						string(StepStateRunning): schema.NewDisplayValue(schema.PointerTo("Running"), nil, nil),
+						string(StepStatePaused):  schema.NewDisplayValue(schema.PointerTo("Paused"), nil, nil),
+					}),
+					schema.NewDisplayValue(
+						schema.PointerTo("State"),
+						schema.PointerTo("State of the step."),
+						nil,
+					),
+					true,
+					nil,
+					nil,
+					nil,
+					nil,
+					nil,
+				),
+				"message": schema.NewPropertySchema(
+					schema.NewStringSchema(nil, nil, nil),
+					schema.NewDisplayValue(
+						schema.PointerTo("Message"),
+						schema.PointerTo("Progress or other details reported by the step."),
+						nil,
+					),
+					false,
+					nil,
+					nil,
+					nil,
+					nil,
+					nil,
+				),
+			},
+		),
+	),
+	schema.NewDisplayValue(
+		schema.PointerTo("Status report"),
+		schema.PointerTo("Reports the status of the running step in reply to the status signal."),
+		nil,
+	),
+)
@@ -0,0 +1,198 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.flow.arcalot.io/pluginsdk/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// Exit codes of the commands that run a step locally.
+const (
+	// ExitCodeSuccess indicates that the step finished with a non-error output.
+	ExitCodeSuccess = 0
+	// ExitCodeErrorOutput indicates that the step finished with an output marked as error.
+	ExitCodeErrorOutput = 1
+	// ExitCodeUsage indicates that the command line was invalid.
+	ExitCodeUsage = 2
+	// ExitCodeInvalidInput indicates that the input did not match the step input schema.
+	ExitCodeInvalidInput = 3
+	// ExitCodeFailure indicates that the step could not be executed, or that its output could not be written.
+	ExitCodeFailure = 4
+	// ExitCodeTimeout indicates that the command was stopped because the duration set with --timeout expired.
+	ExitCodeTimeout = 5
+)
+
+// runStep implements the run command. It executes a single step in-process with the input read from a YAML or JSON
+// file and writes the output ID and the serialized output data.
+func runStep(ctx context.Context, env *environment, args []string) int {
+	s, stdout, stderr := env.schema, env.stdout, env.stderr
+	flags := env.newFlagSet("run")
+	stepID := flags.String("step", "", "ID of the step to run. May be omitted if the plugin has only one step.")
+	inputFile := flags.String("input", "", "YAML or JSON file holding the step input, - for stdin.")
+	outputFile := flags.String("output", "", "File to write the output to instead of stdout.")
+	format := flags.String("format", "", "Output format, yaml or json. Defaults to the output file extension, or yaml.")
+	if err := flags.Parse(args); err != nil {
+		return parseErrorExitCode(err)
+	}
+	if *inputFile == "" {
+		_, _ = fmt.Fprintln(stderr, "--input is required.")
+		return ExitCodeUsage
+	}
+	id, err := selectStep(s, *stepID)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err.Error())
+		return ExitCodeUsage
+	}
+	outputFormat, err := selectFormat(*format, *outputFile)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err.Error())
+		return ExitCodeUsage
+	}
+	input, err := readInputFile(*inputFile, env.stdin)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err.Error())
+		return ExitCodeUsage
+	}
+
+	outputID, outputData, err := s.CallStep(schema.ContextWithExecution(ctx), id, input)
+	if err != nil {
+		var invalidInput schema.InvalidInputError
+		if errors.As(err, &invalidInput) {
+			_, _ = fmt.Fprintln(stderr, err.Error())
+			return ExitCodeInvalidInput
+		}
+		_, _ = fmt.Fprintf(stderr, "Step %s failed: %v\n", id, err)
+		return ExitCodeFailure
+	}
+
+	result, err := marshalOutput(outputFormat, map[string]any{
+		"output_id":   outputID,
+		"output_data": outputData,
+	})
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Failed to marshal the output of step %s (%v)\n", id, err)
+		return ExitCodeFailure
+	}
+	if *outputFile != "" {
+		if err := os.WriteFile(*outputFile, result, 0600); err != nil {
+			_, _ = fmt.Fprintf(stderr, "Failed to write output file %s (%v)\n", *outputFile, err)
+			return ExitCodeFailure
+		}
+		_, _ = fmt.Fprintf(stdout, "Step %s finished with output %s, written to %s.\n", id, outputID, *outputFile)
+	} else {
+		_, _ = stdout.Write(result)
+	}
+	if s.StepsValue[id].Outputs()[outputID].Error() {
+		return ExitCodeErrorOutput
+	}
+	return ExitCodeSuccess
+}
+
+// selectStep returns the step ID to use. The ID may be omitted for plugins with a single step.
+func selectStep(s *schema.CallableSchema, stepID string) (string, error) {
+	if stepID != "" {
+		if _, ok := s.StepsValue[stepID]; !ok {
+			return "", fmt.Errorf("no such step: %s (available steps: %s)", stepID, strings.Join(stepIDs(s), ", "))
+		}
+		return stepID, nil
+	}
+	if len(s.StepsValue) != 1 {
+		return "", fmt.Errorf("--step is required (available steps: %s)", strings.Join(stepIDs(s), ", "))
+	}
+	for id := range s.StepsValue {
+		stepID = id
+	}
+	return stepID, nil
+}
+
+func stepIDs(s *schema.CallableSchema) []string {
+	result := make([]string, 0, len(s.StepsValue))
+	for id := range s.StepsValue {
+		result = append(result, id)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func selectFormat(format string, outputFile string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(outputFile)) {
+		case ".json":
+			return "json", nil
+		default:
+			return "yaml", nil
+		}
+	}
+	switch format {
+	case "yaml", "json":
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported output format: %s (expected yaml or json)", format)
+	}
+}
+
+// readInputFile reads a YAML or JSON file. JSON is read as YAML, since YAML is a superset of JSON.
+func readInputFile(file string, stdin io.Reader) (any, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(file) //nolint:gosec
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input file %s (%w)", file, err)
+	}
+	var input any
+	if err := yaml.Unmarshal(data, &input); err != nil {
+		return nil, fmt.Errorf("failed to parse input file %s (%w)", file, err)
+	}
+	return input, nil
+}
+
+func marshalOutput(format string, data any) ([]byte, error) {
+	if format == "json" {
+		result, err := json.MarshalIndent(jsonCompatible(data), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(result, '\n'), nil
+	}
+	return yaml.Marshal(data)
+}
+
+// jsonCompatible converts the map[any]any values produced by serializing map schemas to maps with string keys, as
+// the JSON encoder does not accept interface keys.
+func jsonCompatible(data any) any {
+	switch value := data.(type) {
+	case map[any]any:
+		result := make(map[string]any, len(value))
+		for key, item := range value {
+			result[fmt.Sprintf("%v", key)] = jsonCompatible(item)
+		}
+		return result
+	case map[string]any:
+		result := make(map[string]any, len(value))
+		for key, item := range value {
+			result[key] = jsonCompatible(item)
+		}
+		return result
+	case []any:
+		result := make([]any, len(value))
+		for i, item := range value {
+			result[i] = jsonCompatible(item)
+		}
+		return result
+	default:
+		return data
+	}
+}
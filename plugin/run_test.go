@@ -0,0 +1,171 @@
+package plugin_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/plugin"
+	"go.flow.arcalot.io/pluginsdk/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// runAsPluginEnv makes the test binary act as a plugin, so the command line handling can be tested end to end.
+const runAsPluginEnv = "PLUGIN_TEST_RUN_AS_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runAsPluginEnv) != "" {
+		plugin.Run(runTestSchema)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+var greetInputSchema = schema.NewScopeSchema(
+	schema.NewStructMappedObjectSchema[greetInput](
+		"Input",
+		map[string]*schema.PropertySchema{
+			"name": schema.NewPropertySchema(
+				schema.NewStringSchema(nil, nil, nil),
+				nil,
+				true,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+		},
+	),
+)
+
+var greetSuccessOutputSchema = schema.NewStepOutputSchema(
+	schema.NewScopeSchema(
+		schema.NewStructMappedObjectSchema[greetOutput](
+			"Output",
+			map[string]*schema.PropertySchema{
+				"message": schema.NewPropertySchema(
+					schema.NewStringSchema(nil, nil, nil),
+					nil,
+					true,
+					nil,
+					nil,
+					nil,
+					nil,
+					nil,
+				),
+			},
+		),
+	),
+	nil,
+	false,
+)
+
+var runTestSchema = schema.NewCallableSchema(
+	schema.NewCallableStep[greetInput](
+		"greet",
+		greetInputSchema,
+		map[string]*schema.StepOutputSchema{
+			"success": greetSuccessOutputSchema,
+			"error": schema.NewStepOutputSchema(
+				schema.NewScopeSchema(
+					schema.NewObjectSchema(
+						"Error",
+						map[string]*schema.PropertySchema{
+							"reason": schema.NewPropertySchema(
+								schema.NewStringSchema(nil, nil, nil),
+								nil,
+								true,
+								nil,
+								nil,
+								nil,
+								nil,
+								nil,
+							),
+						},
+					),
+				),
+				nil,
+				true,
+			),
+		},
+		nil,
+		func(_ context.Context, input greetInput) (string, any) {
+			if input.Name == "nobody" {
+				return "error", map[string]any{"reason": "nobody to greet"}
+			}
+			return "success", greetOutput{Message: "Hello, " + input.Name + "!"}
+		},
+	),
+)
+
+// runPlugin runs the test binary as a plugin with the given arguments.
+func runPlugin(t *testing.T, stdin string, args ...string) (stdout string, stderr string, exitCode int) {
+	cmd := exec.Command(os.Args[0], args...) //nolint:gosec
+	cmd.Env = append(os.Environ(), runAsPluginEnv+"=1")
+	cmd.Stdin = strings.NewReader(stdin)
+	stdoutBuffer := &bytes.Buffer{}
+	stderrBuffer := &bytes.Buffer{}
+	cmd.Stdout = stdoutBuffer
+	cmd.Stderr = stderrBuffer
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	} else {
+		assert.NoError(t, err)
+	}
+	return stdoutBuffer.String(), stderrBuffer.String(), exitCode
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	file := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(file, []byte(content), 0600))
+	return file
+}
+
+func TestRun_RunStep(t *testing.T) {
+	inputFile := writeFile(t, "input.yaml", "name: Arca Lot\n")
+	stdout, stderr, exitCode := runPlugin(t, "", "--run", "--step", "greet", "--input", inputFile)
+	assert.Equals(t, exitCode, plugin.ExitCodeSuccess)
+	assert.Equals(t, stderr, "")
+	var result map[string]any
+	assert.NoError(t, yaml.Unmarshal([]byte(stdout), &result))
+	assert.Equals(t, result["output_id"], any("success"))
+	assert.Equals(t, result["output_data"].(map[string]any)["message"], any("Hello, Arca Lot!"))
+}
+
+func TestRun_RunStep_JSONOutputFile(t *testing.T) {
+	// The step may be omitted as there is only one, and the input is read from stdin.
+	outputFile := filepath.Join(t.TempDir(), "output.json")
+	_, _, exitCode := runPlugin(t, `{"name": "nobody"}`, "--run", "--input", "-", "--output", outputFile)
+	assert.Equals(t, exitCode, plugin.ExitCodeErrorOutput)
+	output, err := os.ReadFile(outputFile) //nolint:gosec
+	assert.NoError(t, err)
+	assert.Equals(t, string(output), `{
+  "output_data": {
+    "reason": "nobody to greet"
+  },
+  "output_id": "error"
+}
+`)
+}
+
+func TestRun_RunStep_Errors(t *testing.T) {
+	_, stderr, exitCode := runPlugin(t, "{}", "--run", "--input", "-")
+	assert.Equals(t, exitCode, plugin.ExitCodeInvalidInput)
+	assert.Contains(t, stderr, "name")
+
+	_, stderr, exitCode = runPlugin(t, "", "--run", "--step", "wave", "--input", "-")
+	assert.Equals(t, exitCode, plugin.ExitCodeUsage)
+	assert.Contains(t, stderr, "no such step: wave")
+
+	_, _, exitCode = runPlugin(t, "", "--run")
+	assert.Equals(t, exitCode, plugin.ExitCodeUsage)
+}
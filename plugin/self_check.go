@@ -0,0 +1,33 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// checkSchema implements the self-check command. It verifies the schema of the plugin and the plugin configuration
+// schema, if any, with schema.CheckCallableSchema and reports every problem found.
+func checkSchema(_ context.Context, env *environment, args []string) int {
+	flags := env.newFlagSet("self-check")
+	if err := flags.Parse(args); err != nil {
+		return parseErrorExitCode(err)
+	}
+	problems := schema.CheckCallableSchema(env.schema)
+	if env.options.config != nil {
+		for _, problem := range schema.CheckScope(env.options.config) {
+			problem.Path = append([]string{"config"}, problem.Path...)
+			problems = append(problems, problem)
+		}
+	}
+	if len(problems) > 0 {
+		_, _ = fmt.Fprintf(env.stderr, "Found %d problem(s) in the schema of the plugin:\n", len(problems))
+		for _, problem := range problems {
+			_, _ = fmt.Fprintf(env.stderr, "  %s\n", problem.Error())
+		}
+		return ExitCodeFailure
+	}
+	_, _ = fmt.Fprintln(env.stdout, "No problems found in the schema of the plugin.")
+	return ExitCodeSuccess
+}
@@ -0,0 +1,46 @@
+package plugin_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/plugin"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+func TestRunWithArgs_SelfCheck(t *testing.T) {
+	stdout, stderr, exitCode := runInProcess(nil, "--self-check")
+	assert.Equals(t, exitCode, plugin.ExitCodeSuccess)
+	assert.Equals(t, stderr, "")
+	assert.Contains(t, stdout, "No problems found")
+
+	brokenConfig := schema.NewScopeSchema(
+		schema.NewObjectSchema("Config", map[string]*schema.PropertySchema{
+			"retries": schema.NewPropertySchema(
+				schema.NewIntSchema(nil, nil, nil),
+				nil,
+				false,
+				nil,
+				nil,
+				nil,
+				schema.PointerTo(`"many"`),
+				nil,
+			),
+		}),
+	)
+	stderrBuffer := &bytes.Buffer{}
+	exitCode = plugin.RunWithArgs(
+		context.Background(),
+		runTestSchema,
+		[]string{"self-check"},
+		nil,
+		&bytes.Buffer{},
+		stderrBuffer,
+		plugin.WithConfig(brokenConfig, "TEST_"),
+	)
+	assert.Equals(t, exitCode, plugin.ExitCodeFailure)
+	assert.Contains(t, stderrBuffer.String(), "Found 1 problem(s)")
+	assert.Contains(t, stderrBuffer.String(), "config -> object Config -> retries: the default value \"many\" is invalid")
+}
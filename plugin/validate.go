@@ -0,0 +1,45 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// validateInput implements the validate command. It checks an input file against the input schema of a step and
+// reports every problem found, without executing the step.
+func validateInput(_ context.Context, env *environment, args []string) int {
+	s, stdout, stderr := env.schema, env.stdout, env.stderr
+	flags := env.newFlagSet("validate")
+	stepID := flags.String("step", "", "ID of the step. May be omitted if the plugin has only one step.")
+	if err := flags.Parse(args); err != nil {
+		return parseErrorExitCode(err)
+	}
+	if flags.NArg() != 1 {
+		_, _ = fmt.Fprintln(stderr, "Exactly one input file is required, - reads from stdin.")
+		return ExitCodeUsage
+	}
+	inputFile := flags.Arg(0)
+	id, err := selectStep(s, *stepID)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err.Error())
+		return ExitCodeUsage
+	}
+	input, err := readInputFile(inputFile, env.stdin)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err.Error())
+		return ExitCodeUsage
+	}
+
+	errs := schema.CollectConstraintErrors(s.StepsValue[id].Input(), input)
+	if len(errs) > 0 {
+		_, _ = fmt.Fprintf(stderr, "%s is not a valid input for step %s:\n", inputFile, id)
+		for _, err := range errs {
+			_, _ = fmt.Fprintf(stderr, "  %s\n", err.Error())
+		}
+		return ExitCodeInvalidInput
+	}
+	_, _ = fmt.Fprintf(stdout, "%s is a valid input for step %s.\n", inputFile, id)
+	return ExitCodeSuccess
+}
@@ -0,0 +1,38 @@
+package plugin_test
+
+import (
+	"strings"
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/plugin"
+)
+
+func TestRun_Validate(t *testing.T) {
+	inputFile := writeFile(t, "input.yaml", "name: Arca Lot\n")
+	stdout, stderr, exitCode := runPlugin(t, "", "--validate", "--step", "greet", inputFile)
+	assert.Equals(t, exitCode, plugin.ExitCodeSuccess)
+	assert.Equals(t, stderr, "")
+	assert.Contains(t, stdout, "is a valid input for step greet")
+}
+
+func TestRun_Validate_Invalid(t *testing.T) {
+	// Every problem is reported, not only the first one.
+	_, stderr, exitCode := runPlugin(t, "nam: Arca\nage: 3\n", "--validate", "-")
+	assert.Equals(t, exitCode, plugin.ExitCodeInvalidInput)
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	assert.Equals(t, len(lines), 4)
+	assert.Contains(t, lines[0], "is not a valid input for step greet")
+	assert.Contains(t, stderr, "Invalid parameter 'age'")
+	assert.Contains(t, stderr, "Invalid parameter 'nam'")
+	assert.Contains(t, stderr, "Validation failed for 'name': This field is required")
+}
+
+func TestRun_Validate_Usage(t *testing.T) {
+	_, _, exitCode := runPlugin(t, "", "--validate")
+	assert.Equals(t, exitCode, plugin.ExitCodeUsage)
+
+	_, stderr, exitCode := runPlugin(t, "", "--validate", "--step", "wave", "-")
+	assert.Equals(t, exitCode, plugin.ExitCodeUsage)
+	assert.Contains(t, stderr, "no such step: wave")
+}
@@ -0,0 +1,63 @@
+package plugintest
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UpdateGoldenEnvironmentVariable holds the name of the environment variable that makes the golden file assertions
+// write the actual data to the golden files instead of comparing it, for example:
+//
+//	PLUGINTEST_UPDATE_GOLDEN=1 go test ./...
+const UpdateGoldenEnvironmentVariable = "PLUGINTEST_UPDATE_GOLDEN"
+
+// AssertGolden compares the data, marshaled as YAML, with the contents of the golden file at the given path, which is
+// usually in the testdata directory of the package. It fails the test if they differ or the golden file does not
+// exist. Set UpdateGoldenEnvironmentVariable to create or update the golden files, and review the changes before
+// committing them.
+func AssertGolden(t TestingT, path string, data any) {
+	t.Helper()
+	actual, err := yaml.Marshal(data)
+	if err != nil {
+		t.Fatalf("failed to marshal data for golden file %s (%v)", path, err)
+	}
+	if os.Getenv(UpdateGoldenEnvironmentVariable) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create the directory of golden file %s (%v)", path, err)
+		}
+		if err := os.WriteFile(path, actual, 0644); err != nil { //nolint:gosec
+			t.Fatalf("failed to update golden file %s (%v)", path, err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("golden file %s does not exist, set %s=1 to create it", path, UpdateGoldenEnvironmentVariable)
+		}
+		t.Fatalf("failed to read golden file %s (%v)", path, err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Fatalf(
+			"data does not match golden file %s, set %s=1 to update it\n--- expected\n%s--- actual\n%s",
+			path,
+			UpdateGoldenEnvironmentVariable,
+			expected,
+			actual,
+		)
+	}
+}
+
+// AssertGoldenOutput compares the output ID and the serialized output data of the result with the golden file at the
+// given path. See AssertGolden for details.
+func AssertGoldenOutput(t TestingT, path string, result *Result) {
+	t.Helper()
+	AssertGolden(t, path, map[string]any{
+		"output_id":   result.OutputID,
+		"output_data": result.OutputData,
+	})
+}
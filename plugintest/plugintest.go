@@ -0,0 +1,378 @@
+// Package plugintest runs plugin steps from Go tests over an in-memory ATP session. The input, the output and the
+// signals pass through the same serialization and protocol handling as when the Arcaflow engine runs the plugin, which
+// catches the problems that calling the step handlers directly does not.
+package plugintest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	log "go.arcalot.io/log/v2"
+	"go.flow.arcalot.io/pluginsdk/atp"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// DefaultTimeout is the time a step may run before the test fails, unless configured otherwise with WithTimeout.
+const DefaultTimeout = 30 * time.Second
+
+// TestingT is the part of *testing.T the helpers use. It allows using the helpers with other test frameworks.
+type TestingT interface {
+	Helper()
+	Fatalf(format string, args ...any)
+}
+
+// Option configures a step execution.
+type Option func(*options)
+
+type options struct {
+	timeout      time.Duration
+	logger       log.Logger
+	atpOptions   []atp.Option
+	pluginConfig any
+}
+
+// WithTimeout sets the time the step may run, as well as the time WaitForSignal waits for a signal. Defaults to
+// DefaultTimeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithLogger sets the logger receiving the ATP client logs, for example log.NewTestLogger(t). If not set, the logs
+// are discarded.
+func WithLogger(logger log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithATPOptions passes options to both sides of the ATP session, for example to test with a different encoding.
+func WithATPOptions(atpOptions ...atp.Option) Option {
+	return func(o *options) {
+		o.atpOptions = append(o.atpOptions, atpOptions...)
+	}
+}
+
+// WithPluginConfig passes the unserialized plugin configuration to the step, like plugin.WithConfig does when the
+// plugin runs. Each session is its own execution, so the step initializer runs with the configuration for every
+// session.
+func WithPluginConfig(config any) Option {
+	return func(o *options) {
+		o.pluginConfig = config
+	}
+}
+
+// Result is the outcome of a step execution.
+type Result struct {
+	// OutputID is the ID of the output the step returned.
+	OutputID string
+	// OutputData is the serialized output data as it was received over ATP.
+	OutputData any
+	// Output is the output data unserialized with the output schema, for example the struct of a struct-mapped
+	// output.
+	Output any
+	// EmittedSignals lists the signals the step emitted, in the order they were received.
+	EmittedSignals []schema.Input
+	// Metrics holds the metrics of the execution as reported by the ATP client.
+	Metrics atp.ExecutionMetrics
+}
+
+// Run runs a step over an in-memory ATP session and returns its result. It fails the test if the step cannot be
+// executed, for example because of invalid input. See Start for the accepted input.
+func Run(t TestingT, s *schema.CallableSchema, stepID string, input any, opts ...Option) *Result {
+	t.Helper()
+	result, err := Start(t, s, stepID, input, opts...).Wait()
+	if err != nil {
+		t.Fatalf("step %s failed (%v)", stepID, err)
+	}
+	return result
+}
+
+// Output returns the unserialized output of the result as T. It fails the test if the step returned a different
+// output ID or the output is not of type T.
+func Output[T any](t TestingT, result *Result, outputID string) T {
+	t.Helper()
+	if result.OutputID != outputID {
+		t.Fatalf("expected output %s, got %s with data %v", outputID, result.OutputID, result.OutputData)
+	}
+	output, ok := result.Output.(T)
+	if !ok {
+		var expected T
+		t.Fatalf("expected output %s to be of type %T, got %T", outputID, expected, result.Output)
+	}
+	return output
+}
+
+// Session is a step running over an in-memory ATP session. Tests use it to send signals to the step and to wait for
+// the signals it emits while it runs.
+type Session struct {
+	t        TestingT
+	step     schema.CallableStep
+	options  *options
+	client   atp.InProcessClient
+	ctx      context.Context
+	signals  chan schema.Input
+	done     chan struct{}
+	result   atp.ExecutionResult
+	err      error
+	lock     sync.Mutex
+	emitted  []schema.Input
+	notify   chan struct{}
+	consumed map[string]int
+
+	// started is closed when the server calls the step. signalLock guards finished, which is set when the step
+	// returns. The ATP client closes the signals channel once the step is done, so Signal only sends while the step
+	// runs and holds signalLock until the client has received the signal.
+	started    chan struct{}
+	signalLock sync.Mutex
+	finished   bool
+}
+
+// Start starts a step over an in-memory ATP session. The input may either be serialized data, like a
+// map[string]any, or a value of the type the input schema unserializes to, which is serialized with the input schema
+// first. Call Wait to get the result.
+func Start(t TestingT, s *schema.CallableSchema, stepID string, input any, opts ...Option) *Session {
+	t.Helper()
+	o := &options{
+		timeout: DefaultTimeout,
+		logger:  log.NewLogger(log.LevelDebug, log.NewNOOPLogger()),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	step, ok := s.StepsValue[stepID]
+	if !ok {
+		t.Fatalf("the schema has no step %s", stepID)
+	}
+	inputData, err := serializeIfTyped(step.Input(), input)
+	if err != nil {
+		t.Fatalf("failed to serialize the input of step %s (%v)", stepID, err)
+	}
+
+	ctx := context.Background()
+	if o.pluginConfig != nil {
+		ctx = schema.ContextWithPluginConfig(ctx, o.pluginConfig)
+	}
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	session := &Session{
+		t:        t,
+		step:     step,
+		options:  o,
+		ctx:      ctx,
+		signals:  make(chan schema.Input),
+		done:     make(chan struct{}),
+		started:  make(chan struct{}),
+		notify:   make(chan struct{}),
+		consumed: map[string]int{},
+	}
+	client, err := atp.NewInProcessClient(ctx, session.trackedSchema(s, stepID), o.logger, o.atpOptions...)
+	if err != nil {
+		cancel()
+		t.Fatalf("failed to start the in-memory ATP session (%v)", err)
+	}
+	session.client = client
+	if _, err := session.client.ReadSchema(); err != nil {
+		cancel()
+		_ = session.client.Close()
+		t.Fatalf("failed to read the schema over ATP (%v)", err)
+	}
+
+	emittedSignals := make(chan schema.Input)
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for signal := range emittedSignals {
+			session.lock.Lock()
+			session.emitted = append(session.emitted, signal)
+			close(session.notify)
+			session.notify = make(chan struct{})
+			session.lock.Unlock()
+		}
+	}()
+	go func() {
+		// Closing the client unblocks the execution if the step does not stop on the cancelled context.
+		select {
+		case <-ctx.Done():
+			_ = session.client.Close()
+		case <-session.done:
+		}
+	}()
+	go func() {
+		defer cancel()
+		session.result, session.err = session.client.ExecuteWithMetrics(
+			schema.Input{ID: stepID, InputData: inputData},
+			session.signals,
+			emittedSignals,
+		)
+		if session.err != nil && ctx.Err() != nil {
+			session.err = fmt.Errorf("step %s did not finish within %s (%w)", stepID, o.timeout, session.err)
+		}
+		_ = session.client.Close()
+		// The client does not emit signals after it returned, so all of them are recorded once done is closed.
+		close(emittedSignals)
+		<-collected
+		close(session.done)
+	}()
+	return session
+}
+
+// Signal sends a signal to the running step. The data may either be serialized data or a value of the type the
+// signal schema unserializes to. It fails the test if the step has no such signal handler or is not running, because
+// it did not start or has already finished.
+func (s *Session) Signal(signalID string, data any) {
+	s.t.Helper()
+	signal, ok := s.step.SignalHandlers()[signalID]
+	if !ok {
+		s.t.Fatalf("step %s has no signal handler %s", s.step.ID(), signalID)
+	}
+	signalData, err := serializeIfTyped(signal.DataSchema(), data)
+	if err != nil {
+		s.t.Fatalf("failed to serialize the data of signal %s (%v)", signalID, err)
+	}
+	if err := s.sendSignal(schema.Input{ID: signalID, InputData: signalData}); err != nil {
+		s.t.Fatalf("cannot send signal %s, %v", signalID, err)
+	}
+}
+
+// sendSignal sends the signal to the ATP client. It fails if the step did not start, for example because of invalid
+// input, has already finished, or the session timed out.
+func (s *Session) sendSignal(signal schema.Input) error {
+	select {
+	case <-s.started:
+	case <-s.done:
+		select {
+		case <-s.started:
+		default:
+			return fmt.Errorf("step %s did not start", s.step.ID())
+		}
+	}
+	s.signalLock.Lock()
+	defer s.signalLock.Unlock()
+	if s.finished {
+		return fmt.Errorf("step %s has already finished", s.step.ID())
+	}
+	select {
+	case s.signals <- signal:
+		return nil
+	case <-s.ctx.Done():
+		return fmt.Errorf("step %s did not finish within %s", s.step.ID(), s.options.timeout)
+	}
+}
+
+// trackedSchema returns a copy of the schema in which the step reports to the session when it starts and finishes.
+func (s *Session) trackedSchema(callableSchema *schema.CallableSchema, stepID string) *schema.CallableSchema {
+	steps := make([]schema.CallableStep, 0, len(callableSchema.StepsValue))
+	for id, step := range callableSchema.StepsValue {
+		if id == stepID {
+			step = trackedStep{step, s}
+		}
+		steps = append(steps, step)
+	}
+	return schema.NewCallableSchema(steps...)
+}
+
+// trackedStep is a step that reports to the session when the server calls it and when it returns.
+type trackedStep struct {
+	schema.CallableStep
+	session *Session
+}
+
+func (t trackedStep) Call(ctx context.Context, data any) (string, any, error) {
+	close(t.session.started)
+	defer func() {
+		t.session.signalLock.Lock()
+		t.session.finished = true
+		t.session.signalLock.Unlock()
+	}()
+	return t.CallableStep.Call(ctx, data)
+}
+
+// WaitForSignal waits until the step emits the signal with the given ID and returns its serialized data. Each call
+// returns the next signal with the ID, so calling it twice waits for the second one. It fails the test if the step
+// finishes or the timeout passes without emitting the signal.
+func (s *Session) WaitForSignal(signalID string) any {
+	s.t.Helper()
+	timeout := time.After(s.options.timeout)
+	for {
+		s.lock.Lock()
+		data, found := s.nextSignal(signalID)
+		notify := s.notify
+		s.lock.Unlock()
+		if found {
+			return data
+		}
+
+		select {
+		case <-notify:
+		case <-s.done:
+			s.lock.Lock()
+			data, found = s.nextSignal(signalID)
+			s.lock.Unlock()
+			if !found {
+				s.t.Fatalf("step %s finished without emitting signal %s", s.step.ID(), signalID)
+			}
+			return data
+		case <-timeout:
+			s.t.Fatalf("timed out waiting for step %s to emit signal %s", s.step.ID(), signalID)
+			return nil
+		}
+	}
+}
+
+// nextSignal returns the data of the first emitted signal with the ID that WaitForSignal has not returned yet. The
+// caller must hold the lock.
+func (s *Session) nextSignal(signalID string) (any, bool) {
+	seen := 0
+	for _, signal := range s.emitted {
+		if signal.ID != signalID {
+			continue
+		}
+		if seen == s.consumed[signalID] {
+			s.consumed[signalID]++
+			return signal.InputData, true
+		}
+		seen++
+	}
+	return nil, false
+}
+
+// Wait waits for the step to finish and returns its result. It returns an error if the step could not be executed,
+// for example because of invalid input or because it timed out, or if the output does not match its schema.
+func (s *Session) Wait() (*Result, error) {
+	<-s.done
+	if s.err != nil {
+		return nil, s.err
+	}
+	output, ok := s.step.Outputs()[s.result.OutputID]
+	if !ok {
+		return nil, fmt.Errorf("step %s returned the undeclared output %s", s.step.ID(), s.result.OutputID)
+	}
+	unserializedOutput, err := output.Schema().Unserialize(s.result.OutputData)
+	if err != nil {
+		return nil, fmt.Errorf("the data of output %s does not match its schema (%w)", s.result.OutputID, err)
+	}
+	s.lock.Lock()
+	emitted := append([]schema.Input(nil), s.emitted...)
+	s.lock.Unlock()
+	return &Result{
+		OutputID:       s.result.OutputID,
+		OutputData:     s.result.OutputData,
+		Output:         unserializedOutput,
+		EmittedSignals: emitted,
+		Metrics:        s.result.Metrics,
+	}, nil
+}
+
+// serializeIfTyped serializes the data with the scope if it is of the type the scope unserializes to. Other data is
+// assumed to be serialized already.
+func serializeIfTyped(scope schema.Scope, data any) (any, error) {
+	reflectedType := scope.ReflectedType()
+	if data == nil || reflectedType == nil || reflect.TypeOf(data) != reflectedType {
+		return data, nil
+	}
+	return scope.Serialize(data)
+}
@@ -0,0 +1,314 @@
+package plugintest_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.arcalot.io/log/v2"
+	"go.flow.arcalot.io/pluginsdk/atp"
+	"go.flow.arcalot.io/pluginsdk/plugintest"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+type greetInput struct {
+	Name string `json:"name"`
+}
+
+type greetOutput struct {
+	Message string `json:"message"`
+}
+
+var greetInputSchema = schema.NewScopeSchema(
+	schema.NewStructMappedObjectSchema[greetInput](
+		"Input",
+		map[string]*schema.PropertySchema{
+			"name": schema.NewPropertySchema(
+				schema.NewStringSchema(schema.IntPointer(1), nil, nil),
+				nil,
+				true,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+		},
+	),
+)
+
+var greetOutputSchema = schema.NewStepOutputSchema(
+	schema.NewScopeSchema(
+		schema.NewStructMappedObjectSchema[greetOutput](
+			"Output",
+			map[string]*schema.PropertySchema{
+				"message": schema.NewPropertySchema(
+					schema.NewStringSchema(nil, nil, nil),
+					nil,
+					true,
+					nil,
+					nil,
+					nil,
+					nil,
+					nil,
+				),
+			},
+		),
+	),
+	nil,
+	false,
+)
+
+var greetStep = schema.NewCallableStep[greetInput](
+	"greet",
+	greetInputSchema,
+	map[string]*schema.StepOutputSchema{
+		"success": greetOutputSchema,
+	},
+	nil,
+	func(_ context.Context, input greetInput) (string, any) {
+		return "success", greetOutput{Message: "Hello, " + input.Name + "!"}
+	},
+)
+
+type waitStepData struct {
+	greetings chan string
+}
+
+// waitStep emits the ready signal, then waits for the greeting from the greet signal.
+var waitStep = schema.NewCallableStepWithSignals[*waitStepData, greetInput](
+	"wait",
+	greetInputSchema,
+	map[string]*schema.StepOutputSchema{
+		"success": greetOutputSchema,
+	},
+	map[string]schema.CallableSignal{
+		"greet": schema.NewCallableSignal(
+			"greet",
+			greetInputSchema,
+			nil,
+			func(_ context.Context, data *waitStepData, input greetInput) {
+				data.greetings <- input.Name
+			},
+		),
+	},
+	map[string]*schema.SignalSchema{
+		"ready": schema.NewSignalSchema("ready", greetInputSchema, nil),
+	},
+	nil,
+	func() *waitStepData {
+		return &waitStepData{greetings: make(chan string, 1)}
+	},
+	func(ctx context.Context, data *waitStepData, input greetInput) (string, any) {
+		sender, ok := atp.MessageSenderFromContext(ctx)
+		if !ok {
+			panic("the step does not run over ATP")
+		}
+		stepID, _ := atp.StepIDFromContext(ctx)
+		if err := atp.SendSignal(sender, stepID, "ready", map[string]any{"name": input.Name}); err != nil {
+			panic(err)
+		}
+		select {
+		case greeting := <-data.greetings:
+			return "success", greetOutput{Message: greeting + ", " + input.Name + "!"}
+		case <-ctx.Done():
+			return "success", greetOutput{Message: "Cancelled"}
+		}
+	},
+)
+
+var testSchema = schema.NewCallableSchema(greetStep, waitStep)
+
+// fakeT records the failure of a helper instead of failing the test.
+type fakeT struct {
+	failure string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Fatalf(format string, args ...any) {
+	f.failure = fmt.Sprintf(format, args...)
+	panic(f)
+}
+
+// expectFailure runs the function, which must fail f, and returns the failure message.
+func expectFailure(t *testing.T, f *fakeT, run func()) string {
+	func() {
+		defer func() {
+			if r := recover(); r != nil && r != f {
+				panic(r)
+			}
+		}()
+		run()
+	}()
+	if f.failure == "" {
+		t.Fatalf("expected a failure")
+	}
+	return f.failure
+}
+
+func TestRun(t *testing.T) {
+	result := plugintest.Run(
+		t,
+		testSchema,
+		"greet",
+		greetInput{Name: "Arca Lot"},
+		plugintest.WithLogger(log.NewTestLogger(t)),
+	)
+	assert.Equals(t, result.OutputID, "success")
+	assert.Equals(t, result.OutputData.(map[any]any)["message"], any("Hello, Arca Lot!"))
+	output := plugintest.Output[greetOutput](t, result, "success")
+	assert.Equals(t, output.Message, "Hello, Arca Lot!")
+	assert.Equals(t, len(result.EmittedSignals), 0)
+}
+
+func TestRun_SerializedInput(t *testing.T) {
+	result := plugintest.Run(
+		t,
+		testSchema,
+		"greet",
+		map[string]any{"name": "Arca Lot"},
+		plugintest.WithATPOptions(atp.WithEncoding(atp.EncodingJSONLines)),
+	)
+	assert.Equals(t, plugintest.Output[greetOutput](t, result, "success").Message, "Hello, Arca Lot!")
+}
+
+func TestRun_InvalidInput(t *testing.T) {
+	_, err := plugintest.Start(t, testSchema, "greet", map[string]any{"name": ""}).Wait()
+	assert.Error(t, err)
+
+	f := &fakeT{}
+	failure := expectFailure(t, f, func() {
+		plugintest.Run(f, testSchema, "greet", map[string]any{})
+	})
+	assert.Equals(t, strings.HasPrefix(failure, "step greet failed"), true)
+
+	f = &fakeT{}
+	failure = expectFailure(t, f, func() {
+		plugintest.Run(f, testSchema, "nonexistent", map[string]any{})
+	})
+	assert.Equals(t, failure, "the schema has no step nonexistent")
+}
+
+func TestOutput_WrongOutput(t *testing.T) {
+	result := plugintest.Run(t, testSchema, "greet", greetInput{Name: "Arca Lot"})
+	f := &fakeT{}
+	failure := expectFailure(t, f, func() {
+		plugintest.Output[greetOutput](f, result, "error")
+	})
+	assert.Equals(t, strings.HasPrefix(failure, "expected output error, got success"), true)
+
+	f = &fakeT{}
+	failure = expectFailure(t, f, func() {
+		plugintest.Output[*greetOutput](f, result, "success")
+	})
+	assert.Equals(t, failure, "expected output success to be of type *plugintest_test.greetOutput, got "+
+		"plugintest_test.greetOutput")
+}
+
+func TestSession_Signals(t *testing.T) {
+	f := &fakeT{}
+	session := plugintest.Start(f, testSchema, "wait", greetInput{Name: "Arca Lot"})
+	ready := session.WaitForSignal("ready")
+	assert.Equals(t, ready.(map[any]any)["name"], any("Arca Lot"))
+	session.Signal("greet", greetInput{Name: "Hello"})
+	result, err := session.Wait()
+	assert.NoError(t, err)
+	assert.Equals(t, plugintest.Output[greetOutput](t, result, "success").Message, "Hello, Arca Lot!")
+	assert.Equals(t, len(result.EmittedSignals), 1)
+	assert.Equals(t, result.EmittedSignals[0].ID, "ready")
+
+	failure := expectFailure(t, f, func() {
+		session.WaitForSignal("ready")
+	})
+	assert.Equals(t, failure, "step wait finished without emitting signal ready")
+}
+
+func TestSession_SignalAfterFinish(t *testing.T) {
+	f := &fakeT{}
+	session := plugintest.Start(f, testSchema, "greet", greetInput{Name: "Arca Lot"})
+	_, err := session.Wait()
+	assert.NoError(t, err)
+	failure := expectFailure(t, f, func() {
+		session.Signal("greet", map[string]any{"name": "Hello"})
+	})
+	assert.Equals(t, failure, "step greet has no signal handler greet")
+
+	f = &fakeT{}
+	session = plugintest.Start(f, testSchema, "wait", greetInput{Name: "Arca Lot"})
+	session.WaitForSignal("ready")
+	session.Signal("greet", map[string]any{"name": "Hello"})
+	_, err = session.Wait()
+	assert.NoError(t, err)
+	failure = expectFailure(t, f, func() {
+		session.Signal("greet", map[string]any{"name": "Hello"})
+	})
+	assert.Equals(t, failure, "cannot send signal greet, step wait has already finished")
+
+	// The step is never called with invalid input, so it cannot receive signals either.
+	f = &fakeT{}
+	session = plugintest.Start(f, testSchema, "wait", map[string]any{})
+	failure = expectFailure(t, f, func() {
+		session.Signal("greet", map[string]any{"name": "Hello"})
+	})
+	assert.Equals(t, failure, "cannot send signal greet, step wait did not start")
+	_, err = session.Wait()
+	assert.Error(t, err)
+}
+
+func TestAssertGoldenOutput(t *testing.T) {
+	result := plugintest.Run(t, testSchema, "greet", greetInput{Name: "Arca Lot"})
+	plugintest.AssertGoldenOutput(t, filepath.Join("testdata", "greet.yaml"), result)
+
+	if os.Getenv(plugintest.UpdateGoldenEnvironmentVariable) != "" {
+		return
+	}
+	result = plugintest.Run(t, testSchema, "greet", greetInput{Name: "nobody"})
+	f := &fakeT{}
+	failure := expectFailure(t, f, func() {
+		plugintest.AssertGoldenOutput(f, filepath.Join("testdata", "greet.yaml"), result)
+	})
+	assert.Equals(t, strings.Contains(failure, "message: Hello, nobody!"), true)
+}
+
+func TestAssertGolden_Update(t *testing.T) {
+	t.Setenv(plugintest.UpdateGoldenEnvironmentVariable, "")
+	path := filepath.Join(t.TempDir(), "testdata", "data.yaml")
+	f := &fakeT{}
+	failure := expectFailure(t, f, func() {
+		plugintest.AssertGolden(f, path, map[string]any{"a": 1})
+	})
+	assert.Equals(t, strings.HasPrefix(failure, "golden file "+path+" does not exist"), true)
+
+	t.Setenv(plugintest.UpdateGoldenEnvironmentVariable, "1")
+	plugintest.AssertGolden(t, path, map[string]any{"a": 1})
+	data, err := os.ReadFile(path) //nolint:gosec
+	assert.NoError(t, err)
+	assert.Equals(t, string(data), "a: 1\n")
+
+	t.Setenv(plugintest.UpdateGoldenEnvironmentVariable, "")
+	plugintest.AssertGolden(t, path, map[string]any{"a": 1})
+}
+
+func TestRun_PluginConfig(t *testing.T) {
+	s := schema.NewCallableSchema(
+		schema.NewCallableStep[greetInput](
+			"greet",
+			greetInputSchema,
+			map[string]*schema.StepOutputSchema{
+				"success": greetOutputSchema,
+			},
+			nil,
+			func(ctx context.Context, input greetInput) (string, any) {
+				greeting, _ := schema.PluginConfigFromContext(ctx).(string)
+				return "success", greetOutput{Message: greeting + ", " + input.Name + "!"}
+			},
+		),
+	)
+	result := plugintest.Run(t, s, "greet", greetInput{Name: "Arca Lot"}, plugintest.WithPluginConfig("Hi"))
+	assert.Equals(t, plugintest.Output[greetOutput](t, result, "success").Message, "Hi, Arca Lot!")
+}
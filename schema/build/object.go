@@ -0,0 +1,100 @@
+package build
+
+import (
+	"fmt"
+
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// ObjectBuilder builds an object. It can be used as the type of a property to embed the object without a reference.
+type ObjectBuilder struct {
+	id          string
+	propertyIDs []string
+	properties  map[string]*PropertyBuilder
+	construct   func(id string, properties map[string]*schema.PropertySchema) *schema.ObjectSchema
+}
+
+// Object starts building an object that unserializes to a map[string]any.
+func Object(id string) *ObjectBuilder {
+	return &ObjectBuilder{
+		id:         id,
+		properties: map[string]*PropertyBuilder{},
+		construct:  schema.NewObjectSchema,
+	}
+}
+
+// StructObject starts building an object that unserializes to the struct T, see schema.NewStructMappedObjectSchema.
+func StructObject[T any](id string) *ObjectBuilder {
+	return &ObjectBuilder{
+		id:         id,
+		properties: map[string]*PropertyBuilder{},
+		construct:  schema.NewStructMappedObjectSchema[T],
+	}
+}
+
+// ID returns the ID of the object.
+func (o *ObjectBuilder) ID() string {
+	return o.id
+}
+
+// Property adds a property to the object. Adding the same property ID twice panics.
+func (o *ObjectBuilder) Property(id string, property *PropertyBuilder) *ObjectBuilder {
+	if _, ok := o.properties[id]; ok {
+		panic(schema.BadArgumentError{
+			Message: fmt.Sprintf("duplicate property %s on object %s", id, o.id),
+		})
+	}
+	o.propertyIDs = append(o.propertyIDs, id)
+	o.properties[id] = property
+	return o
+}
+
+// Build builds the object.
+func (o *ObjectBuilder) Build() *schema.ObjectSchema {
+	properties := make(map[string]*schema.PropertySchema, len(o.properties))
+	for _, id := range o.propertyIDs {
+		properties[id] = o.properties[id].Build()
+	}
+	return o.construct(o.id, properties)
+}
+
+func (o *ObjectBuilder) BuildType() schema.Type {
+	return o.Build()
+}
+
+// ScopeBuilder builds a scope: the root object and the objects the references within it can point to.
+type ScopeBuilder struct {
+	root    *ObjectBuilder
+	objects []*ObjectBuilder
+}
+
+// Scope starts building a scope with the given root object and further objects.
+func Scope(root *ObjectBuilder, objects ...*ObjectBuilder) *ScopeBuilder {
+	return &ScopeBuilder{root: root, objects: objects}
+}
+
+// Object adds an object to the scope.
+func (s *ScopeBuilder) Object(object *ObjectBuilder) *ScopeBuilder {
+	s.objects = append(s.objects, object)
+	return s
+}
+
+// Build builds the scope and resolves the references in it.
+func (s *ScopeBuilder) Build() *schema.ScopeSchema {
+	ids := map[string]bool{s.root.ID(): true}
+	objects := make([]*schema.ObjectSchema, len(s.objects))
+	for i, object := range s.objects {
+		if ids[object.ID()] {
+			panic(schema.BadArgumentError{
+				Message: fmt.Sprintf("duplicate object %s in scope", object.ID()),
+			})
+		}
+		ids[object.ID()] = true
+		objects[i] = object.Build()
+	}
+	return schema.NewScopeSchema(s.root.Build(), objects...)
+}
+
+func (s *ScopeBuilder) BuildType() schema.Type {
+	return s.Build()
+}
@@ -0,0 +1,162 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// PropertyBuilder builds an object property. Unlike schema.NewPropertySchema, the default value and the examples are
+// given as Go values and are encoded to JSON by the builder.
+type PropertyBuilder struct {
+	t              TypeBuilder
+	display        displayBuilder
+	required       bool
+	requiredIf     []string
+	requiredIfNot  []string
+	conflicts      []string
+	defaultValue   *string
+	examples       []string
+	secret         bool
+	disabledReason *string
+	emptyIsDefault bool
+}
+
+// Property starts building a property of the given type.
+func Property(t TypeBuilder) *PropertyBuilder {
+	return &PropertyBuilder{t: t}
+}
+
+// Required marks the property as required.
+func (p *PropertyBuilder) Required() *PropertyBuilder {
+	p.required = true
+	return p
+}
+
+// RequiredIf makes the property required if any of the given properties is set.
+func (p *PropertyBuilder) RequiredIf(propertyIDs ...string) *PropertyBuilder {
+	p.requiredIf = append(p.requiredIf, propertyIDs...)
+	return p
+}
+
+// RequiredIfNot makes the property required if any of the given properties is not set.
+func (p *PropertyBuilder) RequiredIfNot(propertyIDs ...string) *PropertyBuilder {
+	p.requiredIfNot = append(p.requiredIfNot, propertyIDs...)
+	return p
+}
+
+// Conflicts forbids setting the property together with any of the given properties.
+func (p *PropertyBuilder) Conflicts(propertyIDs ...string) *PropertyBuilder {
+	p.conflicts = append(p.conflicts, propertyIDs...)
+	return p
+}
+
+// Name sets the human-readable name of the property.
+func (p *PropertyBuilder) Name(name string) *PropertyBuilder {
+	p.display.name = &name
+	return p
+}
+
+// Description sets the description of the property.
+func (p *PropertyBuilder) Description(description string) *PropertyBuilder {
+	p.display.description = &description
+	return p
+}
+
+// Icon sets the SVG icon of the property.
+func (p *PropertyBuilder) Icon(icon string) *PropertyBuilder {
+	p.display.icon = &icon
+	return p
+}
+
+// Default sets the default value of the property in its serialized form, for example "x" or 5.
+func (p *PropertyBuilder) Default(value any) *PropertyBuilder {
+	encodedValue := encodeJSON("default value", value)
+	p.defaultValue = &encodedValue
+	return p
+}
+
+// Examples adds example values of the property in their serialized form.
+func (p *PropertyBuilder) Examples(values ...any) *PropertyBuilder {
+	for _, value := range values {
+		p.examples = append(p.examples, encodeJSON("example", value))
+	}
+	return p
+}
+
+// Secret marks the property as holding a credential.
+func (p *PropertyBuilder) Secret() *PropertyBuilder {
+	p.secret = true
+	return p
+}
+
+// Disable disables the property for the given reason.
+func (p *PropertyBuilder) Disable(reason string) *PropertyBuilder {
+	p.disabledReason = &reason
+	return p
+}
+
+// TreatEmptyAsDefault treats an empty value as the default value, see schema.PropertySchema.TreatEmptyAsDefaultValue.
+func (p *PropertyBuilder) TreatEmptyAsDefault() *PropertyBuilder {
+	p.emptyIsDefault = true
+	return p
+}
+
+// Build builds the property.
+func (p *PropertyBuilder) Build() *schema.PropertySchema {
+	property := schema.NewPropertySchema(
+		p.t.BuildType(),
+		p.display.buildDisplay(),
+		p.required,
+		p.requiredIf,
+		p.requiredIfNot,
+		p.conflicts,
+		p.defaultValue,
+		p.examples,
+	)
+	if p.secret {
+		property.MarkSecret()
+	}
+	if p.disabledReason != nil {
+		property.Disable(*p.disabledReason)
+	}
+	if p.emptyIsDefault {
+		property.TreatEmptyAsDefaultValue()
+	}
+	return property
+}
+
+func encodeJSON(what string, value any) string {
+	encodedValue, err := json.Marshal(value)
+	if err != nil {
+		panic(schema.BadArgumentError{
+			Message: fmt.Sprintf("cannot encode the %s %v as JSON", what, value),
+			Cause:   err,
+		})
+	}
+	return string(encodedValue)
+}
+
+// displayBuilder collects the display values shared by the builders.
+type displayBuilder struct {
+	name        *string
+	description *string
+	icon        *string
+}
+
+// build returns the display value, or nil if nothing is set.
+func (d displayBuilder) build() *schema.DisplayValue {
+	if d.name == nil && d.description == nil && d.icon == nil {
+		return nil
+	}
+	return schema.NewDisplayValue(d.name, d.description, d.icon)
+}
+
+// buildDisplay returns the display value as a schema.Display, or an untyped nil if nothing is set.
+func (d displayBuilder) buildDisplay() schema.Display {
+	if display := d.build(); display != nil {
+		return display
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package build_test
+
+import (
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/schema"
+	"go.flow.arcalot.io/pluginsdk/schema/build"
+)
+
+func TestProperty(t *testing.T) {
+	property := build.Property(build.String().MinLen(1).MaxLen(10).Pattern("^[a-z]+$")).
+		Required().
+		RequiredIf("a").
+		RequiredIfNot("b").
+		Conflicts("c", "d").
+		Name("Name").
+		Description("Name to greet").
+		Default("x").
+		Examples("arca", "lot").
+		Build()
+
+	assert.Equals(t, property.Required(), true)
+	assert.Equals(t, property.RequiredIf(), []string{"a"})
+	assert.Equals(t, property.RequiredIfNot(), []string{"b"})
+	assert.Equals(t, property.Conflicts(), []string{"c", "d"})
+	assert.Equals(t, *property.Display().Name(), "Name")
+	assert.Equals(t, *property.Display().Description(), "Name to greet")
+	assert.Equals(t, *property.Default(), `"x"`)
+	assert.Equals(t, property.Examples(), []string{`"arca"`, `"lot"`})
+	stringType := property.Type().(*schema.StringSchema)
+	assert.Equals(t, *stringType.Min(), int64(1))
+	assert.Equals(t, *stringType.Max(), int64(10))
+	assert.Equals(t, stringType.Pattern().String(), "^[a-z]+$")
+}
+
+func TestProperty_Minimal(t *testing.T) {
+	property := build.Property(build.Int().Min(1).Units(schema.UnitBytes)).Default(5).Secret().Build()
+	assert.Equals(t, property.Required(), false)
+	assert.Equals(t, property.Display() == nil, true)
+	assert.Equals(t, *property.Default(), "5")
+	assert.Equals(t, property.Secret(), true)
+	assert.Equals(t, property.Type().(*schema.IntSchema).Units(), schema.UnitBytes)
+}
+
+func TestProperty_Invalid(t *testing.T) {
+	assert.Panics(t, func() {
+		build.String().Pattern("[")
+	})
+	assert.Panics(t, func() {
+		build.Property(build.Any()).Default(func() {})
+	})
+	assert.Panics(t, func() {
+		build.Map(build.Bool(), build.String()).Build()
+	})
+}
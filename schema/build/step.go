@@ -0,0 +1,170 @@
+package build
+
+import (
+	"context"
+	"fmt"
+
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// OutputBuilder builds a step output.
+type OutputBuilder struct {
+	scope   *schema.ScopeSchema
+	display displayBuilder
+	error   bool
+}
+
+// Output starts building a step output with data described by the scope, for example built with Scope.
+func Output(scope *schema.ScopeSchema) *OutputBuilder {
+	return &OutputBuilder{scope: scope}
+}
+
+// Error marks the output as an error output.
+func (o *OutputBuilder) Error() *OutputBuilder {
+	o.error = true
+	return o
+}
+
+// Name sets the human-readable name of the output.
+func (o *OutputBuilder) Name(name string) *OutputBuilder {
+	o.display.name = &name
+	return o
+}
+
+// Description sets the description of the output.
+func (o *OutputBuilder) Description(description string) *OutputBuilder {
+	o.display.description = &description
+	return o
+}
+
+// Build builds the output.
+func (o *OutputBuilder) Build() *schema.StepOutputSchema {
+	return schema.NewStepOutputSchema(o.scope, o.display.build(), o.error)
+}
+
+// StepBuilder builds a callable step.
+type StepBuilder[StepData any, StepInputType any] struct {
+	id             string
+	input          *schema.ScopeSchema
+	outputs        map[string]*OutputBuilder
+	signalHandlers map[string]schema.CallableSignal
+	signalEmitters map[string]*schema.SignalSchema
+	display        displayBuilder
+	initializer    func() StepData
+	handler        func(context.Context, StepData, StepInputType) (string, any)
+}
+
+// Step starts building a step calling the handler with the unserialized input.
+func Step[StepInputType any](
+	id string,
+	handler func(context.Context, StepInputType) (string, any),
+) *StepBuilder[any, StepInputType] {
+	return StepWithData[any, StepInputType](
+		id,
+		nil,
+		func(ctx context.Context, _ any, input StepInputType) (string, any) {
+			return handler(ctx, input)
+		},
+	)
+}
+
+// StepWithData starts building a step with step data shared with its signal handlers, see
+// schema.NewCallableStepWithSignals.
+func StepWithData[StepData any, StepInputType any](
+	id string,
+	initializer func() StepData,
+	handler func(context.Context, StepData, StepInputType) (string, any),
+) *StepBuilder[StepData, StepInputType] {
+	return &StepBuilder[StepData, StepInputType]{
+		id:             id,
+		outputs:        map[string]*OutputBuilder{},
+		signalHandlers: map[string]schema.CallableSignal{},
+		signalEmitters: map[string]*schema.SignalSchema{},
+		initializer:    initializer,
+		handler:        handler,
+	}
+}
+
+// Input sets the input of the step.
+func (s *StepBuilder[StepData, StepInputType]) Input(input *schema.ScopeSchema) *StepBuilder[StepData, StepInputType] {
+	s.input = input
+	return s
+}
+
+// Output adds an output with the given ID to the step.
+func (s *StepBuilder[StepData, StepInputType]) Output(
+	id string,
+	output *OutputBuilder,
+) *StepBuilder[StepData, StepInputType] {
+	if _, ok := s.outputs[id]; ok {
+		panic(schema.BadArgumentError{
+			Message: fmt.Sprintf("duplicate output %s on step %s", id, s.id),
+		})
+	}
+	s.outputs[id] = output
+	return s
+}
+
+// SignalHandler adds a signal the step accepts. It is registered under its own ID.
+func (s *StepBuilder[StepData, StepInputType]) SignalHandler(
+	signal schema.CallableSignal,
+) *StepBuilder[StepData, StepInputType] {
+	s.signalHandlers[signal.ID()] = signal
+	return s
+}
+
+// SignalEmitter adds a signal the step emits. It is registered under its own ID.
+func (s *StepBuilder[StepData, StepInputType]) SignalEmitter(
+	signal *schema.SignalSchema,
+) *StepBuilder[StepData, StepInputType] {
+	s.signalEmitters[signal.ID()] = signal
+	return s
+}
+
+// Name sets the human-readable name of the step.
+func (s *StepBuilder[StepData, StepInputType]) Name(name string) *StepBuilder[StepData, StepInputType] {
+	s.display.name = &name
+	return s
+}
+
+// Description sets the description of the step.
+func (s *StepBuilder[StepData, StepInputType]) Description(
+	description string,
+) *StepBuilder[StepData, StepInputType] {
+	s.display.description = &description
+	return s
+}
+
+// Icon sets the SVG icon of the step.
+func (s *StepBuilder[StepData, StepInputType]) Icon(icon string) *StepBuilder[StepData, StepInputType] {
+	s.display.icon = &icon
+	return s
+}
+
+// Build builds the step. It panics if the input or the outputs are missing.
+func (s *StepBuilder[StepData, StepInputType]) Build() schema.CallableStep {
+	if s.input == nil {
+		panic(schema.BadArgumentError{
+			Message: fmt.Sprintf("step %s has no input", s.id),
+		})
+	}
+	if len(s.outputs) == 0 {
+		panic(schema.BadArgumentError{
+			Message: fmt.Sprintf("step %s has no outputs", s.id),
+		})
+	}
+	outputs := make(map[string]*schema.StepOutputSchema, len(s.outputs))
+	for id, output := range s.outputs {
+		outputs[id] = output.Build()
+	}
+	return schema.NewCallableStepWithSignals[StepData, StepInputType](
+		s.id,
+		s.input,
+		outputs,
+		s.signalHandlers,
+		s.signalEmitters,
+		s.display.buildDisplay(),
+		s.initializer,
+		s.handler,
+	)
+}
@@ -0,0 +1,87 @@
+package build_test
+
+import (
+	"context"
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/schema"
+	"go.flow.arcalot.io/pluginsdk/schema/build"
+)
+
+type greetInput struct {
+	Name    string   `json:"name"`
+	Address *address `json:"address"`
+}
+
+type address struct {
+	City string `json:"city"`
+}
+
+type greetOutput struct {
+	Message string `json:"message"`
+}
+
+var greetStep = build.Step("greet", func(_ context.Context, input greetInput) (string, any) {
+	return "success", greetOutput{Message: "Hello, " + input.Name + " from " + input.Address.City + "!"}
+}).
+	Name("Greet").
+	Input(
+		build.Scope(
+			build.StructObject[greetInput]("Input").
+				Property("name", build.Property(build.String().MinLen(1)).Required()).
+				Property("address", build.Property(build.Ref("Address")).Required()),
+			build.StructObject[address]("Address").
+				Property("city", build.Property(build.String()).Default("Arcaville")),
+		).Build(),
+	).
+	Output(
+		"success",
+		build.Output(
+			build.Scope(
+				build.StructObject[greetOutput]("Output").
+					Property("message", build.Property(build.String()).Required()),
+			).Build(),
+		).Name("Success"),
+	).
+	Output(
+		"error",
+		build.Output(
+			build.Scope(
+				build.Object("Error").Property("reason", build.Property(build.String()).Required()),
+			).Build(),
+		).Error(),
+	).
+	Build()
+
+func TestStep(t *testing.T) {
+	assert.Equals(t, *greetStep.Display().Name(), "Greet")
+	assert.Equals(t, greetStep.Outputs()["error"].Error(), true)
+	assert.Equals(t, *greetStep.Outputs()["success"].Display().Name(), "Success")
+	assert.Equals(t, len(schema.CheckCallableSchema(schema.NewCallableSchema(greetStep))), 0)
+
+	outputID, outputData, err := schema.NewCallableSchema(greetStep).CallStep(
+		context.Background(),
+		"greet",
+		map[string]any{"name": "Arca Lot", "address": map[string]any{}},
+	)
+	assert.NoError(t, err)
+	assert.Equals(t, outputID, "success")
+	assert.Equals(t, outputData, any(map[string]any{"message": "Hello, Arca Lot from Arcaville!"}))
+}
+
+func TestStep_Invalid(t *testing.T) {
+	assert.Panics(t, func() {
+		build.Step("noinput", func(_ context.Context, _ greetInput) (string, any) {
+			return "success", nil
+		}).Build()
+	})
+	assert.Panics(t, func() {
+		build.Object("Input").
+			Property("name", build.Property(build.String())).
+			Property("name", build.Property(build.String()))
+	})
+	assert.Panics(t, func() {
+		build.Scope(build.Object("Input"), build.Object("Input")).Build()
+	})
+}
@@ -0,0 +1,261 @@
+// Package build provides chainable builders for schemas as an alternative to the positional constructors of the
+// schema package, for example:
+//
+//	input := build.Scope(
+//	    build.StructObject[Input]("Input").
+//	        Property("name", build.Property(build.String().MinLen(1)).Required().Name("Name")).
+//	        Property("count", build.Property(build.Int().Min(1)).Default(1)),
+//	).Build()
+//
+// The builders panic with a schema.BadArgumentError on invalid arguments, like the constructors of the schema
+// package.
+package build
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+// TypeBuilder builds the type of a property, list item or map value.
+type TypeBuilder interface {
+	// BuildType builds the schema type.
+	BuildType() schema.Type
+}
+
+// Type wraps a type created without a builder.
+func Type(t schema.Type) TypeBuilder {
+	return typeBuilder{t}
+}
+
+type typeBuilder struct {
+	t schema.Type
+}
+
+func (t typeBuilder) BuildType() schema.Type {
+	return t.t
+}
+
+// StringBuilder builds a string type.
+type StringBuilder struct {
+	minLength *int64
+	maxLength *int64
+	pattern   *regexp.Regexp
+}
+
+// String starts building a string type.
+func String() *StringBuilder {
+	return &StringBuilder{}
+}
+
+// MinLen sets the minimum length of the string.
+func (s *StringBuilder) MinLen(minLength int64) *StringBuilder {
+	s.minLength = &minLength
+	return s
+}
+
+// MaxLen sets the maximum length of the string.
+func (s *StringBuilder) MaxLen(maxLength int64) *StringBuilder {
+	s.maxLength = &maxLength
+	return s
+}
+
+// Pattern sets the regular expression the string must match.
+func (s *StringBuilder) Pattern(pattern string) *StringBuilder {
+	compiledPattern, err := regexp.Compile(pattern)
+	if err != nil {
+		panic(schema.BadArgumentError{
+			Message: fmt.Sprintf("invalid pattern %q", pattern),
+			Cause:   err,
+		})
+	}
+	s.pattern = compiledPattern
+	return s
+}
+
+// Build builds the string type.
+func (s *StringBuilder) Build() *schema.StringSchema {
+	return schema.NewStringSchema(s.minLength, s.maxLength, s.pattern)
+}
+
+func (s *StringBuilder) BuildType() schema.Type {
+	return s.Build()
+}
+
+// IntBuilder builds an integer type.
+type IntBuilder struct {
+	min   *int64
+	max   *int64
+	units *schema.UnitsDefinition
+}
+
+// Int starts building an integer type.
+func Int() *IntBuilder {
+	return &IntBuilder{}
+}
+
+// Min sets the minimum value.
+func (i *IntBuilder) Min(min int64) *IntBuilder {
+	i.min = &min
+	return i
+}
+
+// Max sets the maximum value.
+func (i *IntBuilder) Max(max int64) *IntBuilder {
+	i.max = &max
+	return i
+}
+
+// Units sets the units of the value, for example schema.UnitBytes.
+func (i *IntBuilder) Units(units *schema.UnitsDefinition) *IntBuilder {
+	i.units = units
+	return i
+}
+
+// Build builds the integer type.
+func (i *IntBuilder) Build() *schema.IntSchema {
+	return schema.NewIntSchema(i.min, i.max, i.units)
+}
+
+func (i *IntBuilder) BuildType() schema.Type {
+	return i.Build()
+}
+
+// FloatBuilder builds a floating point type.
+type FloatBuilder struct {
+	min   *float64
+	max   *float64
+	units *schema.UnitsDefinition
+}
+
+// Float starts building a floating point type.
+func Float() *FloatBuilder {
+	return &FloatBuilder{}
+}
+
+// Min sets the minimum value.
+func (f *FloatBuilder) Min(min float64) *FloatBuilder {
+	f.min = &min
+	return f
+}
+
+// Max sets the maximum value.
+func (f *FloatBuilder) Max(max float64) *FloatBuilder {
+	f.max = &max
+	return f
+}
+
+// Units sets the units of the value, for example schema.UnitPercentage.
+func (f *FloatBuilder) Units(units *schema.UnitsDefinition) *FloatBuilder {
+	f.units = units
+	return f
+}
+
+// Build builds the floating point type.
+func (f *FloatBuilder) Build() *schema.FloatSchema {
+	return schema.NewFloatSchema(f.min, f.max, f.units)
+}
+
+func (f *FloatBuilder) BuildType() schema.Type {
+	return f.Build()
+}
+
+// Bool returns the builder of a boolean type.
+func Bool() TypeBuilder {
+	return Type(schema.NewBoolSchema())
+}
+
+// Any returns the builder of a type accepting any value.
+func Any() TypeBuilder {
+	return Type(schema.NewAnySchema())
+}
+
+// Ref returns the builder of a reference to the object with the given ID in the same scope.
+func Ref(objectID string) TypeBuilder {
+	return Type(schema.NewRefSchema(objectID, nil))
+}
+
+// Enum returns the builder of a string type accepting only the given values.
+func Enum(values ...string) TypeBuilder {
+	validValues := make(map[string]*schema.DisplayValue, len(values))
+	for _, value := range values {
+		validValues[value] = nil
+	}
+	return Type(schema.NewStringEnumSchema(validValues))
+}
+
+// ListBuilder builds a list type.
+type ListBuilder struct {
+	items    TypeBuilder
+	minItems *int64
+	maxItems *int64
+}
+
+// List starts building a list with items of the given type.
+func List(items TypeBuilder) *ListBuilder {
+	return &ListBuilder{items: items}
+}
+
+// MinItems sets the minimum number of items.
+func (l *ListBuilder) MinItems(minItems int64) *ListBuilder {
+	l.minItems = &minItems
+	return l
+}
+
+// MaxItems sets the maximum number of items.
+func (l *ListBuilder) MaxItems(maxItems int64) *ListBuilder {
+	l.maxItems = &maxItems
+	return l
+}
+
+// Build builds the list type.
+func (l *ListBuilder) Build() *schema.ListSchema {
+	return schema.NewListSchema(l.items.BuildType(), l.minItems, l.maxItems)
+}
+
+func (l *ListBuilder) BuildType() schema.Type {
+	return l.Build()
+}
+
+// MapBuilder builds a map type.
+type MapBuilder struct {
+	keys     TypeBuilder
+	values   TypeBuilder
+	minItems *int64
+	maxItems *int64
+}
+
+// Map starts building a map with keys and values of the given types. The keys must be strings or integers.
+func Map(keys TypeBuilder, values TypeBuilder) *MapBuilder {
+	return &MapBuilder{keys: keys, values: values}
+}
+
+// MinItems sets the minimum number of entries.
+func (m *MapBuilder) MinItems(minItems int64) *MapBuilder {
+	m.minItems = &minItems
+	return m
+}
+
+// MaxItems sets the maximum number of entries.
+func (m *MapBuilder) MaxItems(maxItems int64) *MapBuilder {
+	m.maxItems = &maxItems
+	return m
+}
+
+// Build builds the map type.
+func (m *MapBuilder) Build() *schema.MapSchema[schema.Type, schema.Type] {
+	keys := m.keys.BuildType()
+	switch keys.TypeID() {
+	case schema.TypeIDString, schema.TypeIDInt, schema.TypeIDStringEnum, schema.TypeIDIntEnum:
+	default:
+		panic(schema.BadArgumentError{
+			Message: fmt.Sprintf("Invalid type ID for map: %s, expected one of: string, int", keys.TypeID()),
+		})
+	}
+	return schema.NewMapSchema(keys, m.values.BuildType(), m.minItems, m.maxItems)
+}
+
+func (m *MapBuilder) BuildType() schema.Type {
+	return m.Build()
+}
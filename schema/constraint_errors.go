@@ -0,0 +1,190 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// CollectConstraintErrors unserializes the data like Unserialize, but instead of stopping at the first problem it
+// returns every problem found as a ConstraintError holding the path to the problematic value. It returns nil if the
+// data can be unserialized.
+func CollectConstraintErrors(t Type, data any) []*ConstraintError {
+	if errs := collectNestedConstraintErrors(t, data); len(errs) > 0 {
+		return errs
+	}
+	// Anything the collectors don't check, such as the data type of the value itself, is reported by Unserialize.
+	if _, err := t.Unserialize(data); err != nil {
+		var constraintErr *ConstraintError
+		if errors.As(err, &constraintErr) {
+			return []*ConstraintError{constraintErr}
+		}
+		// Some types report parse errors directly, wrap them so that the path can be added.
+		return []*ConstraintError{{Message: "Invalid value", Cause: err}}
+	}
+	return nil
+}
+
+// constraintErrorCollector is implemented by types that hold other values and can report the errors of all of them.
+type constraintErrorCollector interface {
+	collectConstraintErrors(data any) []*ConstraintError
+}
+
+func collectNestedConstraintErrors(t Type, data any) []*ConstraintError {
+	if collector, ok := t.(constraintErrorCollector); ok {
+		return collector.collectConstraintErrors(data)
+	}
+	return nil
+}
+
+func (p *PropertySchema) collectConstraintErrors(data any) []*ConstraintError {
+	if p.Disabled {
+		return nil
+	}
+	return collectNestedConstraintErrors(p.TypeValue, data)
+}
+
+func (r *RefSchema) collectConstraintErrors(data any) []*ConstraintError {
+	return collectNestedConstraintErrors(r.referencedObjectCache, data)
+}
+
+func (s *ScopeSchema) collectConstraintErrors(data any) []*ConstraintError {
+	return s.ObjectsValue[s.RootValue].collectConstraintErrors(data)
+}
+
+func (o *ObjectSchema) collectConstraintErrors(data any) []*ConstraintError {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Map {
+		return nil
+	}
+	var errs []*ConstraintError
+	rawData := make(map[string]any, v.Len())
+	for _, key := range sortedMapKeys(v) {
+		// Unknown keys are reported under their own path, so that the problematic field can be located.
+		stringKey, ok := key.Interface().(string)
+		if !ok {
+			keyErr := o.invalidKeyError(key.Interface()).(*ConstraintError)
+			errs = append(errs, withPathSegment(keyErr, fmt.Sprintf("%v", key.Interface())))
+			continue
+		}
+		if _, ok := o.PropertiesValue[stringKey]; !ok {
+			errs = append(errs, withPathSegment(o.invalidKeyError(stringKey).(*ConstraintError), stringKey))
+			continue
+		}
+		rawData[stringKey] = v.MapIndex(key).Interface()
+	}
+	// Properties with a default value count as set for the interdependencies, like in Unserialize.
+	setData := make(map[string]any, len(rawData))
+	for propertyID, value := range rawData {
+		setData[propertyID] = value
+	}
+	for propertyID, defaultValue := range o.GetDefaults() {
+		if _, isSet := setData[propertyID]; !isSet {
+			setData[propertyID] = defaultValue
+		}
+	}
+
+	propertyIDs := make([]string, 0, len(o.PropertiesValue))
+	for propertyID := range o.PropertiesValue {
+		propertyIDs = append(propertyIDs, propertyID)
+	}
+	sort.Strings(propertyIDs)
+	for _, propertyID := range propertyIDs {
+		property := o.PropertiesValue[propertyID]
+		var err error
+		if _, isSet := setData[propertyID]; isSet {
+			err = o.validatePropertyInterdependenciesIfSet(setData, propertyID, property)
+		} else {
+			err = o.validatePropertyInterdependenciesIfUnset(setData, propertyID, property)
+		}
+		if err != nil {
+			errs = append(errs, err.(*ConstraintError))
+		}
+		if value, isSet := rawData[propertyID]; isSet {
+			for _, err := range CollectConstraintErrors(property, value) {
+				errs = append(errs, withPathSegment(err, propertyID))
+			}
+		}
+	}
+	return errs
+}
+
+func (l AbstractListSchema[ItemType]) collectConstraintErrors(data any) []*ConstraintError {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice {
+		return nil
+	}
+	var errs []*ConstraintError
+	if err := l.validateLength(v.Len()); err != nil {
+		errs = append(errs, err.(*ConstraintError))
+	}
+	for i := 0; i < v.Len(); i++ {
+		for _, err := range CollectConstraintErrors(l.ItemsValue, v.Index(i).Interface()) {
+			errs = append(errs, withPathSegment(err, fmt.Sprintf("[%d]", i)))
+		}
+	}
+	return errs
+}
+
+func (m MapSchema[K, V]) collectConstraintErrors(data any) []*ConstraintError {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Map {
+		return nil
+	}
+	var errs []*ConstraintError
+	if err := m.validateLength(v.Len()); err != nil {
+		errs = append(errs, err.(*ConstraintError))
+	}
+	for _, k := range sortedMapKeys(v) {
+		for _, err := range CollectConstraintErrors(m.KeysValue, k.Interface()) {
+			errs = append(errs, withPathSegment(err, fmt.Sprintf("{%v}", k.Interface())))
+		}
+		for _, err := range CollectConstraintErrors(m.ValuesValue, v.MapIndex(k).Interface()) {
+			errs = append(errs, withPathSegment(err, fmt.Sprintf("[%v]", k.Interface())))
+		}
+	}
+	return errs
+}
+
+func (o OneOfSchema[KeyType]) collectConstraintErrors(data any) []*ConstraintError {
+	// Problems with the discriminator are reported by Unserialize, only the selected object is inspected further.
+	reflectedValue := reflect.ValueOf(data)
+	if reflectedValue.Kind() != reflect.Map {
+		return nil
+	}
+	discriminatorValue := reflectedValue.MapIndex(reflect.ValueOf(o.DiscriminatorFieldNameValue))
+	if !discriminatorValue.IsValid() {
+		return nil
+	}
+	typedDiscriminator, err := o.getTypedDiscriminator(discriminatorValue.Interface())
+	if err != nil {
+		return nil
+	}
+	selectedType, ok := o.TypesValue[typedDiscriminator]
+	if !ok {
+		return nil
+	}
+	objectData := make(map[any]any, reflectedValue.Len())
+	for _, k := range reflectedValue.MapKeys() {
+		objectData[k.Interface()] = reflectedValue.MapIndex(k).Interface()
+	}
+	if _, ok := selectedType.Properties()[o.DiscriminatorFieldNameValue]; !ok {
+		delete(objectData, o.DiscriminatorFieldNameValue)
+	}
+	return collectNestedConstraintErrors(selectedType, objectData)
+}
+
+func withPathSegment(err *ConstraintError, pathSegment string) *ConstraintError {
+	_ = err.AddPathSegment(pathSegment)
+	return err
+}
+
+// sortedMapKeys returns the keys of a map in a stable order, so that errors are always reported in the same order.
+func sortedMapKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprintf("%v", keys[i].Interface()) < fmt.Sprintf("%v", keys[j].Interface())
+	})
+	return keys
+}
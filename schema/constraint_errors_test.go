@@ -0,0 +1,136 @@
+package schema_test
+
+import (
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+var collectTestScope = schema.NewScopeSchema(
+	schema.NewObjectSchema(
+		"Root",
+		map[string]*schema.PropertySchema{
+			"name": schema.NewPropertySchema(
+				schema.NewStringSchema(schema.IntPointer(3), nil, nil),
+				nil,
+				true,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+			"port": schema.NewPropertySchema(
+				schema.NewIntSchema(schema.IntPointer(1), schema.IntPointer(65535), nil),
+				nil,
+				false,
+				nil,
+				nil,
+				[]string{"socket"},
+				nil,
+				nil,
+			),
+			"socket": schema.NewPropertySchema(
+				schema.NewStringSchema(nil, nil, nil),
+				nil,
+				false,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+			"children": schema.NewPropertySchema(
+				schema.NewListSchema(schema.NewRefSchema("Child", nil), nil, schema.IntPointer(2)),
+				nil,
+				false,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+			"labels": schema.NewPropertySchema(
+				schema.NewMapSchema(
+					schema.NewStringSchema(nil, schema.IntPointer(3), nil),
+					schema.NewIntSchema(nil, nil, nil),
+					nil,
+					nil,
+				),
+				nil,
+				false,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+		},
+	),
+	schema.NewObjectSchema(
+		"Child",
+		map[string]*schema.PropertySchema{
+			"weight": schema.NewPropertySchema(
+				schema.NewFloatSchema(schema.PointerTo(0.0), nil, nil),
+				nil,
+				true,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+		},
+	),
+)
+
+func TestCollectConstraintErrors(t *testing.T) {
+	errs := schema.CollectConstraintErrors(collectTestScope, map[string]any{
+		"name":   "a",
+		"port":   int64(70000),
+		"socket": "/tmp/socket",
+		"children": []any{
+			map[string]any{"weight": -1.0},
+			map[string]any{},
+			map[string]any{"weight": 1.0},
+		},
+		"labels": map[string]any{
+			"ok":        int64(1),
+			"too-long":  int64(2),
+			"not-a-num": "x",
+		},
+		"unknown": true,
+	})
+	paths := make([]string, len(errs))
+	for i, err := range errs {
+		for _, segment := range err.Path {
+			paths[i] += "/" + segment
+		}
+	}
+	assert.Equals(t, paths, []string{
+		"/unknown",
+		"/children",
+		"/children/[0]/weight",
+		"/children/[1]/weight",
+		"/labels/{not-a-num}",
+		"/labels/[not-a-num]",
+		"/labels/{too-long}",
+		"/name",
+		"/port",
+		"/port",
+	})
+}
+
+func TestCollectConstraintErrors_Valid(t *testing.T) {
+	errs := schema.CollectConstraintErrors(collectTestScope, map[string]any{
+		"name":     "abc",
+		"children": []any{map[string]any{"weight": 1.0}},
+	})
+	assert.Equals(t, len(errs), 0)
+}
+
+func TestCollectConstraintErrors_NotAnObject(t *testing.T) {
+	errs := schema.CollectConstraintErrors(collectTestScope, "abc")
+	assert.Equals(t, len(errs), 1)
+}
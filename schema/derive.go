@@ -0,0 +1,498 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DeriveScopeSchema derives a scope schema from the struct type T, so the schema does not have to be written by hand
+// and cannot drift from the struct. T becomes the root object, nested structs become objects of the scope referenced
+// with RefSchema. The object IDs are the names of the struct types. Unserializing with the scope returns a T. T may be
+// a pointer to a struct, unless the struct references itself.
+//
+// Exported fields become properties. The property ID is taken from the json tag, or the field name if there is none,
+// and fields tagged with json:"-" are skipped. The properties are described with the following tags:
+//
+//   - name, description: the display name and description of the property.
+//   - required: "true" if the property is required.
+//   - min, max: the minimum and maximum value of numbers, the length of strings, or the number of items of lists
+//     and maps.
+//   - pattern: a regular expression strings must match.
+//   - units: the units of numbers, one of bytes, nanoseconds, seconds, characters or percentage. time.Duration fields
+//     default to nanoseconds.
+//   - default: the default value as JSON. The default value of strings may also be given as it is, unquoted.
+//   - examples: a comma-separated list of example values written like the default value, or a JSON array of
+//     examples.
+//   - conflicts, required_if, required_if_not: comma-separated lists of property IDs.
+//
+// For example:
+//
+//	type Input struct {
+//	    Name    string        `json:"name" name:"Name" description:"Name to greet" required:"true" min:"1"`
+//	    Timeout time.Duration `json:"timeout" default:"5000000000" examples:"1000000000"`
+//	    Address *Address      `json:"address" conflicts:"name"`
+//	}
+//
+// It returns an error if a field type or a tag cannot be mapped to the schema, or if the derived scope fails
+// CheckScope, for example because a default value does not match the type of the field.
+func DeriveScopeSchema[T any]() (*ScopeSchema, error) {
+	root, objects, err := DeriveObjectSchema[T]()
+	if err != nil {
+		return nil, err
+	}
+	scope := NewScopeSchema(root, objects...)
+	if problems := CheckScope(scope); len(problems) > 0 {
+		messages := make([]string, len(problems))
+		for i, problem := range problems {
+			messages[i] = problem.Error()
+		}
+		return nil, BadArgumentError{
+			Message: fmt.Sprintf(
+				"the schema derived from %s is invalid: %s",
+				reflect.TypeOf((*T)(nil)).Elem(),
+				strings.Join(messages, ", "),
+			),
+		}
+	}
+	return scope, nil
+}
+
+// DeriveObjectSchema derives the object schema of the struct type T the same way as DeriveScopeSchema. It returns the
+// object of T and the objects of the nested structs it references, ready to be added to a scope with NewScopeSchema.
+func DeriveObjectSchema[T any]() (*ObjectSchema, []*ObjectSchema, error) {
+	reflectType := reflect.TypeOf((*T)(nil)).Elem()
+	structType := reflectType
+	if structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return nil, nil, BadArgumentError{
+			Message: fmt.Sprintf("DeriveObjectSchema should only be called with a struct type, %s given", reflectType),
+		}
+	}
+	d := &deriver{
+		objects: map[string]*ObjectSchema{},
+		types:   map[string]reflect.Type{},
+	}
+	rootID, err := d.deriveObject(structType)
+	if err != nil {
+		return nil, nil, err
+	}
+	root := d.objects[rootID]
+	if reflectType != structType {
+		root = newStructMappedObjectSchema(rootID, root.PropertiesValue, reflectType)
+	}
+	objects := make([]*ObjectSchema, 0, len(d.objects)-1)
+	for _, id := range sortedStringKeys(d.objects) {
+		if id != rootID {
+			objects = append(objects, d.objects[id])
+		}
+	}
+	return root, objects, nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+var unitsByName = map[string]*UnitsDefinition{
+	"bytes":       UnitBytes,
+	"nanoseconds": UnitDurationNanoseconds,
+	"seconds":     UnitDurationSeconds,
+	"characters":  UnitCharacters,
+	"percentage":  UnitPercentage,
+}
+
+type deriver struct {
+	// objects holds the derived objects by ID.
+	objects map[string]*ObjectSchema
+	// types holds the struct type of each object ID, to detect structs of different packages with the same name.
+	types map[string]reflect.Type
+}
+
+// deriveObject derives the object of the struct type, unless it is derived already, and returns its ID.
+func (d *deriver) deriveObject(structType reflect.Type) (string, error) {
+	id := structType.Name()
+	if id == "" {
+		return "", BadArgumentError{
+			Message: fmt.Sprintf("cannot derive an object from the anonymous struct %s, please name the type", structType),
+		}
+	}
+	if existingType, ok := d.types[id]; ok {
+		if existingType != structType {
+			return "", BadArgumentError{
+				Message: fmt.Sprintf(
+					"the types %s and %s both map to the object ID %s",
+					existingType.PkgPath()+"."+id,
+					structType.PkgPath()+"."+id,
+					id,
+				),
+			}
+		}
+		return id, nil
+	}
+	// Register the type before deriving the properties, so recursive structs reference themselves.
+	d.types[id] = structType
+
+	properties := map[string]*PropertySchema{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		propertyID, ok := derivedPropertyID(field)
+		if !ok {
+			continue
+		}
+		property, err := d.deriveProperty(field)
+		if err != nil {
+			return "", BadArgumentError{
+				Message: fmt.Sprintf("cannot derive the property of field %s.%s", id, field.Name),
+				Cause:   err,
+			}
+		}
+		properties[propertyID] = property
+	}
+	d.objects[id] = newStructMappedObjectSchema(id, properties, structType)
+	return id, nil
+}
+
+// derivedPropertyID returns the property ID of the field, or false if the field is not a property.
+func derivedPropertyID(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	jsonTag := field.Tag.Get("json")
+	if jsonTag == "-" {
+		return "", false
+	}
+	if name := strings.SplitN(jsonTag, ",", 2)[0]; name != "" {
+		return name, true
+	}
+	return field.Name, true
+}
+
+func (d *deriver) deriveProperty(field reflect.StructField) (*PropertySchema, error) {
+	tags := field.Tag
+	t, err := d.deriveType(field.Type, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	required := false
+	if value, ok := tags.Lookup("required"); ok {
+		if required, err = strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("invalid required tag %q (%w)", value, err)
+		}
+	}
+	var defaultValue *string
+	if value, ok := tags.Lookup("default"); ok {
+		serializedValue, err := serializeTagValue(t, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default tag %q (%w)", value, err)
+		}
+		defaultValue = &serializedValue
+	}
+	var examples []string
+	if value, ok := tags.Lookup("examples"); ok {
+		if examples, err = serializeTagExamples(t, value); err != nil {
+			return nil, fmt.Errorf("invalid examples tag %q (%w)", value, err)
+		}
+	}
+	var display Display
+	name := optionalTag(tags, "name")
+	description := optionalTag(tags, "description")
+	if name != nil || description != nil {
+		display = NewDisplayValue(name, description, nil)
+	}
+	return NewPropertySchema(
+		t,
+		display,
+		required,
+		splitTagList(tags.Get("required_if")),
+		splitTagList(tags.Get("required_if_not")),
+		splitTagList(tags.Get("conflicts")),
+		defaultValue,
+		examples,
+	), nil
+}
+
+// deriveType derives the type of the Go type. The min, max, pattern and units tags apply to the type itself, not the
+// items of lists and maps.
+func (d *deriver) deriveType(reflectType reflect.Type, tags reflect.StructTag) (Type, error) {
+	if reflectType.Kind() == reflect.Pointer {
+		reflectType = reflectType.Elem()
+	}
+	var pattern *regexp.Regexp
+	if value, ok := tags.Lookup("pattern"); ok {
+		if reflectType.Kind() != reflect.String {
+			return nil, fmt.Errorf("the pattern tag is only supported on strings, %s given", reflectType)
+		}
+		var err error
+		if pattern, err = regexp.Compile(value); err != nil {
+			return nil, fmt.Errorf("invalid pattern tag %q (%w)", value, err)
+		}
+	}
+	var units *UnitsDefinition
+	if value, ok := tags.Lookup("units"); ok {
+		if units, ok = unitsByName[value]; !ok {
+			return nil, fmt.Errorf(
+				"invalid units tag %q, expected one of: %s",
+				value,
+				strings.Join(sortedStringKeys(unitsByName), ", "),
+			)
+		}
+	} else if reflectType == durationType {
+		units = UnitDurationNanoseconds
+	}
+	if units != nil {
+		switch reflectType.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8,
+			reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		default:
+			return nil, fmt.Errorf("the units tag is only supported on numbers, %s given", reflectType)
+		}
+	}
+
+	switch reflectType.Kind() {
+	case reflect.String:
+		minLength, maxLength, err := intTagRange(tags)
+		if err != nil {
+			return nil, err
+		}
+		return NewStringSchema(minLength, maxLength, pattern), nil
+	case reflect.Bool:
+		if err := noTagRange(tags, reflectType); err != nil {
+			return nil, err
+		}
+		return NewBoolSchema(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8,
+		reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		minValue, maxValue, err := intKindTagRange(tags, reflectType)
+		if err != nil {
+			return nil, err
+		}
+		return NewIntSchema(minValue, maxValue, units), nil
+	case reflect.Float32, reflect.Float64:
+		minValue, maxValue, err := floatKindTagRange(tags, reflectType)
+		if err != nil {
+			return nil, err
+		}
+		return NewFloatSchema(minValue, maxValue, units), nil
+	case reflect.Slice:
+		minItems, maxItems, err := intTagRange(tags)
+		if err != nil {
+			return nil, err
+		}
+		items, err := d.deriveType(reflectType.Elem(), "")
+		if err != nil {
+			return nil, err
+		}
+		return NewListSchema(items, minItems, maxItems), nil
+	case reflect.Map:
+		minItems, maxItems, err := intTagRange(tags)
+		if err != nil {
+			return nil, err
+		}
+		keys, err := d.deriveType(reflectType.Key(), "")
+		if err != nil {
+			return nil, err
+		}
+		if keys.TypeID() != TypeIDString && keys.TypeID() != TypeIDInt {
+			return nil, fmt.Errorf("map keys must be strings or integers, %s given", reflectType.Key())
+		}
+		values, err := d.deriveType(reflectType.Elem(), "")
+		if err != nil {
+			return nil, err
+		}
+		return NewMapSchema(keys, values, minItems, maxItems), nil
+	case reflect.Struct:
+		if err := noTagRange(tags, reflectType); err != nil {
+			return nil, err
+		}
+		id, err := d.deriveObject(reflectType)
+		if err != nil {
+			return nil, err
+		}
+		return NewRefSchema(id, nil), nil
+	case reflect.Interface:
+		if reflectType.NumMethod() != 0 {
+			return nil, fmt.Errorf("only the empty interface is supported, %s given", reflectType)
+		}
+		return NewAnySchema(), nil
+	default:
+		return nil, fmt.Errorf("the type %s cannot be mapped to the schema", reflectType)
+	}
+}
+
+func noTagRange(tags reflect.StructTag, reflectType reflect.Type) error {
+	for _, tag := range []string{"min", "max"} {
+		if _, ok := tags.Lookup(tag); ok {
+			return fmt.Errorf("the %s tag is not supported on %s", tag, reflectType)
+		}
+	}
+	return nil
+}
+
+func intTagRange(tags reflect.StructTag) (*int64, *int64, error) {
+	var result [2]*int64
+	for i, tag := range []string{"min", "max"} {
+		if value, ok := tags.Lookup(tag); ok {
+			parsedValue, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid %s tag %q (%w)", tag, value, err)
+			}
+			result[i] = &parsedValue
+		}
+	}
+	return result[0], result[1], nil
+}
+
+// intKindTagRange returns the range of the min and max tags, bounded by the values the integer type can hold so that
+// no value wraps around when it is converted to the field. It returns an error if a tag is outside of that range.
+// The bounds the int64 of the schema already enforces are left out; uint64 values above them cannot be represented.
+func intKindTagRange(tags reflect.StructTag, reflectType reflect.Type) (*int64, *int64, error) {
+	minValue, maxValue, err := intTagRange(tags)
+	if err != nil {
+		return nil, nil, err
+	}
+	kindMin, kindMax := intKindRange(reflectType)
+	for i, value := range []*int64{minValue, maxValue} {
+		if value != nil && (*value < kindMin || *value > kindMax) {
+			return nil, nil, fmt.Errorf(
+				"the %s tag %d is out of the range of %s",
+				[]string{"min", "max"}[i],
+				*value,
+				reflectType,
+			)
+		}
+	}
+	if minValue == nil && kindMin != math.MinInt64 {
+		minValue = &kindMin
+	}
+	if maxValue == nil && kindMax != math.MaxInt64 {
+		maxValue = &kindMax
+	}
+	return minValue, maxValue, nil
+}
+
+// intKindRange returns the range of values the integer type can hold, limited to the int64 values of the schema.
+func intKindRange(reflectType reflect.Type) (int64, int64) {
+	bits := reflectType.Bits()
+	switch reflectType.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if bits == 64 {
+			return 0, math.MaxInt64
+		}
+		return 0, int64(1)<<bits - 1
+	default:
+		if bits == 64 {
+			return math.MinInt64, math.MaxInt64
+		}
+		return -(int64(1) << (bits - 1)), int64(1)<<(bits-1) - 1
+	}
+}
+
+// floatKindTagRange returns the range of the min and max tags. For float32 it is bounded by the largest float32, so
+// that no value turns into an infinity when it is converted to the field.
+func floatKindTagRange(tags reflect.StructTag, reflectType reflect.Type) (*float64, *float64, error) {
+	minValue, maxValue, err := floatTagRange(tags)
+	if err != nil || reflectType.Kind() != reflect.Float32 {
+		return minValue, maxValue, err
+	}
+	kindMin, kindMax := -math.MaxFloat32, math.MaxFloat32
+	for i, value := range []*float64{minValue, maxValue} {
+		if value != nil && (*value < kindMin || *value > kindMax) {
+			return nil, nil, fmt.Errorf(
+				"the %s tag %g is out of the range of %s",
+				[]string{"min", "max"}[i],
+				*value,
+				reflectType,
+			)
+		}
+	}
+	if minValue == nil {
+		minValue = &kindMin
+	}
+	if maxValue == nil {
+		maxValue = &kindMax
+	}
+	return minValue, maxValue, nil
+}
+
+func floatTagRange(tags reflect.StructTag) (*float64, *float64, error) {
+	var result [2]*float64
+	for i, tag := range []string{"min", "max"} {
+		if value, ok := tags.Lookup(tag); ok {
+			parsedValue, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid %s tag %q (%w)", tag, value, err)
+			}
+			result[i] = &parsedValue
+		}
+	}
+	return result[0], result[1], nil
+}
+
+// serializeTagValue returns the JSON of a default or example value given in a tag. String values that are not JSON
+// strings are taken as they are.
+func serializeTagValue(t Type, value string) (string, error) {
+	var decoded any
+	if err := json.Unmarshal([]byte(value), &decoded); err == nil {
+		if _, isString := decoded.(string); isString || t.TypeID() != TypeIDString {
+			return value, nil
+		}
+	} else if t.TypeID() != TypeIDString {
+		return "", fmt.Errorf("not valid JSON (%w)", err)
+	}
+	result, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
+
+// serializeTagExamples returns the JSON of each example given in the examples tag, either as a JSON array or as a
+// comma-separated list.
+func serializeTagExamples(t Type, value string) ([]string, error) {
+	var decoded []json.RawMessage
+	if strings.HasPrefix(strings.TrimSpace(value), "[") {
+		if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+			return nil, fmt.Errorf("not a valid JSON array (%w)", err)
+		}
+		result := make([]string, len(decoded))
+		for i, example := range decoded {
+			result[i] = string(example)
+		}
+		return result, nil
+	}
+	examples := splitTagList(value)
+	result := make([]string, len(examples))
+	for i, example := range examples {
+		serializedExample, err := serializeTagValue(t, example)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = serializedExample
+	}
+	return result, nil
+}
+
+func optionalTag(tags reflect.StructTag, key string) *string {
+	if value, ok := tags.Lookup(key); ok {
+		return &value
+	}
+	return nil
+}
+
+func splitTagList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	parts := strings.Split(value, ",")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return parts
+}
@@ -0,0 +1,204 @@
+package schema_test
+
+import (
+	"testing"
+	"time"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+type derivedAddress struct {
+	Street string `json:"street" required:"true"`
+	City   string `json:"city" default:"Arcaville"`
+}
+
+type derivedInput struct {
+	Name      string                    `json:"name" name:"Name" description:"Name to greet" required:"true" min:"1"`
+	Code      string                    `json:"code" pattern:"^[A-Z]+$" examples:"AB,CD"`
+	Count     int                       `json:"count" min:"1" max:"10" default:"3" conflicts:"ratio"`
+	Ratio     float64                   `json:"ratio" max:"1" examples:"0.5"`
+	Size      int64                     `json:"size" units:"bytes"`
+	Timeout   time.Duration             `json:"timeout"`
+	Verbose   bool                      `json:"verbose,omitempty" required_if:"ratio"`
+	Tags      []string                  `json:"tags" max:"3" examples:"[[\"a\", \"b\"]]"`
+	Labels    map[string]string         `json:"labels"`
+	Address   *derivedAddress           `json:"address"`
+	Addresses []derivedAddress          `json:"addresses"`
+	Extra     any                       `json:"extra"`
+	Ignored   string                    `json:"-"`
+	internal  string                    //nolint:unused
+	Nested    map[string]derivedAddress `json:"nested"`
+}
+
+func TestDeriveScopeSchema(t *testing.T) {
+	scope, err := schema.DeriveScopeSchema[derivedInput]()
+	assert.NoError(t, err)
+	assert.Equals(t, scope.Root(), "derivedInput")
+	assert.Equals(t, len(scope.Objects()), 2)
+
+	properties := scope.Properties()
+	assert.Equals(t, len(properties), 13)
+	name := properties["name"]
+	assert.Equals(t, name.Required(), true)
+	assert.Equals(t, *name.Display().Name(), "Name")
+	assert.Equals(t, *name.Display().Description(), "Name to greet")
+	assert.Equals(t, *name.Type().(*schema.StringSchema).Min(), int64(1))
+	assert.Equals(t, properties["code"].Type().(*schema.StringSchema).Pattern().String(), "^[A-Z]+$")
+	assert.Equals(t, properties["code"].Examples(), []string{`"AB"`, `"CD"`})
+	assert.Equals(t, *properties["count"].Default(), "3")
+	assert.Equals(t, properties["count"].Conflicts(), []string{"ratio"})
+	assert.Equals(t, *properties["ratio"].Type().(*schema.FloatSchema).Max(), 1.0)
+	assert.Equals(t, properties["size"].Type().(*schema.IntSchema).Units(), schema.UnitBytes)
+	assert.Equals(
+		t,
+		properties["timeout"].Type().(*schema.IntSchema).Units(),
+		schema.UnitDurationNanoseconds,
+	)
+	assert.Equals(t, properties["verbose"].RequiredIf(), []string{"ratio"})
+	assert.Equals(t, properties["tags"].Examples(), []string{`["a", "b"]`})
+	assert.Equals(t, properties["address"].Type().TypeID(), schema.TypeIDRef)
+	assert.Equals(t, properties["extra"].Type().TypeID(), schema.TypeIDAny)
+	assert.Equals(t, *scope.Objects()["derivedAddress"].Properties()["city"].Default(), `"Arcaville"`)
+
+	unserialized, err := scope.Unserialize(map[string]any{
+		"name":      "Arca Lot",
+		"size":      "2kB",
+		"tags":      []any{"a"},
+		"address":   map[string]any{"street": "Main Street"},
+		"addresses": []any{map[string]any{"street": "Side Street", "city": "Flowtown"}},
+	})
+	assert.NoError(t, err)
+	input := unserialized.(derivedInput)
+	assert.Equals(t, input.Name, "Arca Lot")
+	assert.Equals(t, input.Count, 3)
+	assert.Equals(t, input.Size, int64(2048))
+	assert.Equals(t, *input.Address, derivedAddress{Street: "Main Street", City: "Arcaville"})
+	assert.Equals(t, input.Addresses, []derivedAddress{{Street: "Side Street", City: "Flowtown"}})
+
+	_, err = scope.Unserialize(map[string]any{"name": "Arca Lot", "count": 11})
+	assert.Error(t, err)
+	_, err = scope.Unserialize(map[string]any{"count": 1})
+	assert.Error(t, err)
+}
+
+type derivedNode struct {
+	Value    string        `json:"value"`
+	Children []derivedNode `json:"children"`
+}
+
+func TestDeriveScopeSchema_Recursive(t *testing.T) {
+	scope, err := schema.DeriveScopeSchema[derivedNode]()
+	assert.NoError(t, err)
+	assert.Equals(t, len(scope.Objects()), 1)
+	unserialized, err := scope.Unserialize(map[string]any{
+		"value":    "root",
+		"children": []any{map[string]any{"value": "leaf"}},
+	})
+	assert.NoError(t, err)
+	assert.Equals(t, unserialized.(derivedNode).Children[0].Value, "leaf")
+}
+
+func TestDeriveScopeSchema_Pointer(t *testing.T) {
+	scope, err := schema.DeriveScopeSchema[*derivedAddress]()
+	assert.NoError(t, err)
+	unserialized, err := scope.Unserialize(map[string]any{"street": "Main Street"})
+	assert.NoError(t, err)
+	assert.Equals(t, *unserialized.(*derivedAddress), derivedAddress{Street: "Main Street", City: "Arcaville"})
+}
+
+type derivedCounts struct {
+	Counts  []int              `json:"counts"`
+	Weights map[string]float32 `json:"weights"`
+	Matrix  [][]int32          `json:"matrix"`
+}
+
+func TestDeriveScopeSchema_NumberLists(t *testing.T) {
+	scope, err := schema.DeriveScopeSchema[derivedCounts]()
+	assert.NoError(t, err)
+	data := map[string]any{
+		"counts":  []any{1, 2, 3},
+		"weights": map[string]any{"a": 0.5},
+		"matrix":  []any{[]any{1, 2}, []any{3}},
+	}
+	unserialized, err := scope.Unserialize(data)
+	assert.NoError(t, err)
+	counts := unserialized.(derivedCounts)
+	assert.Equals(t, counts, derivedCounts{
+		Counts:  []int{1, 2, 3},
+		Weights: map[string]float32{"a": 0.5},
+		Matrix:  [][]int32{{1, 2}, {3}},
+	})
+	serialized, err := scope.Serialize(counts)
+	assert.NoError(t, err)
+	assert.Equals(t, serialized.(map[string]any)["counts"].([]any), []any{int64(1), int64(2), int64(3)})
+}
+
+type derivedSizes struct {
+	Count uint8   `json:"count"`
+	Small int8    `json:"small"`
+	Level int16   `json:"level" min:"1"`
+	Large uint64  `json:"large"`
+	Items []int32 `json:"items"`
+}
+
+func TestDeriveScopeSchema_IntRanges(t *testing.T) {
+	scope, err := schema.DeriveScopeSchema[derivedSizes]()
+	assert.NoError(t, err)
+	properties := scope.Properties()
+	count := properties["count"].Type().(*schema.IntSchema)
+	assert.Equals(t, *count.Min(), int64(0))
+	assert.Equals(t, *count.Max(), int64(255))
+	level := properties["level"].Type().(*schema.IntSchema)
+	assert.Equals(t, *level.Min(), int64(1))
+	assert.Equals(t, *level.Max(), int64(32767))
+	large := properties["large"].Type().(*schema.IntSchema)
+	assert.Equals(t, *large.Min(), int64(0))
+	assert.Nil(t, large.Max())
+
+	unserialized, err := scope.Unserialize(map[string]any{"count": 255, "small": -128, "items": []any{-2147483648}})
+	assert.NoError(t, err)
+	assert.Equals(t, unserialized.(derivedSizes), derivedSizes{Count: 255, Small: -128, Items: []int32{-2147483648}})
+
+	for name, data := range map[string]map[string]any{
+		"negative to unsigned": {"count": -1},
+		"overflow to uint8":    {"count": 256},
+		"overflow to int8":     {"small": 300},
+		"underflow to int8":    {"small": -129},
+		"overflow to int32":    {"items": []any{2147483648}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := scope.Unserialize(data)
+			assert.Error(t, err)
+		})
+	}
+}
+
+type derivedInvalidUnits struct {
+	Name string `json:"name" units:"bytes"`
+}
+
+type derivedInvalidDefault struct {
+	Count int `json:"count" min:"1" default:"0"`
+}
+
+type derivedInvalidRange struct {
+	Count uint8 `json:"count" max:"256"`
+}
+
+type derivedInvalidType struct {
+	Callback func() `json:"callback"`
+}
+
+func TestDeriveScopeSchema_Invalid(t *testing.T) {
+	_, err := schema.DeriveScopeSchema[derivedInvalidUnits]()
+	assert.Contains(t, err.Error(), "the units tag is only supported on numbers")
+	_, err = schema.DeriveScopeSchema[derivedInvalidDefault]()
+	assert.Contains(t, err.Error(), "count: the default value 0 is invalid")
+	_, err = schema.DeriveScopeSchema[derivedInvalidRange]()
+	assert.Contains(t, err.Error(), "the max tag 256 is out of the range of uint8")
+	_, err = schema.DeriveScopeSchema[derivedInvalidType]()
+	assert.Contains(t, err.Error(), "the type func() cannot be mapped to the schema")
+	_, err = schema.DeriveScopeSchema[string]()
+	assert.Error(t, err)
+}
@@ -1,5 +1,7 @@
 package schema
 
+import "reflect"
+
 // Display holds the data related to displaying fields.
 type Display interface {
 	Name() *string
@@ -34,3 +36,21 @@ func (d DisplayValue) Description() *string {
 func (d DisplayValue) Icon() *string {
 	return d.IconValue
 }
+
+// DisplayText returns the name and the description of a display, or empty strings if they are not set. The display
+// may be nil, including a nil *DisplayValue, which is what the schemas return if no display was given.
+func DisplayText(display Display) (name string, description string) {
+	if display == nil {
+		return "", ""
+	}
+	if value := reflect.ValueOf(display); value.Kind() == reflect.Pointer && value.IsNil() {
+		return "", ""
+	}
+	if display.Name() != nil {
+		name = *display.Name()
+	}
+	if display.Description() != nil {
+		description = *display.Description()
+	}
+	return name, description
+}
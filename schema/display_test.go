@@ -17,3 +17,15 @@ func TestDisplayValue(t *testing.T) {
 	assert.Equals(t, *dv.Description(), "Hello world!")
 	assert.Equals(t, *dv.Icon(), "<svg ...></svg>")
 }
+
+func TestDisplayText(t *testing.T) {
+	name, description := schema.DisplayText(schema.NewDisplayValue(schema.PointerTo("Greeting"), nil, nil))
+	assert.Equals(t, name, "Greeting")
+	assert.Equals(t, description, "")
+
+	var nilDisplay *schema.DisplayValue
+	name, description = schema.DisplayText(nilDisplay)
+	assert.Equals(t, name+description, "")
+	name, description = schema.DisplayText(nil)
+	assert.Equals(t, name+description, "")
+}
@@ -0,0 +1,73 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type executionKey struct{}
+
+// execution holds the step data of a single step execution. It is created by ContextWithExecution and filled in by
+// the first step call using the context.
+type execution struct {
+	lock  sync.Mutex
+	step  any
+	data  any
+	ready chan struct{}
+}
+
+// ContextWithExecution returns a copy of the context that marks a new execution of a single step. Step calls and
+// signal calls using the returned context share the step data of this execution only, while calls with a context of
+// another execution get their own step data. Servers running more than one execution with the same callable schema
+// must use a new execution context for every execution, and must pass it to the signals of that execution.
+//
+// Calls without an execution context share one set of step data for the lifetime of the step, which is only correct
+// if the step runs once.
+func ContextWithExecution(ctx context.Context) context.Context {
+	return context.WithValue(ctx, executionKey{}, &execution{ready: make(chan struct{})})
+}
+
+func executionFromContext(ctx context.Context) *execution {
+	exec, _ := ctx.Value(executionKey{}).(*execution)
+	return exec
+}
+
+// initialize creates the step data of the execution for the given step, unless it has already been created.
+func (e *execution) initialize(step any, initializer func() (any, error)) (any, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.step != nil {
+		if e.step != step {
+			return nil, IllegalStateError{
+				fmt.Errorf("the execution context has already been used for a different step"),
+			}
+		}
+		return e.data, nil
+	}
+	data, err := initializer()
+	if err != nil {
+		return nil, err
+	}
+	e.step = step
+	e.data = data
+	close(e.ready)
+	return e.data, nil
+}
+
+// wait waits until the step data of the execution has been created by the given step, or the context is done.
+func (e *execution) wait(ctx context.Context, step any) (any, error) {
+	select {
+	case <-e.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.step != step {
+		return nil, IllegalStateError{
+			fmt.Errorf("the execution context belongs to a different step"),
+		}
+	}
+	return e.data, nil
+}
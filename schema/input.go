@@ -5,4 +5,9 @@ type Input struct {
 	ID string
 	// The data being input into the step/signal/other
 	InputData any
+	// TraceContext optionally holds the trace context to propagate to the plugin. It is only sent with step inputs.
+	TraceContext *TraceContext
+	// Secrets optionally holds credentials for input properties marked as secret. They are sent separately from the
+	// input data. It is only sent with step inputs.
+	Secrets Secrets
 }
@@ -0,0 +1,98 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ConvertJSONNumbers replaces the json.Number values a JSON decoder with UseNumber stored in the interface values
+// reachable from the pointer v. Integers become int64, other numbers float64. Decoding without UseNumber turns all
+// numbers into float64, which silently changes integers above 2^53, so decoders feeding the schema should use
+// UseNumber and convert the numbers with this function. It returns an error for integers outside of the int64 range,
+// as they cannot be represented exactly.
+func ConvertJSONNumbers(v any) error {
+	return convertJSONNumbers(reflect.ValueOf(v))
+}
+
+func convertJSONNumbers(value reflect.Value) error {
+	switch value.Kind() {
+	case reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		if number, ok := value.Interface().(json.Number); ok {
+			if !value.CanSet() {
+				return nil
+			}
+			converted, err := jsonNumberValue(number)
+			if err != nil {
+				return err
+			}
+			value.Set(reflect.ValueOf(converted))
+			return nil
+		}
+		return convertJSONNumbers(value.Elem())
+	case reflect.Pointer:
+		if value.IsNil() {
+			return nil
+		}
+		return convertJSONNumbers(value.Elem())
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).IsExported() {
+				if err := convertJSONNumbers(value.Field(i)); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if !mayHoldJSONNumbers(value.Type().Elem()) {
+			return nil
+		}
+		for i := 0; i < value.Len(); i++ {
+			if err := convertJSONNumbers(value.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if !mayHoldJSONNumbers(value.Type().Elem()) {
+			return nil
+		}
+		for iter := value.MapRange(); iter.Next(); {
+			item := iter.Value()
+			// Map values cannot be set in place, so numbers are replaced through the map.
+			if number, ok := item.Interface().(json.Number); ok && item.Kind() == reflect.Interface {
+				converted, err := jsonNumberValue(number)
+				if err != nil {
+					return err
+				}
+				value.SetMapIndex(iter.Key(), reflect.ValueOf(converted))
+			} else if err := convertJSONNumbers(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func mayHoldJSONNumbers(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Interface, reflect.Pointer, reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	default:
+		return false
+	}
+}
+
+func jsonNumberValue(number json.Number) (any, error) {
+	if !strings.ContainsAny(number.String(), ".eE") {
+		value, err := number.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("the integer %s cannot be represented as a 64-bit integer (%w)", number, err)
+		}
+		return value, nil
+	}
+	return number.Float64()
+}
@@ -0,0 +1,53 @@
+package schema_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+type jsonNumberMessage struct {
+	ID   uint32 `json:"id"`
+	Data any    `json:"data"`
+}
+
+func decodeJSONNumbers(t *testing.T, data string, v any) error {
+	decoder := json.NewDecoder(bytes.NewBufferString(data))
+	decoder.UseNumber()
+	assert.NoError(t, decoder.Decode(v))
+	return schema.ConvertJSONNumbers(v)
+}
+
+func TestConvertJSONNumbers(t *testing.T) {
+	var data any
+	assert.NoError(t, decodeJSONNumbers(
+		t,
+		`{"big": 9007199254740993, "ratio": 0.5, "exponent": 1e3, "list": [1, {"count": -2}], "nested": [[3]]}`,
+		&data,
+	))
+	assert.Equals(t, data, any(map[string]any{
+		"big":      int64(9007199254740993),
+		"ratio":    0.5,
+		"exponent": 1000.0,
+		"list":     []any{int64(1), map[string]any{"count": int64(-2)}},
+		"nested":   []any{[]any{int64(3)}},
+	}))
+
+	var message jsonNumberMessage
+	assert.NoError(t, decodeJSONNumbers(t, `{"id": 1, "data": {"count": 9007199254740993}}`, &message))
+	assert.Equals(t, message, jsonNumberMessage{ID: 1, Data: map[string]any{"count": int64(9007199254740993)}})
+
+	var number any
+	assert.NoError(t, decodeJSONNumbers(t, `42`, &number))
+	assert.Equals(t, number, any(int64(42)))
+}
+
+func TestConvertJSONNumbers_OutOfRange(t *testing.T) {
+	var data any
+	err := decodeJSONNumbers(t, `{"big": 100000000000000000000}`, &data)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "the integer 100000000000000000000 cannot be represented")
+}
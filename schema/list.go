@@ -22,6 +22,16 @@ type TypedList[UnserializedType any, ItemType TypedType[UnserializedType]] inter
 // UntypedList specifies a list that has no specific type.
 type UntypedList = List[Type]
 
+// ListItems returns the item type of any list schema, or nil if the type is not a list. Typed lists return a
+// specialized item type from Items, so they cannot be used as an UntypedList.
+func ListItems(t Type) Type {
+	list, ok := t.(interface{ itemType() Type })
+	if !ok {
+		return nil
+	}
+	return list.itemType()
+}
+
 // NewListSchema creates a new list schema from the specified values.
 func NewListSchema(items Type, min *int64, max *int64) *ListSchema {
 	return &ListSchema{
@@ -73,6 +83,11 @@ func (l AbstractListSchema[ItemType]) Items() ItemType {
 	return l.ItemsValue
 }
 
+// itemType returns the item type as a Type, which the typed lists do not return from Items.
+func (l AbstractListSchema[ItemType]) itemType() Type {
+	return l.ItemsValue
+}
+
 func (l AbstractListSchema[ItemType]) Min() *int64 {
 	return l.MinValue
 }
@@ -90,19 +105,26 @@ func (l AbstractListSchema[ItemType]) ReflectedType() reflect.Type {
 	return reflect.SliceOf(elementType)
 }
 
+func (l AbstractListSchema[ItemType]) validateLength(length int) error {
+	if l.MinValue != nil && *l.MinValue > int64(length) {
+		return &ConstraintError{
+			Message: fmt.Sprintf("Must have at least %d items, %d given", *l.MinValue, length),
+		}
+	}
+	if l.MaxValue != nil && *l.MaxValue < int64(length) {
+		return &ConstraintError{
+			Message: fmt.Sprintf("Must have at most %d items, %d given", *l.MaxValue, length),
+		}
+	}
+	return nil
+}
+
 func (l AbstractListSchema[ItemType]) Unserialize(data any) (any, error) {
 	v := reflect.ValueOf(data)
 	switch v.Kind() {
 	case reflect.Slice:
-		if l.MinValue != nil && *l.MinValue > int64(v.Len()) {
-			return nil, &ConstraintError{
-				Message: fmt.Sprintf("Must have at least %d items, %d given", *l.MinValue, v.Len()),
-			}
-		}
-		if l.MaxValue != nil && *l.MaxValue < int64(v.Len()) {
-			return nil, &ConstraintError{
-				Message: fmt.Sprintf("Must have at most %d items, %d given", *l.MaxValue, v.Len()),
-			}
+		if err := l.validateLength(v.Len()); err != nil {
+			return nil, err
 		}
 
 		result := reflect.MakeSlice(reflect.SliceOf(l.ItemsValue.ReflectedType()), v.Len(), v.Len())
@@ -181,3 +181,14 @@ func TestListVerifyCompatibility(t *testing.T) {
 	assert.NoError(t, intListSchema.ValidateCompatibility([]any{schema.NewIntSchema(nil, nil, nil)}))
 	assert.Error(t, intListSchema.ValidateCompatibility([]any{schema.NewStringSchema(nil, nil, nil)}))
 }
+
+func TestListItems(t *testing.T) {
+	items := schema.NewStringSchema(nil, nil, nil)
+	assert.Equals(t, schema.ListItems(schema.NewListSchema(items, nil, nil)), schema.Type(items))
+	assert.Equals[schema.Type](
+		t,
+		schema.ListItems(schema.NewTypedListSchema[string](items, nil, nil)),
+		schema.TypedType[string](items),
+	)
+	assert.Nil(t, schema.ListItems(items))
+}
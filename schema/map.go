@@ -19,6 +19,16 @@ type Map[KeyType Type, ValueType Type] interface {
 // UntypedMap is a map schema without specific underlying types.
 type UntypedMap = Map[Type, Type]
 
+// MapKeysValues returns the key and the value type of any map schema, or nil if the type is not a map. Typed maps
+// return specialized types from Keys and Values, so they cannot be used as an UntypedMap.
+func MapKeysValues(t Type) (keys Type, values Type) {
+	m, ok := t.(interface{ keyValueTypes() (Type, Type) })
+	if !ok {
+		return nil, nil
+	}
+	return m.keyValueTypes()
+}
+
 // TypedMap is a map schema that can be unserialized in its underlying components.
 type TypedMap[KeyType comparable, ValueType any] interface {
 	TypedType[map[KeyType]ValueType]
@@ -72,6 +82,11 @@ func (m MapSchema[K, V]) Values() V {
 	return m.ValuesValue
 }
 
+// keyValueTypes returns the key and the value type as a Type, which the typed maps do not return from Keys and Values.
+func (m MapSchema[K, V]) keyValueTypes() (Type, Type) {
+	return m.KeysValue, m.ValuesValue
+}
+
 func (m MapSchema[K, V]) Min() *int64 {
 	return m.MinValue
 }
@@ -85,6 +100,20 @@ func (m MapSchema[K, V]) ApplyScope(scope Scope) {
 	m.ValuesValue.ApplyScope(scope)
 }
 
+func (m MapSchema[K, V]) validateLength(length int) error {
+	if m.MinValue != nil && *m.MinValue > int64(length) {
+		return &ConstraintError{
+			Message: fmt.Sprintf("Must have at least %d items, %d given", *m.MinValue, length),
+		}
+	}
+	if m.MaxValue != nil && *m.MaxValue < int64(length) {
+		return &ConstraintError{
+			Message: fmt.Sprintf("Must have at most %d items, %d given", *m.MaxValue, length),
+		}
+	}
+	return nil
+}
+
 func (m MapSchema[K, V]) Unserialize(data any) (any, error) {
 	v := reflect.ValueOf(data)
 	if v.Kind() != reflect.Map {
@@ -93,15 +122,8 @@ func (m MapSchema[K, V]) Unserialize(data any) (any, error) {
 		}
 	}
 
-	if m.MinValue != nil && *m.MinValue > int64(v.Len()) {
-		return nil, &ConstraintError{
-			Message: fmt.Sprintf("Must have at least %d items, %d given", *m.MinValue, v.Len()),
-		}
-	}
-	if m.MaxValue != nil && *m.MaxValue < int64(v.Len()) {
-		return nil, &ConstraintError{
-			Message: fmt.Sprintf("Must have at most %d items, %d given", *m.MaxValue, v.Len()),
-		}
+	if err := m.validateLength(v.Len()); err != nil {
+		return nil, err
 	}
 
 	t := m.ReflectedType()
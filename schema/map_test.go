@@ -344,3 +344,14 @@ func TestMapCompatibilityValidation(t *testing.T) {
 	assert.Error(t, s1.ValidateCompatibility(true))
 	assert.Error(t, s1.ValidateCompatibility([]string{}))
 }
+
+func TestMapKeysValues(t *testing.T) {
+	keys := schema.NewStringSchema(nil, nil, nil)
+	values := schema.NewIntSchema(nil, nil, nil)
+	mapKeys, mapValues := schema.MapKeysValues(schema.NewTypedMapSchema[string, int64](keys, values, nil, nil))
+	assert.Equals[schema.Type](t, mapKeys, keys)
+	assert.Equals[schema.Type](t, mapValues, values)
+	mapKeys, mapValues = schema.MapKeysValues(keys)
+	assert.Nil(t, mapKeys)
+	assert.Nil(t, mapValues)
+}
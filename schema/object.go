@@ -124,10 +124,10 @@ func (o *ObjectSchema) unserializeToStruct(rawData map[string]any) (any, error)
 			}()
 			if field.Kind() == reflect.Pointer && v.Kind() != reflect.Pointer {
 				f = reflect.New(f.Type().Elem())
-				f.Elem().Set(v.Convert(f.Elem().Type()))
+				f.Elem().Set(convertValue(v, f.Elem().Type()))
 				field.Set(f)
 			} else {
-				f.Set(v.Convert(f.Type()))
+				f.Set(convertValue(v, f.Type()))
 			}
 		}()
 		if recoveredError != nil {
@@ -148,6 +148,39 @@ func (o *ObjectSchema) unserializeToStruct(rawData map[string]any) (any, error)
 	return result, nil
 }
 
+// convertValue converts the unserialized value to the type of a struct field. Lists and maps are converted item by
+// item, because the schema unserializes for example a list of integers to []int64, which Go cannot convert to []int
+// directly. It panics if the value cannot be converted.
+func convertValue(v reflect.Value, t reflect.Type) reflect.Value {
+	if v.Type().ConvertibleTo(t) {
+		return v.Convert(t)
+	}
+	switch {
+	case v.Kind() == reflect.Slice && t.Kind() == reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(t)
+		}
+		result := reflect.MakeSlice(t, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			result.Index(i).Set(convertValue(v.Index(i), t.Elem()))
+		}
+		return result
+	case v.Kind() == reflect.Map && t.Kind() == reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(t)
+		}
+		result := reflect.MakeMapWithSize(t, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			result.SetMapIndex(convertValue(iter.Key(), t.Key()), convertValue(iter.Value(), t.Elem()))
+		}
+		return result
+	case v.Kind() == reflect.Interface && !v.IsNil():
+		return convertValue(v.Elem(), t)
+	}
+	return v.Convert(t)
+}
+
 func (o *ObjectSchema) serializeMap(data map[string]any) (any, error) {
 	if err := o.validateFieldInterdependencies(data); err != nil {
 		return nil, err
@@ -602,15 +635,24 @@ type TypedObject[T any] interface {
 func NewStructMappedObjectSchema[T any](id string, properties map[string]*PropertySchema) *ObjectSchema {
 	validateObjectIsStruct[T]()
 	var defaultValue T
+	return newStructMappedObjectSchema(id, properties, reflect.TypeOf(&defaultValue).Elem())
+}
+
+// newStructMappedObjectSchema creates an object schema tied to the struct type, or pointer to struct type, given.
+func newStructMappedObjectSchema(
+	id string,
+	properties map[string]*PropertySchema,
+	reflectType reflect.Type,
+) *ObjectSchema {
 	return &ObjectSchema{
 		IDValue:         id,
 		PropertiesValue: properties,
 
 		defaultValues: extractObjectDefaultValues(properties),
 
-		defaultValue:     defaultValue,
-		defaultValueType: reflect.TypeOf(&defaultValue).Elem(),
-		fieldCache:       buildObjectFieldCache[T](properties),
+		defaultValue:     reflect.Zero(reflectType).Interface(),
+		defaultValueType: reflectType,
+		fieldCache:       buildObjectFieldCache(reflectType, properties),
 	}
 }
 
@@ -702,10 +744,11 @@ func extractObjectDefaultValues(properties map[string]*PropertySchema) map[strin
 	return defaultValues
 }
 
-func buildObjectFieldCache[T any](properties map[string]*PropertySchema) map[string]reflect.StructField {
-	var defaultValue T
+func buildObjectFieldCache(
+	reflectType reflect.Type,
+	properties map[string]*PropertySchema,
+) map[string]reflect.StructField {
 	fieldCache := make(map[string]reflect.StructField, len(properties))
-	reflectType := reflect.TypeOf(defaultValue)
 	if reflectType.Kind() == reflect.Pointer {
 		reflectType = reflectType.Elem()
 	}
@@ -0,0 +1,19 @@
+package schema
+
+import "context"
+
+type pluginConfigKey struct{}
+
+// ContextWithPluginConfig returns a copy of the context carrying the unserialized plugin configuration. The plugin
+// package adds it to the context of every step call when the plugin declares a configuration. Step initializers
+// receive it when the step is created with NewCallableStepWithConfig, and step handlers can retrieve it using
+// PluginConfigFromContext.
+func ContextWithPluginConfig(ctx context.Context, config any) context.Context {
+	return context.WithValue(ctx, pluginConfigKey{}, config)
+}
+
+// PluginConfigFromContext returns the unserialized plugin configuration stored in the context, or nil if there is
+// none.
+func PluginConfigFromContext(ctx context.Context) any {
+	return ctx.Value(pluginConfigKey{})
+}
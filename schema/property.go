@@ -21,6 +21,19 @@ type Property interface {
 	Examples() []string
 }
 
+// SecretProperty is a Property that can be marked as holding a credential. It is separate from Property so existing
+// implementations of Property keep working; properties not implementing it are never secret.
+type SecretProperty interface {
+	Property
+	Secret() bool
+}
+
+// IsSecret returns true if the property is marked as holding a credential.
+func IsSecret(p Property) bool {
+	secretProperty, ok := p.(SecretProperty)
+	return ok && secretProperty.Secret()
+}
+
 // NewPropertySchema creates a new object property schema.
 func NewPropertySchema(
 	t Type,
@@ -44,6 +57,7 @@ func NewPropertySchema(
 		false,
 		false,
 		nil,
+		false,
 	}
 }
 
@@ -63,6 +77,9 @@ type PropertySchema struct {
 	Disabled bool `json:"disabled"`
 	// DisabledReason explains why the property is disabled. Default nil
 	DisabledReason *string `json:"disabled_reason"`
+	// SecretValue marks the property as holding a credential. Secret properties may be passed to a step
+	// separately from the input, see Secrets.
+	SecretValue bool `json:"secret"`
 }
 
 // TreatEmptyAsDefaultValue triggers the property to treat an empty value (e.g. "", or 0) as the default value for
@@ -82,6 +99,12 @@ func (p *PropertySchema) Disable(reason string) *PropertySchema {
 	return p
 }
 
+// MarkSecret is a builder-pattern way of marking the property as holding a credential.
+func (p *PropertySchema) MarkSecret() *PropertySchema {
+	p.SecretValue = true
+	return p
+}
+
 func (p *PropertySchema) Default() *string {
 	return p.DefaultValue
 }
@@ -122,6 +145,10 @@ func (p *PropertySchema) Examples() []string {
 	return p.ExamplesValue
 }
 
+func (p *PropertySchema) Secret() bool {
+	return p.SecretValue
+}
+
 func (p *PropertySchema) ApplyScope(scope Scope) {
 	p.TypeValue.ApplyScope(scope)
 }
@@ -3,6 +3,7 @@ package schema
 import (
 	"context"
 	"fmt"
+	"time"
 )
 
 // Schema is a collection of steps supported by a plugin.
@@ -80,6 +81,23 @@ func (s CallableSchema) CallStep(
 	outputID string,
 	serializedOutputData any,
 	err error,
+) {
+	return s.CallStepWithSecrets(ctx, stepID, serializedInputData, nil)
+}
+
+// CallStepWithSecrets calls a step like CallStep, but also passes secrets separately from the input. The secrets
+// must belong to root input properties marked as secret. They are never added to the input, the input is checked as
+// if the properties passed as secrets did not exist, and the step reads them using SecretsFromContext. Errors never
+// include the secret values.
+func (s CallableSchema) CallStepWithSecrets(
+	ctx context.Context,
+	stepID string,
+	serializedInputData any,
+	serializedSecrets Secrets,
+) (
+	outputID string,
+	serializedOutputData any,
+	err error,
 ) {
 	step, ok := s.StepsValue[stepID]
 	if !ok {
@@ -87,7 +105,19 @@ func (s CallableSchema) CallStep(
 			Message: fmt.Sprintf("Invalid step called: %s", stepID),
 		}
 	}
-	unserializedInputData, err := step.Input().Unserialize(serializedInputData)
+	timings := callTimingsFromContext(ctx)
+	start := time.Now()
+	input := step.Input()
+	if len(serializedSecrets) > 0 {
+		secrets, err := unserializeSecrets(input, serializedInputData, serializedSecrets)
+		if err != nil {
+			return "", nil, InvalidInputError{err}
+		}
+		ctx = ContextWithSecrets(ctx, secrets)
+		input = withoutSecrets(input, secrets)
+	}
+	unserializedInputData, err := input.Unserialize(serializedInputData)
+	timings.InputUnserialize = time.Since(start)
 	if err != nil {
 		return "", nil, InvalidInputError{err}
 	}
@@ -96,7 +126,9 @@ func (s CallableSchema) CallStep(
 		return outputID, nil, err
 	}
 	output := step.Outputs()[outputID]
+	start = time.Now()
 	serializedData, err := output.Schema().Serialize(unserializedOutput)
+	timings.OutputSerialize = time.Since(start)
 	if err != nil {
 		return "", nil, InvalidOutputError{err}
 	}
@@ -118,7 +150,13 @@ func (s CallableSchema) CallSignal(
 			Message: fmt.Sprintf("Invalid step called: %s", stepID),
 		}
 	}
-	unserializedInputData, err := step.SignalHandlers()[signalID].DataSchema().Unserialize(serializedInputData)
+	signal, ok := step.SignalHandlers()[signalID]
+	if !ok {
+		return BadArgumentError{
+			Message: fmt.Sprintf("Invalid signal called: %s (step %s)", signalID, stepID),
+		}
+	}
+	unserializedInputData, err := signal.DataSchema().Unserialize(serializedInputData)
 	if err != nil {
 		return InvalidInputError{err}
 	}
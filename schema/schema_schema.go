@@ -781,6 +781,20 @@ var basicObjects = []*ObjectSchema{
 				nil,
 				nil,
 			),
+			"secret": NewPropertySchema(
+				NewBoolSchema(),
+				NewDisplayValue(
+					PointerTo("Secret"),
+					PointerTo("Whether the field holds a credential that may be passed separately from the input."),
+					nil,
+				),
+				false,
+				nil,
+				nil,
+				nil,
+				PointerTo("false"),
+				nil,
+			).TreatEmptyAsDefaultValue(),
 		},
 	),
 	NewStructMappedObjectSchema[*RefSchema](
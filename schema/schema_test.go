@@ -2,6 +2,7 @@ package schema_test
 
 import (
 	"context"
+	"errors"
 	"go.arcalot.io/assert"
 	"testing"
 
@@ -24,3 +25,14 @@ func TestSchemaCall(t *testing.T) {
 	typedData := outputData.(map[string]any)
 	assert.Equals(t, typedData["message"].(string), "Hello, Arca Lot!")
 }
+
+func TestSchemaCallSignal_UnknownSignal(t *testing.T) {
+	// A client may send any signal ID, so undeclared signals must be rejected rather than crash the plugin.
+	err := schemaTestSchema.CallSignal(context.Background(), "hello", "wave", map[string]any{})
+	assert.Error(t, err)
+	var badArgument schema.BadArgumentError
+	assert.Equals(t, errors.As(err, &badArgument), true)
+
+	err = schemaTestSchema.CallSignal(context.Background(), "wave", "wave", map[string]any{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,125 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Secrets holds credentials passed to a step separately from its input, keyed by the name of the root input property
+// they belong to. Only properties marked as secret in the step input schema may be passed this way.
+//
+// Printing Secrets with any of the fmt verbs only shows the property names, never the values, so they can't leak
+// into logs by accident.
+type Secrets map[string]any
+
+// Format implements fmt.Formatter to redact the secret values.
+func (s Secrets) Format(f fmt.State, _ rune) {
+	_, _ = f.Write([]byte(s.redacted()))
+}
+
+// String returns the property names with the values redacted.
+func (s Secrets) String() string {
+	return s.redacted()
+}
+
+func (s Secrets) redacted() string {
+	keys := make([]string, 0, len(s))
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		keys[i] = key + ":<redacted>"
+	}
+	return "Secrets[" + strings.Join(keys, " ") + "]"
+}
+
+type secretsKey struct{}
+
+// ContextWithSecrets returns a copy of the context carrying the specified secrets. Step handlers can retrieve them
+// using SecretsFromContext.
+func ContextWithSecrets(ctx context.Context, secrets Secrets) context.Context {
+	return context.WithValue(ctx, secretsKey{}, secrets)
+}
+
+// SecretsFromContext returns the unserialized secrets passed to the current step. It returns nil if the step was
+// called without secrets.
+func SecretsFromContext(ctx context.Context) Secrets {
+	secrets, _ := ctx.Value(secretsKey{}).(Secrets)
+	return secrets
+}
+
+// unserializeSecrets validates the secrets against the secret properties of the root object of the input and
+// returns their unserialized form. Secrets must not also be present in the serialized input data. The returned errors
+// never contain the secret values.
+func unserializeSecrets(input Scope, serializedInputData any, secrets Secrets) (Secrets, error) {
+	var inputKeys map[string]bool
+	if value := reflect.ValueOf(serializedInputData); value.Kind() == reflect.Map {
+		inputKeys = make(map[string]bool, value.Len())
+		for _, key := range value.MapKeys() {
+			if keyString, ok := key.Interface().(string); ok {
+				inputKeys[keyString] = true
+			}
+		}
+	}
+	result := make(Secrets, len(secrets))
+	properties := input.Properties()
+	for name, value := range secrets {
+		property, ok := properties[name]
+		if !ok || !IsSecret(property) {
+			return nil, &ConstraintError{
+				Message: "this property is not marked as secret and cannot be passed as a secret",
+				Path:    []string{name},
+			}
+		}
+		if inputKeys[name] {
+			return nil, &ConstraintError{
+				Message: "this property was passed both as input and as a secret",
+				Path:    []string{name},
+			}
+		}
+		unserializedValue, err := property.Unserialize(value)
+		if err != nil {
+			// The cause is deliberately dropped as it may contain the secret value.
+			return nil, &ConstraintError{
+				Message: "invalid secret value (details redacted)",
+				Path:    []string{name},
+			}
+		}
+		result[name] = unserializedValue
+	}
+	return result, nil
+}
+
+// withoutSecrets returns a copy of the input scope whose root object lacks the properties passed as secrets, so the
+// input is unserialized and validated without them. The input is returned unchanged if there are no secrets.
+func withoutSecrets(input Scope, secrets Secrets) Scope {
+	if len(secrets) == 0 {
+		return input
+	}
+	root := input.Objects()[input.Root()]
+	properties := make(map[string]*PropertySchema, len(root.PropertiesValue))
+	for name, property := range root.PropertiesValue {
+		if _, ok := secrets[name]; !ok {
+			properties[name] = property
+		}
+	}
+	var strippedRoot *ObjectSchema
+	if root.fieldCache != nil {
+		strippedRoot = newStructMappedObjectSchema(root.IDValue, properties, root.defaultValueType)
+	} else {
+		strippedRoot = NewObjectSchema(root.IDValue, properties)
+	}
+	objects := make(map[string]*ObjectSchema, len(input.Objects()))
+	for id, object := range input.Objects() {
+		objects[id] = object
+	}
+	objects[input.Root()] = strippedRoot
+	return &ScopeSchema{
+		ObjectsValue: objects,
+		RootValue:    input.Root(),
+	}
+}
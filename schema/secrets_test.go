@@ -0,0 +1,180 @@
+package schema_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+type secretsTestInput struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+var secretsTestSchema = schema.NewCallableSchema(
+	schema.NewCallableStep[secretsTestInput](
+		"login",
+		schema.NewScopeSchema(
+			schema.NewStructMappedObjectSchema[secretsTestInput](
+				"Input",
+				map[string]*schema.PropertySchema{
+					"username": schema.NewPropertySchema(
+						schema.NewStringSchema(nil, nil, nil),
+						nil,
+						true,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+					),
+					"password": schema.NewPropertySchema(
+						schema.NewStringSchema(schema.IntPointer(8), nil, nil),
+						nil,
+						true,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+					).MarkSecret(),
+				},
+			),
+		),
+		map[string]*schema.StepOutputSchema{
+			"success": schema.NewStepOutputSchema(
+				schema.NewScopeSchema(
+					schema.NewObjectSchema(
+						"Output",
+						map[string]*schema.PropertySchema{
+							"password_from_input": schema.NewPropertySchema(
+								schema.NewStringSchema(nil, nil, nil),
+								nil,
+								true,
+								nil,
+								nil,
+								nil,
+								nil,
+								nil,
+							),
+							"password_from_context": schema.NewPropertySchema(
+								schema.NewStringSchema(nil, nil, nil),
+								nil,
+								true,
+								nil,
+								nil,
+								nil,
+								nil,
+								nil,
+							),
+						},
+					),
+				),
+				nil,
+				false,
+			),
+		},
+		nil,
+		func(ctx context.Context, input secretsTestInput) (string, any) {
+			return "success", map[string]any{
+				"password_from_input":   input.Password,
+				"password_from_context": schema.SecretsFromContext(ctx)["password"],
+			}
+		},
+	),
+)
+
+func TestSecretsRedaction(t *testing.T) {
+	secrets := schema.Secrets{"password": "hunter2hunter2", "token": "abcdefgh"}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q"} {
+		formatted := fmt.Sprintf(format, secrets)
+		assert.Equals(t, formatted, "Secrets[password:<redacted> token:<redacted>]")
+	}
+	assert.Equals(t, strings.Contains(secrets.String(), "hunter2"), false)
+}
+
+func TestCallStepWithSecrets(t *testing.T) {
+	assert.Equals(t, len(schema.SecretsFromContext(context.Background())), 0)
+
+	outputID, outputData, err := secretsTestSchema.CallStepWithSecrets(
+		context.Background(),
+		"login",
+		map[string]any{"username": "arcalot"},
+		schema.Secrets{"password": "hunter2hunter2"},
+	)
+	assert.NoError(t, err)
+	assert.Equals(t, outputID, "success")
+	// The secret only reaches the step through the context, never through the input.
+	assert.Equals(t, outputData.(map[string]any)["password_from_input"].(string), "")
+	assert.Equals(t, outputData.(map[string]any)["password_from_context"].(string), "hunter2hunter2")
+}
+
+func TestCallStepWithSecrets_Errors(t *testing.T) {
+	testCases := map[string]struct {
+		input   map[string]any
+		secrets schema.Secrets
+	}{
+		"not-secret": {
+			map[string]any{},
+			schema.Secrets{"username": "hunter2hunter2", "password": "hunter2hunter2"},
+		},
+		"unknown": {
+			map[string]any{"username": "arcalot"},
+			schema.Secrets{"hunter2hunter2": "hunter2hunter2"},
+		},
+		"invalid": {
+			map[string]any{"username": "arcalot"},
+			schema.Secrets{"password": "hunter2"},
+		},
+		"duplicate": {
+			map[string]any{"username": "arcalot", "password": "hunter2hunter2"},
+			schema.Secrets{"password": "hunter2hunter2"},
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := secretsTestSchema.CallStepWithSecrets(
+				context.Background(),
+				"login",
+				testCase.input,
+				testCase.secrets,
+			)
+			assert.Error(t, err)
+			assert.InstanceOf[schema.InvalidInputError](t, err)
+			if name != "unknown" {
+				assert.Equals(t, strings.Contains(err.Error(), "hunter2"), false)
+			}
+		})
+	}
+}
+
+func TestSecretPropertySerialization(t *testing.T) {
+	serializedSchema, err := secretsTestSchema.SelfSerialize()
+	assert.NoError(t, err)
+	properties := lookupSerialized(serializedSchema, "steps", "login", "input", "objects", "Input", "properties")
+	assert.Equals(t, lookupSerialized(properties, "password", "secret"), any(true))
+	assert.Nil(t, lookupSerialized(properties, "username", "secret"))
+
+	unserializedSchema, err := schema.UnserializeSchema(serializedSchema)
+	assert.NoError(t, err)
+	inputProperties := unserializedSchema.Steps()["login"].Input().Properties()
+	assert.Equals(t, schema.IsSecret(inputProperties["password"]), true)
+	assert.Equals(t, schema.IsSecret(inputProperties["username"]), false)
+}
+
+// lookupSerialized walks serialized data along the given map keys. It returns nil if a key is missing.
+func lookupSerialized(data any, keys ...string) any {
+	for _, key := range keys {
+		value := reflect.ValueOf(data).MapIndex(reflect.ValueOf(key))
+		if !value.IsValid() {
+			return nil
+		}
+		data = value.Interface()
+	}
+	return data
+}
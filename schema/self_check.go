@@ -0,0 +1,294 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Problem is a mistake in a schema definition found by CheckCallableSchema or CheckScope.
+type Problem struct {
+	// Path locates the problem, for example the step, the object and the property ID.
+	Path []string
+	// Message describes the problem.
+	Message string
+}
+
+func (p Problem) Error() string {
+	return strings.Join(p.Path, " -> ") + ": " + p.Message
+}
+
+// CheckCallableSchema statically verifies a callable schema. It checks that the steps and signals are stored under
+// their own ID, and checks the input, output and signal scopes with CheckScope. Mistakes found this way otherwise only
+// surface as panics or confusing constraint errors when a step is called.
+func CheckCallableSchema(s *CallableSchema) []Problem {
+	c := &selfCheck{}
+	for _, stepID := range sortedStringKeys(s.StepsValue) {
+		step := s.StepsValue[stepID]
+		path := []string{"step " + stepID}
+		if step.ID() != stepID {
+			c.add(path, "the step is registered under %s, but its ID is %s", stepID, step.ID())
+		}
+		c.checkScope(append(path, "input"), step.Input())
+		outputs := step.Outputs()
+		for _, outputID := range sortedStringKeys(outputs) {
+			c.checkScope(append(path, "output "+outputID), outputs[outputID].Schema())
+		}
+		c.checkSignals(append(path, "signal handler"), step.SignalHandlers())
+		c.checkSignals(append(path, "signal emitter"), step.SignalEmitters())
+	}
+	return c.problems
+}
+
+// CheckScope statically verifies a scope. It checks that all references resolve to an object of the scope, that
+// struct-mapped objects match the fields of their Go types, that RequiredIf, RequiredIfNot and Conflicts name
+// existing properties, and that defaults and examples unserialize.
+func CheckScope(scope Scope) []Problem {
+	c := &selfCheck{}
+	c.checkScope(nil, scope)
+	return c.problems
+}
+
+type selfCheck struct {
+	problems []Problem
+}
+
+func (c *selfCheck) add(path []string, format string, args ...any) {
+	c.problems = append(c.problems, Problem{
+		Path:    append([]string{}, path...),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *selfCheck) checkSignals(path []string, signals map[string]*SignalSchema) {
+	for _, signalID := range sortedStringKeys(signals) {
+		signal := signals[signalID]
+		signalPath := append(path[:len(path):len(path)], signalID)
+		if signal.ID() != signalID {
+			c.add(signalPath, "the signal is registered under %s, but its ID is %s", signalID, signal.ID())
+		}
+		c.checkScope(signalPath, signal.DataSchema())
+	}
+}
+
+func (c *selfCheck) checkScope(path []string, scope Scope) {
+	path = path[:len(path):len(path)]
+	objects := scope.Objects()
+	if _, ok := objects[scope.Root()]; !ok {
+		c.add(path, "the root object %s is not part of the scope", scope.Root())
+	}
+	for _, objectID := range sortedStringKeys(objects) {
+		object := objects[objectID]
+		objectPath := append(path, "object "+objectID)
+		if object.ID() != objectID {
+			c.add(objectPath, "the object is registered under %s, but its ID is %s", objectID, object.ID())
+		}
+		c.checkObject(objectPath, object, scope)
+	}
+}
+
+func (c *selfCheck) checkObject(path []string, object *ObjectSchema, scope Scope) {
+	properties := object.PropertiesValue
+	for _, propertyID := range sortedStringKeys(properties) {
+		property := properties[propertyID]
+		propertyPath := append(path[:len(path):len(path)], propertyID)
+		c.checkType(propertyPath, property.TypeValue, scope)
+		c.checkPropertyNames(propertyPath, "RequiredIf", property.RequiredIfValue, properties)
+		c.checkPropertyNames(propertyPath, "RequiredIfNot", property.RequiredIfNotValue, properties)
+		c.checkPropertyNames(propertyPath, "Conflicts", property.ConflictsValue, properties)
+		if property.DefaultValue != nil {
+			if err := checkSerializedValue(property.TypeValue, *property.DefaultValue); err != nil {
+				c.add(propertyPath, "the default value %s is invalid (%v)", *property.DefaultValue, err)
+			}
+		}
+		for _, example := range property.ExamplesValue {
+			if err := checkSerializedValue(property.TypeValue, example); err != nil {
+				c.add(propertyPath, "the example %s is invalid (%v)", example, err)
+			}
+		}
+		if object.fieldCache != nil {
+			c.checkField(propertyPath, object.fieldCache[propertyID], property)
+		}
+	}
+}
+
+func (c *selfCheck) checkPropertyNames(
+	path []string,
+	what string,
+	names []string,
+	properties map[string]*PropertySchema,
+) {
+	for _, name := range names {
+		if _, ok := properties[name]; !ok {
+			c.add(path, "%s names %s, which is not a property of the object", what, name)
+		}
+	}
+}
+
+// checkField verifies that the unserialized values of the property can be set on the struct field, the same way
+// ObjectSchema.Unserialize sets them.
+func (c *selfCheck) checkField(path []string, field reflect.StructField, property *PropertySchema) {
+	valueType, err := reflectedType(property.TypeValue)
+	if err != nil {
+		// The reference problem is reported already.
+		return
+	}
+	fieldType := field.Type
+	if compatibleFieldType(property.TypeValue, valueType, fieldType) ||
+		(fieldType.Kind() == reflect.Pointer && compatibleFieldType(property.TypeValue, valueType, fieldType.Elem())) {
+		return
+	}
+	c.add(path, "the value of type %s cannot be stored in the struct field %s of type %s",
+		valueType, field.Name, fieldType)
+}
+
+// compatibleFieldType returns true if values of the value type can be converted to the field type without changing
+// their meaning. Go converts integers to strings as runes, which is never what the schema intends. Numbers are only
+// compatible if every value the type t accepts fits the field. Lists and maps are compatible if their items are,
+// because ObjectSchema.Unserialize converts them item by item.
+func compatibleFieldType(t Type, valueType reflect.Type, fieldType reflect.Type) bool {
+	switch {
+	case valueType == fieldType:
+		return true
+	case valueType.Kind() == reflect.Slice && fieldType.Kind() == reflect.Slice:
+		return compatibleFieldType(ListItems(t), valueType.Elem(), fieldType.Elem())
+	case valueType.Kind() == reflect.Map && fieldType.Kind() == reflect.Map:
+		keys, values := MapKeysValues(t)
+		return compatibleFieldType(keys, valueType.Key(), fieldType.Key()) &&
+			compatibleFieldType(values, valueType.Elem(), fieldType.Elem())
+	}
+	if !valueType.ConvertibleTo(fieldType) {
+		return false
+	}
+	if isNumeric(valueType.Kind()) && isNumeric(fieldType.Kind()) {
+		return numberFits(t, valueType, fieldType)
+	}
+	return valueType.Kind() == fieldType.Kind() || fieldType.Kind() == reflect.Interface
+}
+
+func isNumeric(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Float64
+}
+
+func isFloat(kind reflect.Kind) bool {
+	return kind == reflect.Float32 || kind == reflect.Float64
+}
+
+// numberFits returns true if every number of the type t fits the numeric field type. Integers and floats are never
+// mixed, as floats lose the precision of large integers and integers the fractions of floats.
+func numberFits(t Type, valueType reflect.Type, fieldType reflect.Type) bool {
+	switch {
+	case valueType.Kind() == fieldType.Kind():
+		return true
+	case isFloat(valueType.Kind()) && isFloat(fieldType.Kind()):
+		if fieldType.Kind() == reflect.Float64 {
+			return true
+		}
+		float, ok := t.(Float)
+		return ok && float.Min() != nil && float.Max() != nil &&
+			*float.Min() >= -math.MaxFloat32 && *float.Max() <= math.MaxFloat32
+	case isFloat(valueType.Kind()) || isFloat(fieldType.Kind()):
+		return false
+	}
+	valueMin, valueMax := intKindRange(valueType)
+	switch typedT := t.(type) {
+	case Int:
+		if typedT.Min() != nil {
+			valueMin = *typedT.Min()
+		}
+		if typedT.Max() != nil {
+			valueMax = *typedT.Max()
+		}
+	case Enum[int64]:
+		valueMin, valueMax = math.MaxInt64, math.MinInt64
+		for value := range typedT.ValidValues() {
+			if value < valueMin {
+				valueMin = value
+			}
+			if value > valueMax {
+				valueMax = value
+			}
+		}
+	}
+	fieldMin, fieldMax := intKindRange(fieldType)
+	return valueMin >= fieldMin && valueMax <= fieldMax
+}
+
+func (c *selfCheck) checkType(path []string, t Type, scope Scope) {
+	switch typedT := t.(type) {
+	case *RefSchema:
+		referencedObject, ok := scope.Objects()[typedT.IDValue]
+		switch {
+		case !ok:
+			c.add(path, "the reference to %s does not resolve, the scope has no such object", typedT.IDValue)
+		case typedT.referencedObjectCache == nil:
+			c.add(path, "the reference to %s was never resolved, it is not part of a scope", typedT.IDValue)
+		case typedT.referencedObjectCache != Object(referencedObject):
+			c.add(path, "the reference to %s resolves to an object of another scope", typedT.IDValue)
+		}
+	case *ScopeSchema:
+		c.checkScope(path, typedT)
+	case *ObjectSchema:
+		c.checkObject(path, typedT, scope)
+	case OneOf[int64]:
+		types := typedT.Types()
+		keys := make([]int64, 0, len(types))
+		for key := range types {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		for _, key := range keys {
+			c.checkType(append(path[:len(path):len(path)], fmt.Sprintf("%d", key)), types[key], scope)
+		}
+	case OneOf[string]:
+		for _, key := range sortedStringKeys(typedT.Types()) {
+			c.checkType(append(path[:len(path):len(path)], key), typedT.Types()[key], scope)
+		}
+	default:
+		if items := ListItems(t); items != nil {
+			c.checkType(append(path[:len(path):len(path)], "items"), items, scope)
+		}
+		if keys, values := MapKeysValues(t); keys != nil {
+			c.checkType(append(path[:len(path):len(path)], "keys"), keys, scope)
+			c.checkType(append(path[:len(path):len(path)], "values"), values, scope)
+		}
+	}
+}
+
+// checkSerializedValue checks that the JSON-encoded value unserializes with the type.
+func checkSerializedValue(t Type, value string) (err error) {
+	var decoded any
+	if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+		return fmt.Errorf("not valid JSON: %w", err)
+	}
+	defer func() {
+		// Unresolved references panic, they are reported separately.
+		if e := recover(); e != nil {
+			err = fmt.Errorf("%v", e)
+		}
+	}()
+	_, err = t.Unserialize(decoded)
+	return err
+}
+
+// reflectedType returns the reflected type, or an error if the type contains an unresolved reference.
+func reflectedType(t Type) (result reflect.Type, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("%v", e)
+		}
+	}()
+	return t.ReflectedType(), nil
+}
+
+func sortedStringKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
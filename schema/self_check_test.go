@@ -0,0 +1,212 @@
+package schema_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+type selfCheckInput struct {
+	Name  string `json:"name"`
+	Count string `json:"count"`
+}
+
+func TestCheckCallableSchema_Valid(t *testing.T) {
+	assert.Equals(t, len(schema.CheckCallableSchema(schema.NewCallableSchema(testStepSchema))), 0)
+}
+
+func TestCheckCallableSchema(t *testing.T) {
+	inputSchema := schema.NewScopeSchema(
+		schema.NewStructMappedObjectSchema[selfCheckInput](
+			"input",
+			map[string]*schema.PropertySchema{
+				"name": schema.NewPropertySchema(
+					schema.NewStringSchema(schema.IntPointer(1), nil, nil),
+					nil,
+					false,
+					[]string{"nonexistent"},
+					nil,
+					[]string{"count", "other"},
+					schema.PointerTo(`""`),
+					[]string{`"Arca Lot"`, `{}`},
+				),
+				"count": schema.NewPropertySchema(
+					schema.NewIntSchema(nil, nil, nil),
+					nil,
+					false,
+					nil,
+					[]string{"missing"},
+					nil,
+					nil,
+					nil,
+				),
+			},
+		),
+	)
+	step := schema.NewCallableStepWithSignals[any, selfCheckInput](
+		"greet",
+		inputSchema,
+		map[string]*schema.StepOutputSchema{
+			"success": schema.NewStepOutputSchema(
+				// The reference is never resolved as the scope is not created with NewScopeSchema.
+				&schema.ScopeSchema{
+					ObjectsValue: map[string]*schema.ObjectSchema{
+						"output": schema.NewObjectSchema("output", map[string]*schema.PropertySchema{
+							"details": schema.NewPropertySchema(
+								schema.NewListSchema(schema.NewRefSchema("details", nil), nil, nil),
+								nil,
+								true,
+								nil,
+								nil,
+								nil,
+								nil,
+								nil,
+							),
+						}),
+					},
+					RootValue: "output",
+				},
+				nil,
+				false,
+			),
+		},
+		nil,
+		map[string]*schema.SignalSchema{
+			"ready": schema.NewSignalSchema(
+				"started",
+				schema.NewScopeSchema(schema.NewObjectSchema("ready", map[string]*schema.PropertySchema{})),
+				nil,
+			),
+		},
+		nil,
+		nil,
+		func(_ context.Context, _ any, _ selfCheckInput) (string, any) {
+			return "success", map[string]any{}
+		},
+	)
+	problems := schema.CheckCallableSchema(&schema.CallableSchema{
+		StepsValue: map[string]schema.CallableStep{"hello": step},
+	})
+	messages := make([]string, len(problems))
+	for i, problem := range problems {
+		messages[i] = problem.Error()
+	}
+	assert.Equals(t, messages, []string{
+		"step hello: the step is registered under hello, but its ID is greet",
+		"step hello -> input -> object input -> count: RequiredIfNot names missing, which is not a property of " +
+			"the object",
+		"step hello -> input -> object input -> count: the value of type int64 cannot be stored in the struct " +
+			"field Count of type string",
+		"step hello -> input -> object input -> name: RequiredIf names nonexistent, which is not a property of " +
+			"the object",
+		"step hello -> input -> object input -> name: Conflicts names other, which is not a property of the object",
+		"step hello -> input -> object input -> name: the default value \"\" is invalid (Validation failed: " +
+			"String must be at least 1 characters, 0 given)",
+		"step hello -> input -> object input -> name: the example {} is invalid (map[string]interface {} cannot be " +
+			"converted to a string)",
+		"step hello -> output success -> object output -> details -> items: the reference to details does not " +
+			"resolve, the scope has no such object",
+		"step hello -> signal emitter -> ready: the signal is registered under ready, but its ID is started",
+	})
+}
+
+func TestCheckScope_NestedTypes(t *testing.T) {
+	// Every option has a default value that does not match the type of the property.
+	option := func(id string) *schema.ObjectSchema {
+		return schema.NewObjectSchema(id, map[string]*schema.PropertySchema{
+			"size": schema.NewPropertySchema(
+				schema.NewIntSchema(nil, nil, nil),
+				nil,
+				false,
+				nil,
+				nil,
+				nil,
+				schema.PointerTo(`"large"`),
+				nil,
+			),
+		})
+	}
+	scope := schema.NewScopeSchema(
+		schema.NewObjectSchema("root", map[string]*schema.PropertySchema{
+			"by_name": schema.NewPropertySchema(
+				schema.NewListSchema(
+					schema.NewOneOfStringSchema[any](map[string]schema.Object{"a": option("a")}, "kind"),
+					nil,
+					nil,
+				),
+				nil,
+				false,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+			"by_id": schema.NewPropertySchema(
+				schema.NewMapSchema(
+					schema.NewStringSchema(nil, nil, nil),
+					schema.NewOneOfIntSchema[any](map[int64]schema.Object{2: option("b"), 1: option("c")}, "kind"),
+					nil,
+					nil,
+				),
+				nil,
+				false,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+		}),
+	)
+	problems := schema.CheckScope(scope)
+	paths := make([]string, len(problems))
+	for i, problem := range problems {
+		paths[i] = strings.Join(problem.Path, " -> ")
+	}
+	assert.Equals(t, paths, []string{
+		"object root -> by_id -> values -> 1 -> size",
+		"object root -> by_id -> values -> 2 -> size",
+		"object root -> by_name -> items -> a -> size",
+	})
+}
+
+type selfCheckNumbers struct {
+	Level  uint8   `json:"level"`
+	Count  uint8   `json:"count"`
+	Ratio  int     `json:"ratio"`
+	Counts []int16 `json:"counts"`
+}
+
+func TestCheckScope_NumberFields(t *testing.T) {
+	property := func(t schema.Type) *schema.PropertySchema {
+		return schema.NewPropertySchema(t, nil, false, nil, nil, nil, nil, nil)
+	}
+	scope := schema.NewScopeSchema(
+		schema.NewStructMappedObjectSchema[selfCheckNumbers]("numbers", map[string]*schema.PropertySchema{
+			// The bounds fit the field, so no value can wrap around.
+			"level": property(schema.NewIntSchema(schema.IntPointer(0), schema.IntPointer(255), nil)),
+			"count": property(schema.NewIntSchema(nil, nil, nil)),
+			"ratio": property(schema.NewFloatSchema(nil, nil, nil)),
+			"counts": property(schema.NewListSchema(
+				schema.NewIntSchema(schema.IntPointer(0), schema.IntPointer(40000), nil),
+				nil,
+				nil,
+			)),
+		}),
+	)
+	problems := schema.CheckScope(scope)
+	messages := make([]string, len(problems))
+	for i, problem := range problems {
+		messages[i] = problem.Error()
+	}
+	assert.Equals(t, messages, []string{
+		"object numbers -> count: the value of type int64 cannot be stored in the struct field Count of type uint8",
+		"object numbers -> counts: the value of type []int64 cannot be stored in the struct field Counts of type " +
+			"[]int16",
+		"object numbers -> ratio: the value of type float64 cannot be stored in the struct field Ratio of type int",
+	})
+}
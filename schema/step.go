@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 )
 
 // Step holds the definition for a single step, it's input and output definitions.
@@ -112,6 +113,81 @@ func NewCallableStepWithSignals[StepData any, StepInputType any](
 	display Display,
 	initializer func() StepData,
 	handler func(context.Context, StepData, StepInputType) (string, any),
+) CallableStep {
+	var contextInitializer func(context.Context) (StepData, error)
+	if initializer != nil {
+		contextInitializer = func(context.Context) (StepData, error) {
+			return initializer(), nil
+		}
+	}
+	return newCallableStepWithSignals(
+		id,
+		input,
+		outputs,
+		signalHandlers,
+		signalEmitters,
+		display,
+		contextInitializer,
+		handler,
+	)
+}
+
+// NewCallableStepWithConfig creates a callable step definition like NewCallableStepWithSignals, but passes the plugin
+// configuration to the initializer of the step data. The configuration is taken from the context of the call that
+// creates the step data, see ContextWithPluginConfig. That is the first call of each execution if the step is called
+// with an execution context, see ContextWithExecution, and the first call of the step otherwise, in which case later
+// changes to the configuration are not seen by the step data. Calls fail if the context holds no configuration of
+// type ConfigType.
+func NewCallableStepWithConfig[ConfigType any, StepData any, StepInputType any](
+	id string,
+	input *ScopeSchema,
+	outputs map[string]*StepOutputSchema,
+	signalHandlers map[string]CallableSignal,
+	signalEmitters map[string]*SignalSchema,
+	display Display,
+	initializer func(ConfigType) StepData,
+	handler func(context.Context, StepData, StepInputType) (string, any),
+) CallableStep {
+	var contextInitializer func(context.Context) (StepData, error)
+	if initializer != nil {
+		contextInitializer = func(ctx context.Context) (StepData, error) {
+			config, ok := PluginConfigFromContext(ctx).(ConfigType)
+			if !ok {
+				var stepData StepData
+				var expected ConfigType
+				return stepData, IllegalStateError{
+					fmt.Errorf(
+						"step %s requires a plugin configuration of type %T, but the context holds %T",
+						id,
+						expected,
+						PluginConfigFromContext(ctx),
+					),
+				}
+			}
+			return initializer(config), nil
+		}
+	}
+	return newCallableStepWithSignals(
+		id,
+		input,
+		outputs,
+		signalHandlers,
+		signalEmitters,
+		display,
+		contextInitializer,
+		handler,
+	)
+}
+
+func newCallableStepWithSignals[StepData any, StepInputType any](
+	id string,
+	input *ScopeSchema,
+	outputs map[string]*StepOutputSchema,
+	signalHandlers map[string]CallableSignal,
+	signalEmitters map[string]*SignalSchema,
+	display Display,
+	initializer func(context.Context) (StepData, error),
+	handler func(context.Context, StepData, StepInputType) (string, any),
 ) CallableStep {
 	wg := sync.WaitGroup{}
 	if initializer != nil {
@@ -138,7 +214,7 @@ type CallableStepSchema[StepData any, InputType any] struct {
 	SignalEmittersValue map[string]*SignalSchema     `json:"signal_emitters"`
 	OutputsValue        map[string]*StepOutputSchema `json:"outputs"`
 	DisplayValue        Display                      `json:"display"`
-	initializer         func() StepData
+	initializer         func(context.Context) (StepData, error)
 	initializerWG       *sync.WaitGroup
 	initializerMutex    sync.Mutex
 	initializedData     *StepData
@@ -189,32 +265,71 @@ func (s *CallableStepSchema[StepData, InputType]) ToStepSchema() *StepSchema {
 }
 
 func (s *CallableStepSchema[StepData, InputType]) Call(ctx context.Context, input any) (string, any, error) {
-	if err := s.InputValue.Validate(input); err != nil {
+	timings := callTimingsFromContext(ctx)
+	start := time.Now()
+	// Properties passed as secrets are not part of the input.
+	err := withoutSecrets(s.InputValue, SecretsFromContext(ctx)).Validate(input)
+	timings.InputValidate = time.Since(start)
+	if err != nil {
 		return "", nil, InvalidInputError{err}
 	}
 
-	s.initializerMutex.Lock()
-	if s.initializedData == nil && s.initializer != nil {
-		newInitializedData := s.initializer()
-		s.initializedData = &newInitializedData
-		s.initializerWG.Done()
-	}
-	s.initializerMutex.Unlock()
-	var stepData StepData
-	if s.initializer != nil {
-		stepData = *s.initializedData
+	start = time.Now()
+	stepData, err := s.stepData(ctx)
+	if err != nil {
+		return "", nil, err
 	}
 	outputID, outputData := s.handler(ctx, stepData, input.(InputType))
+	timings.Handler = time.Since(start)
 	output, ok := s.OutputsValue[outputID]
 	if !ok {
 		return "", nil, InvalidOutputError{
 			fmt.Errorf("undeclared output ID: %s", outputID),
 		}
 	}
-	return outputID, outputData, output.Validate(outputData)
+	start = time.Now()
+	err = output.Validate(outputData)
+	timings.OutputValidate = time.Since(start)
+	return outputID, outputData, err
+}
+
+// stepData returns the step data for the execution in the context, creating it if needed. Without an execution
+// context, the step data is created on the first call and shared by all later calls.
+func (s *CallableStepSchema[StepData, InputType]) stepData(ctx context.Context) (StepData, error) {
+	var stepData StepData
+	if s.initializer == nil {
+		return stepData, nil
+	}
+	if exec := executionFromContext(ctx); exec != nil {
+		data, err := exec.initialize(s, func() (any, error) {
+			return s.initializer(ctx)
+		})
+		if err != nil {
+			return stepData, err
+		}
+		return data.(StepData), nil
+	}
+	s.initializerMutex.Lock()
+	defer s.initializerMutex.Unlock()
+	if s.initializedData == nil {
+		newInitializedData, err := s.initializer(ctx)
+		if err != nil {
+			return stepData, err
+		}
+		s.initializedData = &newInitializedData
+		s.initializerWG.Done()
+	}
+	return *s.initializedData, nil
 }
 
 func (s *CallableStepSchema[StepData, InputType]) CallSignal(ctx context.Context, signalID string, input any) error {
+	if exec := executionFromContext(ctx); exec != nil && s.initializer != nil {
+		data, err := exec.wait(ctx, s)
+		if err != nil {
+			return err
+		}
+		return s.SignalHandlersValue[signalID].Call(ctx, data.(StepData), input)
+	}
 	s.initializerWG.Wait()
 	if s.initializedData == nil {
 		return IllegalStateError{
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"go.arcalot.io/assert"
 	"testing"
+	"time"
 
 	"go.flow.arcalot.io/pluginsdk/schema"
 )
@@ -101,3 +102,84 @@ func TestStepExecution(t *testing.T) {
 	assert.Equals(t, outputID, "success")
 	assert.Equals(t, outputData.(stepTestSuccessOutput).Message, "Hello, Arca Lot!")
 }
+
+func TestStepExecutionTimings(t *testing.T) {
+	timings := &schema.CallTimings{}
+	ctx := schema.ContextWithCallTimings(context.Background(), timings)
+	callableSchema := schema.NewCallableSchema(testStepSchema)
+	outputID, _, err := callableSchema.CallStep(ctx, "hello", map[string]any{"name": "Arca Lot"})
+	assert.NoError(t, err)
+	assert.Equals(t, outputID, "success")
+	assert.Equals(t, timings.InputUnserialize > 0, true)
+	assert.Equals(t, timings.Handler > 0, true)
+	assert.Equals(t, timings.OutputSerialize > 0, true)
+}
+
+type counterStepData struct {
+	calls int
+}
+
+func newCounterStep() schema.CallableStep {
+	helloStep := testStepSchema.(*schema.CallableStepSchema[any, stepTestInputData])
+	return schema.NewCallableStepWithSignals[*counterStepData, stepTestInputData](
+		"counter",
+		helloStep.InputValue,
+		helloStep.OutputsValue,
+		map[string]schema.CallableSignal{
+			"increment": schema.NewCallableSignal(
+				"increment",
+				helloStep.InputValue,
+				nil,
+				func(_ context.Context, data *counterStepData, _ stepTestInputData) {
+					data.calls++
+				},
+			),
+		},
+		nil,
+		nil,
+		func() *counterStepData {
+			return &counterStepData{}
+		},
+		func(_ context.Context, data *counterStepData, _ stepTestInputData) (string, any) {
+			data.calls++
+			return "success", stepTestSuccessOutput{Message: fmt.Sprintf("call %d", data.calls)}
+		},
+	)
+}
+
+func TestStepExecutionContext(t *testing.T) {
+	step := newCounterStep()
+	input := stepTestInputData{Name: "Arca Lot"}
+
+	// Without an execution context, the step data is shared by all calls.
+	_, outputData, err := step.Call(context.Background(), input)
+	assert.NoError(t, err)
+	assert.Equals(t, outputData.(stepTestSuccessOutput).Message, "call 1")
+	_, outputData, err = step.Call(context.Background(), input)
+	assert.NoError(t, err)
+	assert.Equals(t, outputData.(stepTestSuccessOutput).Message, "call 2")
+
+	// Each execution context gets its own step data, which its signals use.
+	for i := 0; i < 2; i++ {
+		ctx := schema.ContextWithExecution(context.Background())
+		_, outputData, err = step.Call(ctx, input)
+		assert.NoError(t, err)
+		assert.Equals(t, outputData.(stepTestSuccessOutput).Message, "call 1")
+		assert.NoError(t, step.CallSignal(ctx, "increment", input))
+		_, outputData, err = step.Call(ctx, input)
+		assert.NoError(t, err)
+		assert.Equals(t, outputData.(stepTestSuccessOutput).Message, "call 3")
+	}
+
+	// Signals to an execution that hasn't started wait until the context is done.
+	ctx, cancel := context.WithTimeout(schema.ContextWithExecution(context.Background()), 10*time.Millisecond)
+	defer cancel()
+	assert.Error(t, step.CallSignal(ctx, "increment", input))
+
+	// An execution context belongs to a single step.
+	ctx = schema.ContextWithExecution(context.Background())
+	_, _, err = step.Call(ctx, input)
+	assert.NoError(t, err)
+	_, _, err = newCounterStep().Call(ctx, input)
+	assert.Error(t, err)
+}
@@ -0,0 +1,38 @@
+package schema
+
+import (
+	"context"
+	"time"
+)
+
+// CallTimings records how long the individual phases of a step call took. Attach it to the context passed to
+// CallableSchema.CallStep using ContextWithCallTimings to have it filled in.
+type CallTimings struct {
+	// InputUnserialize is the time spent unserializing the raw input data.
+	InputUnserialize time.Duration
+	// InputValidate is the time spent validating the unserialized input data.
+	InputValidate time.Duration
+	// Handler is the time spent in the step initializer and handler.
+	Handler time.Duration
+	// OutputValidate is the time spent validating the output data returned by the handler.
+	OutputValidate time.Duration
+	// OutputSerialize is the time spent serializing the output data.
+	OutputSerialize time.Duration
+}
+
+type callTimingsKey struct{}
+
+// ContextWithCallTimings returns a copy of the context that instructs step calls to record their timings into the
+// passed CallTimings.
+func ContextWithCallTimings(ctx context.Context, timings *CallTimings) context.Context {
+	return context.WithValue(ctx, callTimingsKey{}, timings)
+}
+
+// callTimingsFromContext returns the timings attached to the context. If none are attached, a throwaway value is
+// returned so callers don't need to check for nil.
+func callTimingsFromContext(ctx context.Context) *CallTimings {
+	if timings, ok := ctx.Value(callTimingsKey{}).(*CallTimings); ok && timings != nil {
+		return timings
+	}
+	return &CallTimings{}
+}
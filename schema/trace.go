@@ -0,0 +1,33 @@
+package schema
+
+import "context"
+
+// TraceContext holds the W3C trace context (traceparent and tracestate headers) and arbitrary baggage that the
+// caller of a step wishes to correlate with the work done in the plugin. All fields are optional.
+type TraceContext struct {
+	// TraceParent holds the W3C traceparent value, e.g. 00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01.
+	TraceParent string
+	// TraceState holds the vendor-specific W3C tracestate value.
+	TraceState string
+	// Baggage holds arbitrary key-value pairs propagated alongside the trace.
+	Baggage map[string]string
+}
+
+// IsEmpty returns true if no trace information is set.
+func (t TraceContext) IsEmpty() bool {
+	return t.TraceParent == "" && t.TraceState == "" && len(t.Baggage) == 0
+}
+
+type traceContextKey struct{}
+
+// ContextWithTraceContext returns a copy of the context carrying the specified trace context. Step handlers can
+// retrieve it using TraceContextFromContext.
+func ContextWithTraceContext(ctx context.Context, traceContext TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, traceContext)
+}
+
+// TraceContextFromContext returns the trace context stored in the context, if any.
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	traceContext, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return traceContext, ok
+}
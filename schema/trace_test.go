@@ -0,0 +1,26 @@
+package schema_test
+
+import (
+	"context"
+	"testing"
+
+	"go.arcalot.io/assert"
+	"go.flow.arcalot.io/pluginsdk/schema"
+)
+
+func TestTraceContextFromContext(t *testing.T) {
+	_, ok := schema.TraceContextFromContext(context.Background())
+	assert.Equals(t, ok, false)
+
+	ctx := schema.ContextWithTraceContext(context.Background(), schema.TraceContext{
+		TraceParent: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		TraceState:  "congo=t61rcWkgMzE",
+		Baggage:     map[string]string{"tenant": "arcalot"},
+	})
+	traceContext, ok := schema.TraceContextFromContext(ctx)
+	assert.Equals(t, ok, true)
+	assert.Equals(t, traceContext.TraceState, "congo=t61rcWkgMzE")
+	assert.Equals(t, traceContext.Baggage["tenant"], "arcalot")
+	assert.Equals(t, traceContext.IsEmpty(), false)
+	assert.Equals(t, schema.TraceContext{}.IsEmpty(), true)
+}